@@ -1,26 +1,383 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 )
 
+// Profiles selected with APP_ENV.
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvProduction  = "production"
+)
+
+// AppConfig is the configuration of the application. Values are read, from
+// lowest to highest priority, from the defaults of the profile, config.yaml,
+// config.<profile>.yaml, .env and the environment. Nested keys map to
+// environment variables with dots replaced by underscores, e.g. db.max_open_conns
+// is DB_MAX_OPEN_CONNS. Any variable can instead be read from a file named by
+// <VARIABLE>_FILE, e.g. AUTH_JWT_SECRET_FILE=/run/secrets/jwt.
 type AppConfig struct {
-	DB_SOURCE  string `mapstructure:"DB_SOURCE"`
-	DB_DEBUG   bool   `mapstructure:"DB_DEBUG"`
-	JWT_SECRET string `mapstructure:"JWT_SECRET"`
+	Env     string        `mapstructure:"app_env" validate:"oneof=development test production"`
+	Server  ServerConfig  `mapstructure:"server"`
+	DB      DBConfig      `mapstructure:"db"`
+	Auth    AuthConfig    `mapstructure:"auth"`
+	Log     LogConfig     `mapstructure:"log"`
+	Storage StorageConfig `mapstructure:"storage"`
+	Mail    MailConfig    `mapstructure:"mail"`
+	Tracing TracingConfig `mapstructure:"tracing"`
+}
+
+// ServerConfig is the HTTP server. It listens on Socket when set, otherwise
+// on Host:Port, and serves HTTPS when both TLS files are set.
+type ServerConfig struct {
+	Host            string        `mapstructure:"host"`
+	Port            int           `mapstructure:"port" validate:"min=1,max=65535"`
+	Socket          string        `mapstructure:"socket"` // Path Unix socket, mis. di belakang nginx
+	ReadTimeout     time.Duration `mapstructure:"read_timeout" validate:"min=0"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout" validate:"min=0"`
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout" validate:"min=0"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" validate:"min=0"`
+	BodyLimit       int           `mapstructure:"body_limit" validate:"min=1"` // Dalam byte
+	TLSCertFile     string        `mapstructure:"tls_cert_file"`
+	TLSKeyFile      string        `mapstructure:"tls_key_file"`
+}
+
+// Address is the host:port the server listens on.
+func (c ServerConfig) Address() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// TLS reports whether the server serves HTTPS.
+func (c ServerConfig) TLS() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
 }
 
+type DBConfig struct {
+	Source          string        `mapstructure:"source"` // DSN lengkap; jika kosong dibangun dari host, port, user, dst.
+	Host            string        `mapstructure:"host"`
+	Port            int           `mapstructure:"port" validate:"min=1,max=65535"`
+	User            string        `mapstructure:"user"`
+	Password        string        `mapstructure:"password"`
+	Name            string        `mapstructure:"name"`
+	SSLMode         string        `mapstructure:"ssl_mode" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	Debug           bool          `mapstructure:"debug"`
+	SlowQuery       time.Duration `mapstructure:"slow_query" validate:"min=0"` // Query yang lebih lama dicatat sebagai warning; 0 mematikan
+	MaxOpenConns    int           `mapstructure:"max_open_conns" validate:"min=0"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" validate:"min=0"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" validate:"min=0"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" validate:"min=0"`
+}
+
+// DSN returns Source, or a connection URL built from the separate settings.
+func (c DBConfig) DSN() string {
+	if c.Source != "" {
+		return c.Source
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+type AuthConfig struct {
+	JWTSecret string        `mapstructure:"jwt_secret" validate:"required"`
+	TokenTTL  time.Duration `mapstructure:"token_ttl" validate:"min=1m"`
+}
+
+// LogConfig is the application log. Lines go to every output in Outputs;
+// Format applies to stdout and stderr, files are always JSON. Files are
+// rotated daily and kept for MaxAge days.
+type LogConfig struct {
+	Level   string   `mapstructure:"level" validate:"oneof=debug info warn error"`
+	Format  string   `mapstructure:"format" validate:"oneof=json console"`
+	Outputs []string `mapstructure:"outputs" validate:"min=1,dive,oneof=stdout stderr file"`
+	Dir     string   `mapstructure:"dir"`                      // Folder file log app-<tanggal>.log
+	MaxAge  int      `mapstructure:"max_age" validate:"min=0"` // Hari; 0 berarti file lama tidak dihapus
+}
+
+// HasOutput reports whether the log is written to output.
+func (c LogConfig) HasOutput(output string) bool {
+	for _, o := range c.Outputs {
+		if o == output {
+			return true
+		}
+	}
+	return false
+}
+
+type StorageConfig struct {
+	Dir string `mapstructure:"dir" validate:"required"` // Folder untuk file upload (cover)
+	URL string `mapstructure:"url" validate:"required"` // URL publik folder tersebut
+}
+
+// MailConfig is the SMTP server used for outgoing mail. Mail is disabled
+// while Host is empty.
+type MailConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port" validate:"min=1,max=65535"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from" validate:"omitempty,email"`
+}
+
+// Enabled reports whether an SMTP server is configured.
+func (c MailConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// TracingConfig is the OpenTelemetry tracing of requests and queries. Spans
+// are sent to an OTLP/HTTP collector when Exporter is otlp.
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter" validate:"oneof=none otlp"`
+	Endpoint    string  `mapstructure:"endpoint"` // host:port collector; kosong memakai OTEL_EXPORTER_OTLP_ENDPOINT atau localhost:4318
+	Insecure    bool    `mapstructure:"insecure"` // HTTP biasa tanpa TLS
+	SampleRatio float64 `mapstructure:"sample_ratio" validate:"min=0,max=1"`
+	ServiceName string  `mapstructure:"service_name" validate:"required"`
+}
+
+// IsProduction reports whether the production profile is active.
+// Development-only seed data is never loaded in production.
+func (c AppConfig) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// defaults are the values used when nothing else sets them. Every key must be
+// listed here (an empty value is fine) so it can be read from the environment.
+var defaults = map[string]interface{}{
+	"app_env":                 EnvDevelopment,
+	"server.host":             "",
+	"server.port":             3000,
+	"server.socket":           "",
+	"server.read_timeout":     "30s",
+	"server.write_timeout":    "60s",
+	"server.idle_timeout":     "120s",
+	"server.shutdown_timeout": "30s",
+	"server.body_limit":       100 << 20, // EPUB files and Calibre databases are up to 100 MB
+	"server.tls_cert_file":    "",
+	"server.tls_key_file":     "",
+	"db.source":               "",
+	"db.host":                 "localhost",
+	"db.port":                 5432,
+	"db.user":                 "postgres",
+	"db.password":             "",
+	"db.name":                 "ayo_baca_buku",
+	"db.ssl_mode":             "disable",
+	"db.debug":                false,
+	"db.slow_query":           "200ms",
+	"db.max_open_conns":       25,
+	"db.max_idle_conns":       5,
+	"db.conn_max_lifetime":    "30m",
+	"db.conn_max_idle_time":   "5m",
+	"auth.jwt_secret":         "",
+	"auth.token_ttl":          "24h",
+	"log.level":               "info",
+	"log.format":              "console",
+	"log.outputs":             "stdout,file",
+	"log.dir":                 "logs",
+	"log.max_age":             7,
+	"storage.dir":             "storage",
+	"storage.url":             "/storage",
+	"mail.host":               "",
+	"mail.port":               587,
+	"mail.username":           "",
+	"mail.password":           "",
+	"mail.from":               "",
+	"tracing.exporter":        "none",
+	"tracing.endpoint":        "",
+	"tracing.insecure":        false,
+	"tracing.sample_ratio":    1.0,
+	"tracing.service_name":    "ayo-baca-buku",
+}
+
+// profileDefaults override defaults per APP_ENV.
+var profileDefaults = map[string]map[string]interface{}{
+	EnvDevelopment: {
+		"log.level": "debug",
+	},
+	EnvTest: {
+		"log.level":       "warn",
+		"log.outputs":     "stdout",
+		"auth.jwt_secret": "test-secret-do-not-use-in-production",
+	},
+	EnvProduction: {
+		"log.format": "json",
+	},
+}
+
+// aliases are the environment variables of earlier versions and common
+// conventions, still accepted after the primary name.
+var aliases = map[string][]string{
+	"auth.jwt_secret": {"JWT_SECRET"},
+	"server.port":     {"PORT"},
+}
+
+// minProductionSecretLength is the shortest JWT secret accepted in production.
+const minProductionSecretLength = 32
+
+// LoadAppConfig reads the configuration from the .env and YAML files in path
+// and the environment, and validates it. A missing .env or YAML file is not an
+// error; CONFIG_FILE names a YAML file to use instead of the ones in path.
+// APP_ENV selects the profile and is read from the environment or .env only.
 func LoadAppConfig(path string) (config AppConfig, err error) {
-	viper.AddConfigPath(path)
-	viper.SetConfigType("env")
-	viper.SetConfigName(".env")
+	if err = loadDotEnv(filepath.Join(path, ".env")); err != nil {
+		return config, err
+	}
+
+	v := viper.New()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	for key, names := range aliases {
+		if err = v.BindEnv(append([]string{key, envName(key)}, names...)...); err != nil {
+			return config, err
+		}
+	}
+
+	env := strings.ToLower(strings.TrimSpace(v.GetString("app_env")))
+	for key, value := range profileDefaults[env] {
+		v.SetDefault(key, value)
+	}
+
+	files := []string{filepath.Join(path, "config.yaml"), filepath.Join(path, "config."+env+".yaml")}
+	explicit := os.Getenv("CONFIG_FILE")
+	if explicit != "" {
+		files = []string{explicit}
+	}
+	v.SetConfigType("yaml")
+	for _, file := range files {
+		data, readErr := os.ReadFile(file)
+		if errors.Is(readErr, os.ErrNotExist) && explicit == "" {
+			continue
+		}
+		if readErr != nil {
+			return config, fmt.Errorf("config: %w", readErr)
+		}
+		if err = v.MergeConfig(bytes.NewReader(data)); err != nil {
+			return config, fmt.Errorf("config: %s: %w", file, err)
+		}
+	}
+
+	if err = readSecretFiles(v); err != nil {
+		return config, err
+	}
+	if err = v.Unmarshal(&config); err != nil {
+		return config, fmt.Errorf("config: %w", err)
+	}
+	config.Env = strings.ToLower(strings.TrimSpace(config.Env))
+	for i, output := range config.Log.Outputs {
+		config.Log.Outputs[i] = strings.ToLower(strings.TrimSpace(output))
+	}
+	return config, config.Validate()
+}
+
+// Validate checks the configuration and names every invalid setting by its
+// environment variable.
+func (c AppConfig) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("mapstructure"), ",", 2)[0]
+	})
 
-	viper.AutomaticEnv()
-	err = viper.ReadInConfig()
-	if err != nil {
-		return
+	var problems []string
+	if err := validate.Struct(c); err != nil {
+		var vErrs validator.ValidationErrors
+		if !errors.As(err, &vErrs) {
+			return fmt.Errorf("config: %w", err)
+		}
+		for _, vErr := range vErrs {
+			// The namespace starts with the struct name: "AppConfig.db.port".
+			key := vErr.Namespace()[strings.IndexByte(vErr.Namespace(), '.')+1:]
+			// Drop the index of list items: "log.outputs[1]".
+			if i := strings.IndexByte(key, '['); i >= 0 {
+				key = key[:i]
+			}
+			problems = append(problems, describe(key, vErr))
+		}
 	}
 
-	err = viper.Unmarshal(&config)
-	return
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		problems = append(problems, "SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together")
+	}
+	if c.DB.Source == "" && (c.DB.Host == "" || c.DB.User == "" || c.DB.Name == "") {
+		problems = append(problems, "DB_SOURCE, or DB_HOST, DB_USER and DB_NAME, must be set")
+	}
+	if c.Log.HasOutput("file") && c.Log.Dir == "" {
+		problems = append(problems, "LOG_DIR must be set when LOG_OUTPUTS includes file")
+	}
+	if c.Mail.Enabled() && c.Mail.From == "" {
+		problems = append(problems, "MAIL_FROM must be set when MAIL_HOST is set")
+	}
+	if c.IsProduction() {
+		if len(c.Auth.JWTSecret) < minProductionSecretLength {
+			problems = append(problems, fmt.Sprintf("AUTH_JWT_SECRET must be at least %d characters in production", minProductionSecretLength))
+		}
+		if c.DB.Debug {
+			problems = append(problems, "DB_DEBUG must be off in production, it logs query parameters")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration (APP_ENV=%s):\n  %s", c.Env, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// describe turns a validation error into a message naming the variable.
+func describe(key string, vErr validator.FieldError) string {
+	name := envName(key)
+	switch vErr.Tag() {
+	case "required":
+		return name + " is required"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s, got %q", name, strings.ReplaceAll(vErr.Param(), " ", ", "), vErr.Value())
+	case "min", "max":
+		return fmt.Sprintf("%s must be %s %s, got %v", name, map[string]string{"min": "at least", "max": "at most"}[vErr.Tag()], vErr.Param(), vErr.Value())
+	case "email":
+		return fmt.Sprintf("%s must be an email address, got %q", name, vErr.Value())
+	}
+	return fmt.Sprintf("%s is invalid (%s)", name, vErr.Tag())
+}
+
+// envName returns the environment variable of a key, e.g. DB_MAX_OPEN_CONNS.
+func envName(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// readSecretFiles sets every key whose <VARIABLE>_FILE is set to the contents
+// of that file, so secrets can come from Docker or Kubernetes secret files.
+func readSecretFiles(v *viper.Viper) error {
+	for key := range defaults {
+		names := append([]string{envName(key)}, aliases[key]...)
+		for _, name := range names {
+			file := os.Getenv(name + "_FILE")
+			if file == "" {
+				continue
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("config: %s_FILE: %w", name, err)
+			}
+			v.Set(key, strings.TrimRight(string(data), "\r\n"))
+			break
+		}
+	}
+	return nil
 }
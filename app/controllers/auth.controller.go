@@ -2,10 +2,11 @@ package controllers
 
 import (
 	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
 	"ayo-baca-buku/app/util/jwt"
 	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/metrics"
 	"ayo-baca-buku/app/util/validation"
-	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -16,24 +17,30 @@ import (
 type AuthController struct {
 	DB       *gorm.DB
 	Validate *validator.Validate
+	Tokens   *jwt.Manager
 }
 
-func NewAuthController(DB *gorm.DB) *AuthController {
+func NewAuthController(DB *gorm.DB, tokens *jwt.Manager) *AuthController {
 	return &AuthController{
 		DB:       DB,
-		Validate: validator.New(),
+		Validate: validation.New(),
+		Tokens:   tokens,
 	}
 }
 
+// dummyPasswordHash is compared with the password of unknown and deleted
+// users, so rejecting them takes as long as rejecting a wrong password. It has
+// the cost of jwt.HashPassword.
+const dummyPasswordHash = "$2a$14$7jAhCh3hJDiEnvdRhsaJceYCGL4gvgRPqeuUAJC1MDD9NiP9kx2g."
+
 type LoginRequest struct {
 	Username string `json:"username" validate:"required,min=3,alphanum"`
 	Password string `json:"password" validate:"required,min=6,alphanum"`
 }
 
 type LoginResponse struct {
-	Message string            `json:"message"`
-	Token   string            `json:"token,omitempty"`
-	Errors  map[string]string `json:"errors,omitempty"`
+	Message string `json:"message"`
+	Token   string `json:"token"`
 }
 
 // Login godoc
@@ -44,73 +51,64 @@ type LoginResponse struct {
 // @Produce json
 // @Param request body LoginRequest true "Login Request"
 // @Success 200 {object} LoginResponse
-// @Failure 400 {object} LoginResponse
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem "Invalid username or password"
 // @Router /login [post]
 func (c *AuthController) Login(ctx *fiber.Ctx) error {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(ctx.UserContext())
 	logger.Info("AuthController.Login Begin")
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var req LoginRequest
 	if err := ctx.BodyParser(&req); err != nil {
 		logger.Error("Failed to parse request body", zap.Error(err))
-		return ctx.Status(fiber.StatusBadRequest).JSON(LoginResponse{
-			Message: "Invalid Request",
-			Errors: map[string]string{
-				"body": "Failed to parse request body",
-			},
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	if err := c.Validate.Struct(&req); err != nil {
 		logger.Error("Validation failed", zap.Error(err))
-		validationErrors := make(map[string]string)
-
-		for _, vErr := range err.(validator.ValidationErrors) {
-			validationErrors[strings.ToLower(vErr.Field())] = vErr.Error()
-		}
-		return ctx.Status(fiber.StatusBadRequest).JSON(LoginResponse{
-			Message: "Validation failed",
-			Errors:  validationErrors,
-		})
+		return apperror.Validation(err)
 	}
 
+	// Unknown, deleted and wrong password all get the same answer, so the
+	// response does not tell whether a username exists. The log keeps the reason.
 	user := models.User{}
-	if err := c.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		logger.Error("User not found", zap.Error(err))
-		return ctx.Status(fiber.StatusNotFound).JSON(LoginResponse{
-			Message: "Invalid credentials (1)",
-		})
+	if err := db.Where("username = ?", req.Username).First(&user).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			logger.Error("Failed to fetch user", zap.Error(err))
+			return apperror.Internal("Failed to log in")
+		}
+		jwt.CheckPasswordHash(req.Password, dummyPasswordHash)
+		logger.Warn("Login failed: user not found")
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		return apperror.InvalidCredentials()
 	}
 
 	if user.DeletedBy != 0 {
-		logger.Error("User deleted")
-		return ctx.Status(fiber.StatusUnauthorized).JSON(LoginResponse{
-			Message: "User deleted (soft)",
-		})
+		jwt.CheckPasswordHash(req.Password, dummyPasswordHash)
+		logger.Warn("Login failed: user deleted", zap.Uint("userID", user.ID))
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		return apperror.InvalidCredentials()
 	}
 
 	if !jwt.CheckPasswordHash(req.Password, user.Password) {
-		logger.Error("Invalid password")
-		return ctx.Status(fiber.StatusUnauthorized).JSON(LoginResponse{
-			Message: "Invalid credentials (2)",
-		})
+		logger.Warn("Login failed: invalid password", zap.Uint("userID", user.ID))
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		return apperror.InvalidCredentials()
 	}
 
-	token, err := jwt.GenerateToken(user.UID, user.Username)
+	token, err := c.Tokens.GenerateToken(user.UID, user.Username)
 	if err != nil {
 		logger.Error("Failed to generate token", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(LoginResponse{
-			Message: "Failed to generate token",
-		})
+		return apperror.Internal("Failed to generate token")
 	}
 
-	if err := c.DB.Model(&user).Where("username = ?", user.Username).Update("token", token).Error; err != nil {
+	if err := db.Model(&user).Where("username = ?", user.Username).Update("token", token).Error; err != nil {
 		logger.Error("Failed to update token", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(LoginResponse{
-			Message: "Failed to update token",
-		})
+		return apperror.Internal("Failed to update token")
 	}
 
+	metrics.Logins.WithLabelValues(metrics.LoginSucceeded).Inc()
 	return ctx.Status(fiber.StatusOK).JSON(LoginResponse{
 		Message: "Success",
 		Token:   token,
@@ -126,9 +124,8 @@ type RegisterRequest struct {
 }
 
 type RegisterResponse struct {
-	Message string            `json:"message"`
-	Data    *models.User      `json:"data,omitempty"`
-	Errors  map[string]string `json:"errors,omitempty"`
+	Message string       `json:"message"`
+	Data    *models.User `json:"data"`
 }
 
 // Register godoc
@@ -139,45 +136,31 @@ type RegisterResponse struct {
 // @Produce json
 // @Param request body RegisterRequest true "Register Request"
 // @Success 200 {object} RegisterResponse
-// @Failure 400 {object} RegisterResponse
+// @Failure 400 {object} apperror.Problem
 // @Router /register [post]
 func (c *AuthController) Register(ctx *fiber.Ctx) error {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(ctx.UserContext())
 	logger.Info("AuthController.Register Begin")
+	db := c.DB.WithContext(ctx.UserContext())
 
-	c.Validate.RegisterValidation("unique_username", validation.UniqueUsername(c.DB, 0))
-	c.Validate.RegisterValidation("unique_email", validation.UniqueEmail(c.DB, 0))
+	c.Validate.RegisterValidation("unique_username", validation.UniqueUsername(db, 0))
+	c.Validate.RegisterValidation("unique_email", validation.UniqueEmail(db, 0))
 
 	var req RegisterRequest
 	if err := ctx.BodyParser(&req); err != nil {
 		logger.Error("Failed to parse request body", zap.Error(err))
-		return ctx.Status(fiber.StatusBadRequest).JSON(RegisterResponse{
-			Message: "Invalid Request",
-			Errors: map[string]string{
-				"body": "Failed to parse request body",
-			},
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	if err := c.Validate.Struct(&req); err != nil {
 		logger.Error("Validation failed", zap.Error(err))
-		validationErrors := make(map[string]string)
-
-		for _, vErr := range err.(validator.ValidationErrors) {
-			validationErrors[strings.ToLower(vErr.Field())] = vErr.Error()
-		}
-		return ctx.Status(fiber.StatusBadRequest).JSON(RegisterResponse{
-			Message: "Validation failed",
-			Errors:  validationErrors,
-		})
+		return apperror.Validation(err)
 	}
 
 	hash, err := jwt.HashPassword(req.Password)
 	if err != nil {
 		logger.Error("Failed to hash password", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(RegisterResponse{
-			Message: "Failed to hash password",
-		})
+		return apperror.Internal("Failed to hash password")
 	}
 
 	user := models.User{
@@ -188,11 +171,9 @@ func (c *AuthController) Register(ctx *fiber.Ctx) error {
 		Role:     "user",
 	}
 
-	if err := c.DB.Create(&user).Error; err != nil {
+	if err := db.Create(&user).Error; err != nil {
 		logger.Error("Failed to create user", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(RegisterResponse{
-			Message: "Failed to create user",
-		})
+		return apperror.Internal("Failed to create user")
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(RegisterResponse{
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/barcode"
+	"ayo-baca-buku/app/util/isbn"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/validation"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BarcodeController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewBarcodeController(DB *gorm.DB) *BarcodeController {
+	return &BarcodeController{
+		DB:       DB,
+		Validate: validation.New(),
+	}
+}
+
+// maxBarcodeImageSize limits uploaded photos; phone cameras stay well below it.
+const maxBarcodeImageSize = 20 << 20
+
+// ScanIsbnBarcode godoc
+// @Summary Read the ISBN barcode on a photo
+// @Description Upload a photo of the back cover to read its EAN-13 barcode. The ISBN is returned as a pre-filled UserBookCreateRequest for POST /userbooks. Title, author, publisher, genre and page count are copied from a user book with the same ISBN when one exists. user_book is the user's own book with this ISBN, if the user already has it.
+// @Tags UserBook
+// @Accept multipart/form-data
+// @Produce json
+// @Param image formData file true "JPEG, PNG or GIF photo"
+// @Param user_id formData int false "User ID to pre-fill"
+// @Success 200 {object} fiber.Map{message=string, data=models.UserBookCreateRequest, isbn10=string, user_book=models.UserBook}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/scan [post]
+func (c *BarcodeController) ScanIsbnBarcode(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("BarcodeController.ScanIsbnBarcode Begin", zap.String("userID", ctx.FormValue("user_id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	fileHeader, err := ctx.FormFile("image")
+	if err != nil {
+		log.Warn("Barcode image missing", zap.Error(err))
+		return apperror.InvalidField("image", "The photo must be uploaded as the image field")
+	}
+	if fileHeader.Size > maxBarcodeImageSize {
+		return apperror.InvalidField("image", fmt.Sprintf("File must not be larger than %d MB", maxBarcodeImageSize>>20))
+	}
+
+	var userID uint
+	if value := ctx.FormValue("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return apperror.InvalidField("user_id", "user_id must be a number")
+		}
+		userID = uint(id)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error("Failed to open uploaded image", zap.Error(err))
+		return apperror.Internal("Failed to read uploaded file")
+	}
+	defer file.Close()
+
+	code, err := barcode.DecodeImage(file)
+	if err != nil {
+		var (
+			notISBN *barcode.NotISBNError
+			message string
+		)
+		switch {
+		case errors.As(err, &notISBN):
+			message = fmt.Sprintf("Barcode %s is not an ISBN", notISBN.EAN)
+		case errors.Is(err, barcode.ErrNotFound):
+			message = "No EAN-13 barcode found; take the photo closer and keep the barcode sharp"
+		case errors.Is(err, barcode.ErrInvalidImage):
+			message = "Image must be a JPEG, PNG or GIF"
+		case errors.Is(err, barcode.ErrImageTooLarge):
+			message = fmt.Sprintf("Image must not have more than %d megapixels", barcode.MaxPixels/1_000_000)
+		default:
+			log.Error("Failed to read uploaded image", zap.Error(err))
+			return apperror.Internal("Failed to read uploaded file")
+		}
+		log.Warn("No ISBN barcode in image", zap.Error(err))
+		return apperror.InvalidField("image", message)
+	}
+
+	req := models.UserBookCreateRequest{
+		UserID:    userID,
+		ISBN:      code,
+		StartDate: time.Now(),
+	}
+
+	// Copy the details another user already entered for this edition. The
+	// cover is left out as it is a file of that user.
+	var known models.UserBook
+	if err := db.Where("isbn = ?", code).Order("updated_at DESC").First(&known).Error; err == nil {
+		req.Title = known.Title
+		req.Author = known.Author
+		req.Publisher = known.Publisher
+		req.Genre = known.Genre
+		req.TotalPages = known.TotalPages
+	} else if err != gorm.ErrRecordNotFound {
+		log.Error("Failed to look up books by ISBN", zap.Error(err), zap.String("isbn", code))
+		return apperror.Internal("Failed to look up ISBN")
+	}
+
+	response := fiber.Map{
+		"message": "ISBN barcode read successfully",
+		"data":    req,
+	}
+	if isbn10, ok := isbn.ToISBN10(code); ok {
+		response["isbn10"] = isbn10
+	}
+	if userID != 0 {
+		var owned models.UserBook
+		err := db.Where("user_id = ? AND isbn = ?", userID, code).Order("id").First(&owned).Error
+		if err == nil {
+			response["user_book"] = owned
+		} else if err != gorm.ErrRecordNotFound {
+			log.Error("Failed to look up user book by ISBN", zap.Error(err), zap.Uint("userID", userID))
+			return apperror.Internal("Failed to look up ISBN")
+		}
+	}
+
+	log.Info("ISBN barcode read", zap.String("isbn", code), zap.Bool("known", req.Title != ""))
+	return ctx.JSON(response)
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/ical"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/streak"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type CalendarController struct {
+	DB *gorm.DB
+}
+
+func NewCalendarController(DB *gorm.DB) *CalendarController {
+	return &CalendarController{
+		DB: DB,
+	}
+}
+
+const calendarProdID = "-//Ayo Baca Buku//Reading Calendar//ID"
+
+func calendarUID(kind string, id uint, date time.Time) string {
+	return fmt.Sprintf("%s-%d-%s@ayo-baca-buku", kind, id, date.Format("20060102"))
+}
+
+func calendarFeedURL(ctx *fiber.Ctx, token string) string {
+	return ctx.BaseURL() + "/calendar/" + token + ".ics"
+}
+
+// GenerateCalendarToken godoc
+// @Summary Generate a calendar feed token
+// @Description Create (or rotate) the secret token of the authenticated user's iCalendar feed. Rotating invalidates previously shared feed URLs.
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string, data=map[string]string}
+// @Failure 401 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /me/calendar-token [post]
+func (c *CalendarController) GenerateCalendarToken(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	user := middlewares.CurrentUser(ctx)
+	log.Info("CalendarController.GenerateCalendarToken Begin", zap.Uint("userID", user.ID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Error("Failed to generate calendar token", zap.Error(err))
+		return apperror.Internal("Failed to generate calendar token")
+	}
+	token := hex.EncodeToString(secret)
+
+	if err := db.Model(user).Update("calendar_token", token).Error; err != nil {
+		log.Error("Failed to save calendar token", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to generate calendar token")
+	}
+
+	log.Info("Calendar token generated successfully", zap.Uint("userID", user.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Calendar token generated successfully",
+		"data": fiber.Map{
+			"token": token,
+			"url":   calendarFeedURL(ctx, token),
+		},
+	})
+}
+
+// RevokeCalendarToken godoc
+// @Summary Revoke the calendar feed token
+// @Description Disable the authenticated user's iCalendar feed.
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 401 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /me/calendar-token [delete]
+func (c *CalendarController) RevokeCalendarToken(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	user := middlewares.CurrentUser(ctx)
+	log.Info("CalendarController.RevokeCalendarToken Begin", zap.Uint("userID", user.ID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	if err := db.Model(user).Update("calendar_token", "").Error; err != nil {
+		log.Error("Failed to revoke calendar token", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to revoke calendar token")
+	}
+
+	log.Info("Calendar token revoked successfully", zap.Uint("userID", user.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Calendar token revoked successfully"})
+}
+
+// GetCalendarFeed godoc
+// @Summary Get the iCalendar feed of a user
+// @Description RFC 5545 feed with planned reading days, reading plan deadlines, goal checkpoints and finished books. Authenticated by the secret token in the URL so it can be subscribed to from any calendar client.
+// @Tags Calendar
+// @Produce text/calendar
+// @Param token path string true "Calendar token"
+// @Success 200 {file} file
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /calendar/{token}.ics [get]
+func (c *CalendarController) GetCalendarFeed(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("CalendarController.GetCalendarFeed Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	token := ctx.Params("token")
+	if len(token) != 64 {
+		return apperror.NotFound("Calendar not found")
+	}
+
+	var user models.User
+	if err := db.Where("calendar_token = ?", token).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("Calendar token not found")
+			return apperror.NotFound("Calendar not found")
+		}
+		log.Error("Failed to fetch user by calendar token", zap.Error(err))
+		return apperror.Internal("Failed to fetch calendar")
+	}
+
+	calendar := ical.Calendar{
+		ProdID:      calendarProdID,
+		Name:        "Ayo Baca Buku - " + user.Name,
+		Description: "Reading plans, goals and finished books",
+	}
+	now := time.Now()
+
+	planEvents, err := c.readingPlanEvents(db, &user, now)
+	if err != nil {
+		log.Error("Failed to build reading plan events", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to build calendar")
+	}
+	goalEvents, err := c.readingGoalEvents(db, &user, now)
+	if err != nil {
+		log.Error("Failed to build reading goal events", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to build calendar")
+	}
+	finishedEvents, err := c.finishedBookEvents(db, &user)
+	if err != nil {
+		log.Error("Failed to build finished book events", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to build calendar")
+	}
+	calendar.Events = append(calendar.Events, planEvents...)
+	calendar.Events = append(calendar.Events, goalEvents...)
+	calendar.Events = append(calendar.Events, finishedEvents...)
+
+	ctx.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	ctx.Set(fiber.HeaderContentDisposition, `inline; filename="ayo-baca-buku.ics"`)
+	if err := calendar.Write(ctx.Response().BodyWriter()); err != nil {
+		log.Error("Failed to write calendar feed", zap.Error(err), zap.Uint("userID", user.ID))
+		return err
+	}
+
+	log.Info("Calendar feed generated successfully", zap.Uint("userID", user.ID), zap.Int("events", len(calendar.Events)))
+	return nil
+}
+
+// readingPlanEvents returns one all-day event per planned reading day plus one
+// for each plan's deadline.
+func (c *CalendarController) readingPlanEvents(db *gorm.DB, user *models.User, now time.Time) ([]ical.Event, error) {
+	var plans []models.ReadingPlan
+	if err := db.Preload("UserBook").
+		Joins("JOIN user_books ON user_books.id = reading_plans.user_book_id AND user_books.deleted_at IS NULL").
+		Where("user_books.user_id = ?", user.ID).
+		Find(&plans).Error; err != nil {
+		return nil, err
+	}
+
+	events := []ical.Event{}
+	for i := range plans {
+		plan := &plans[i]
+		plan.UserBook.User = *user
+		schedule, err := buildReadingPlanSchedule(db, plan, now)
+		if err != nil {
+			return nil, err
+		}
+
+		name := plan.UserBook.Title
+		if plan.Title != "" {
+			name = plan.Title + " - " + plan.UserBook.Title
+		}
+		for _, day := range schedule.Days {
+			if day.Rest || day.Pages == 0 {
+				continue
+			}
+			events = append(events, ical.Event{
+				UID:         calendarUID("plan", plan.ID, day.Date),
+				Summary:     fmt.Sprintf("Read %s: %d pages", plan.UserBook.Title, day.Pages),
+				Description: fmt.Sprintf("Read pages %d-%d of %d.", day.TargetPage-day.Pages+1, day.TargetPage, schedule.TotalPages),
+				Categories:  []string{"Reading plan"},
+				Start:       day.Date,
+				AllDay:      true,
+				Stamp:       plan.UpdatedAt,
+			})
+		}
+		events = append(events, ical.Event{
+			UID:         calendarUID("plan-deadline", plan.ID, plan.Deadline),
+			Summary:     "Deadline: " + name,
+			Description: fmt.Sprintf("Finish %s by %s (%d of %d pages read).", plan.UserBook.Title, plan.Deadline.Format("2006-01-02"), schedule.CurrentPage, schedule.TotalPages),
+			Categories:  []string{"Reading plan"},
+			Start:       streak.Day(plan.Deadline, time.UTC),
+			AllDay:      true,
+			Stamp:       plan.UpdatedAt,
+		})
+	}
+	return events, nil
+}
+
+// readingGoalEvents returns a checkpoint at the end of every month inside a
+// goal's period and a final event on the goal's end date.
+func (c *CalendarController) readingGoalEvents(db *gorm.DB, user *models.User, now time.Time) ([]ical.Event, error) {
+	var goals []models.ReadingGoal
+	if err := db.Where("user_id = ?", user.ID).Find(&goals).Error; err != nil {
+		return nil, err
+	}
+
+	events := []ical.Event{}
+	for i := range goals {
+		goal := &goals[i]
+		progress, err := calculateGoalProgress(db, user, goal, now)
+		if err != nil {
+			return nil, err
+		}
+
+		start := streak.Day(goal.StartDate, time.UTC)
+		end := streak.Day(goal.EndDate, time.UTC)
+		for checkpoint := time.Date(start.Year(), start.Month()+1, 0, 0, 0, 0, 0, time.UTC); checkpoint.Before(end); checkpoint = time.Date(checkpoint.Year(), checkpoint.Month()+2, 0, 0, 0, 0, 0, time.UTC) {
+			elapsed := int(checkpoint.Sub(start).Hours()/24) + 1
+			expected := float64(progress.TargetTotal) * float64(elapsed) / float64(progress.TotalDays)
+			events = append(events, ical.Event{
+				UID:         calendarUID("goal-checkpoint", goal.ID, checkpoint),
+				Summary:     "Goal checkpoint: " + goal.Title,
+				Description: fmt.Sprintf("To stay on track you should be at %.0f of %d %s by today.", expected, progress.TargetTotal, goalUnit(goal.Type)),
+				Categories:  []string{"Reading goal"},
+				Start:       checkpoint,
+				AllDay:      true,
+				Stamp:       goal.UpdatedAt,
+			})
+		}
+		events = append(events, ical.Event{
+			UID:         calendarUID("goal-end", goal.ID, end),
+			Summary:     "Goal ends: " + goal.Title,
+			Description: fmt.Sprintf("Progress: %d of %d %s (%s).", progress.Current, progress.TargetTotal, goalUnit(goal.Type), progress.Status),
+			Categories:  []string{"Reading goal"},
+			Start:       end,
+			AllDay:      true,
+			Stamp:       goal.UpdatedAt,
+		})
+	}
+	return events, nil
+}
+
+func goalUnit(goalType string) string {
+	if goalType == models.GoalTypeBooks {
+		return "books"
+	}
+	return "pages"
+}
+
+// finishedBookEvents returns an all-day event on the end date of every finished book.
+func (c *CalendarController) finishedBookEvents(db *gorm.DB, user *models.User) ([]ical.Event, error) {
+	var userBooks []models.UserBook
+	if err := db.Where("user_id = ? AND status = ?", user.ID, "finished").Find(&userBooks).Error; err != nil {
+		return nil, err
+	}
+
+	loc := userLocation(user)
+	events := []ical.Event{}
+	for _, userBook := range userBooks {
+		if userBook.EndDate.IsZero() {
+			continue
+		}
+		day := streak.Day(userBook.EndDate, loc)
+		events = append(events, ical.Event{
+			UID:         calendarUID("finished", userBook.ID, day),
+			Summary:     "Finished: " + userBook.Title,
+			Description: fmt.Sprintf("%s by %s, %d pages.", userBook.Title, userBook.Author, userBook.TotalPages),
+			Categories:  []string{"Finished book"},
+			Start:       day,
+			AllDay:      true,
+			Stamp:       userBook.UpdatedAt,
+		})
+	}
+	return events, nil
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/epub"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/storage"
+	"ayo-baca-buku/app/util/validation"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type EpubController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Files    *storage.Local
+}
+
+func NewEpubController(DB *gorm.DB, files *storage.Local) *EpubController {
+	validate := validation.New()
+	// Same isbn rule as UserBookController
+	validate.RegisterValidation("isbn", validation.ISBN)
+
+	return &EpubController{
+		DB:       DB,
+		Validate: validate,
+		Files:    files,
+	}
+}
+
+// maxEpubSize limits uploaded EPUBs; large illustrated books stay below it.
+const maxEpubSize = 100 << 20
+
+// limitRunes shortens extracted metadata to the column sizes of UserBook.
+func limitRunes(value string, limit int) string {
+	if runes := []rune(value); len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return value
+}
+
+// parseEpubUpload reads the EPUB uploaded in the "file" field. The error is an
+// *apperror.Error the handler can return as it is.
+func parseEpubUpload(ctx *fiber.Ctx, log *zap.Logger) (*epub.Book, error) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		log.Warn("EPUB file missing", zap.Error(err))
+		return nil, apperror.InvalidField("file", "The EPUB must be uploaded as the file field")
+	}
+	if fileHeader.Size > maxEpubSize {
+		return nil, apperror.InvalidField("file", fmt.Sprintf("File must not be larger than %d MB", maxEpubSize>>20))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error("Failed to open uploaded EPUB", zap.Error(err))
+		return nil, apperror.Internal("Failed to read uploaded file")
+	}
+	defer file.Close()
+
+	book, err := epub.Parse(file, fileHeader.Size)
+	if err != nil {
+		log.Warn("Failed to parse EPUB", zap.Error(err))
+		return nil, apperror.InvalidField("file", "File is not a readable EPUB")
+	}
+	return book, nil
+}
+
+// saveEpubCover stores the cover of the EPUB for userBook and returns its URL.
+func (c *EpubController) saveEpubCover(userBook *models.UserBook, book *epub.Book) (string, error) {
+	name := fmt.Sprintf("covers/%d/%d%s", userBook.UserID, userBook.ID, book.CoverExtension())
+	return c.Files.Save(name, bytes.NewReader(book.Cover))
+}
+
+// CreateUserBookFromEpub godoc
+// @Summary Create a user book from an EPUB
+// @Description Upload an EPUB to add it to a user's books. Title, authors, publisher, ISBN, first subject (as genre) and cover are read from the OPF package. TotalPages comes from the EPUB's page list and is otherwise estimated from the length of the text. The optional form fields override the extracted values.
+// @Tags UserBook
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "EPUB file"
+// @Param user_id formData int true "User ID"
+// @Param title formData string false "Overrides the EPUB title"
+// @Param author formData string false "Overrides the EPUB authors"
+// @Param total_pages formData int false "Overrides the page count"
+// @Param start_date formData string false "YYYY-MM-DD, defaults to today"
+// @Success 201 {object} fiber.Map{message=string, data=models.UserBook, epub=epub.Book}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/epub [post]
+func (c *EpubController) CreateUserBookFromEpub(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("EpubController.CreateUserBookFromEpub Begin", zap.String("userID", ctx.FormValue("user_id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	book, err := parseEpubUpload(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	req := models.UserBookCreateRequest{
+		Title:      limitRunes(book.Title, 255),
+		Author:     limitRunes(strings.Join(book.Authors, " & "), 255),
+		Publisher:  limitRunes(book.Publisher, 255),
+		ISBN:       book.ISBN,
+		TotalPages: book.Pages,
+		StartDate:  time.Now(),
+	}
+	if len(book.Subjects) > 0 {
+		req.Genre = limitRunes(book.Subjects[0], 100)
+	}
+
+	invalid := apperror.Invalid()
+	if userID, err := strconv.ParseUint(ctx.FormValue("user_id"), 10, 64); err == nil {
+		req.UserID = uint(userID)
+	}
+	if title := strings.TrimSpace(ctx.FormValue("title")); title != "" {
+		req.Title = title
+	}
+	if author := strings.TrimSpace(ctx.FormValue("author")); author != "" {
+		req.Author = author
+	}
+	if value := ctx.FormValue("total_pages"); value != "" {
+		pages, err := strconv.Atoi(value)
+		if err != nil {
+			invalid.WithField("total_pages", "total_pages must be a number")
+		}
+		req.TotalPages = pages
+	}
+	if value := ctx.FormValue("start_date"); value != "" {
+		startDate, err := time.Parse(statisticDateLayout, value)
+		if err != nil {
+			invalid.WithField("start_date", "start_date must use the YYYY-MM-DD format")
+		}
+		req.StartDate = startDate
+	}
+	if err := c.Validate.Struct(&req); err != nil {
+		validationErr := apperror.Validation(err)
+		if validationErr.Code != apperror.CodeValidation {
+			return validationErr
+		}
+		for _, field := range validationErr.Fields {
+			switch field.Field {
+			case "title", "author", "total_pages":
+				// Missing in the EPUB, so the client has to provide it
+				field.Message = fmt.Sprintf("The EPUB does not contain %s; provide it as a form field", field.Field)
+			}
+			invalid.Fields = append(invalid.Fields, field)
+		}
+	}
+	if len(invalid.Fields) > 0 {
+		log.Warn("Validation failed for EPUB UserBook creation", zap.Any("errors", invalid.Fields))
+		return invalid
+	}
+
+	var user models.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("User not found for UserBook creation", zap.Uint("userID", req.UserID))
+			return apperror.InvalidField("user_id", "User not found")
+		}
+		log.Error("Failed to check user existence", zap.Error(err), zap.Uint("userID", req.UserID))
+		return apperror.Internal("Error checking user")
+	}
+
+	userBook := models.UserBook{
+		UserID:      req.UserID,
+		Title:       req.Title,
+		Author:      req.Author,
+		Publisher:   req.Publisher,
+		Genre:       req.Genre,
+		ISBN:        isbnOf(req.ISBN),
+		TotalPages:  req.TotalPages,
+		Status:      "reading",
+		StartDate:   req.StartDate,
+		CurrentPage: 0,
+		CreatedBy:   int64(req.UserID), // Placeholder for actor ID
+		UpdatedBy:   int64(req.UserID), // Placeholder for actor ID
+	}
+	if err := db.Create(&userBook).Error; err != nil {
+		log.Error("Failed to create UserBook in database", zap.Error(err))
+		return apperror.Internal("Failed to create user book entry")
+	}
+
+	if len(book.Cover) > 0 {
+		cover, err := c.saveEpubCover(&userBook, book)
+		if err == nil {
+			err = db.Model(&userBook).Update("cover", cover).Error
+		}
+		if err != nil {
+			// The book itself was created; the cover can be uploaded again.
+			log.Error("Failed to store EPUB cover", zap.Error(err), zap.Uint("userBookID", userBook.ID))
+		}
+	}
+
+	log.Info("UserBook created from EPUB", zap.Uint("userBookID", userBook.ID), zap.Bool("pagesEstimated", book.PagesEstimated))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "User book entry created successfully",
+		"data":    userBook,
+		"epub":    book,
+	})
+}
+
+// UploadUserBookEpub godoc
+// @Summary Fill a user book from an EPUB
+// @Description Upload the EPUB of an existing user book. Empty fields (publisher, genre, ISBN, cover and a page count of 0) are filled from the EPUB; with overwrite=true they are replaced. Title and author are never changed, nor is the page count set below the current page.
+// @Tags UserBook
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "UserBook ID"
+// @Param file formData file true "EPUB file"
+// @Param overwrite query bool false "Replace fields that already have a value"
+// @Success 200 {object} fiber.Map{message=string, data=models.UserBook, epub=epub.Book}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{id}/epub [post]
+func (c *EpubController) UploadUserBookEpub(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	log.Info("EpubController.UploadUserBookEpub Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var userBook models.UserBook
+	if err := db.Where("id = ?", userBookID).First(&userBook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("UserBook not found", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
+		}
+		log.Error("Failed to fetch UserBook", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to fetch user book")
+	}
+
+	// TODO: Authorization check
+
+	book, err := parseEpubUpload(ctx, log)
+	if err != nil {
+		return err
+	}
+	overwrite := ctx.QueryBool("overwrite", false)
+
+	updates := map[string]interface{}{}
+	if book.Publisher != "" && (overwrite || userBook.Publisher == "") {
+		updates["publisher"] = limitRunes(book.Publisher, 255)
+	}
+	if len(book.Subjects) > 0 && (overwrite || userBook.Genre == "") {
+		updates["genre"] = limitRunes(book.Subjects[0], 100)
+	}
+	if book.ISBN != "" && (overwrite || userBook.ISBN == "") {
+		updates["isbn"] = book.ISBN
+	}
+	if book.Pages > 0 && (overwrite || userBook.TotalPages == 0) {
+		// The reader may already be past the end of a shorter EPUB edition
+		if book.Pages < userBook.CurrentPage {
+			log.Warn("EPUB page count below current page, keeping total pages",
+				zap.Uint("userBookID", userBook.ID), zap.Int("pages", book.Pages), zap.Int("currentPage", userBook.CurrentPage))
+		} else {
+			updates["total_pages"] = book.Pages
+		}
+	}
+	if len(book.Cover) > 0 && (overwrite || userBook.Cover == "") {
+		cover, err := c.saveEpubCover(&userBook, book)
+		if err != nil {
+			log.Error("Failed to store EPUB cover", zap.Error(err), zap.Uint("userBookID", userBook.ID))
+			return apperror.Internal("Failed to store cover")
+		}
+		updates["cover"] = cover
+	}
+
+	if len(updates) > 0 {
+		updates["updated_by"] = int64(userBook.UserID) // Placeholder for actor ID
+		if err := db.Model(&userBook).Updates(updates).Error; err != nil {
+			log.Error("Failed to update UserBook from EPUB", zap.Error(err), zap.Uint("userBookID", userBook.ID))
+			return apperror.Internal("Failed to update user book entry")
+		}
+	}
+
+	log.Info("UserBook filled from EPUB", zap.Uint("userBookID", userBook.ID), zap.Int("fields", len(updates)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "User book entry updated successfully",
+		"data":    userBook,
+		"epub":    book,
+	})
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/exporter"
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/validation"
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ExportController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewExportController(DB *gorm.DB) *ExportController {
+	return &ExportController{
+		DB:       DB,
+		Validate: validation.New(),
+	}
+}
+
+// ExportLibrary godoc
+// @Summary Export my library
+// @Description Download all books of the authenticated user with progress, dates, status, review, shelves, tags and reading history. format=csv writes one row per reading activity, format=json nests the activities in each book and format=goodreads uses the Goodreads CSV layout (without reading history). The file is streamed, so large libraries are not loaded into memory.
+// @Tags Export
+// @Produce text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "csv (default), json or goodreads"
+// @Success 200 {string} string "Library export"
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /me/export [get]
+func (c *ExportController) ExportLibrary(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	user := middlewares.CurrentUser(ctx)
+	format := ctx.Query("format", "csv")
+	log.Info("ExportController.ExportLibrary Begin", zap.Uint("userID", user.ID), zap.String("format", format))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var (
+		contentType string
+		fileName    string
+		write       func(w io.Writer, library *exporter.Library) error
+	)
+	exportName := fmt.Sprintf("ayo-baca-buku-%s-%s", user.Username, time.Now().Format(statisticDateLayout))
+	switch format {
+	case "csv":
+		contentType, fileName, write = "text/csv; charset=utf-8", exportName+".csv", exporter.WriteCSV
+	case "json":
+		contentType, fileName = fiber.MIMEApplicationJSONCharsetUTF8, exportName+".json"
+		write = func(w io.Writer, library *exporter.Library) error {
+			return exporter.WriteJSON(w, user, library)
+		}
+	case "goodreads":
+		contentType, fileName, write = "text/csv; charset=utf-8", "goodreads_library_export.csv", exporter.WriteGoodreads
+	default:
+		log.Warn("Invalid export format", zap.String("format", format))
+		return apperror.InvalidField("format", "format must be one of csv json goodreads")
+	}
+
+	library, err := exporter.OpenLibrary(db, user.ID)
+	if err != nil {
+		log.Error("Failed to open library for export", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to export library")
+	}
+
+	streamExport(ctx, log, user.ID, format, contentType, fileName, library, func(w io.Writer) error {
+		return write(w, library)
+	})
+	return nil
+}
+
+// ExportMarkdown godoc
+// @Summary Export my notes as Markdown
+// @Description Download a ZIP with one Markdown file per book for Obsidian or another note app. Each file has YAML front matter (title, author, dates, status, rating), the reading notes ordered by reading date and the highlights. GET uses the built-in template; POST accepts a custom Go text/template that receives one book (see GET /me/export/markdown/template for the default). range may only loop over .ReadingActivities, .Highlights, .Shelves and .Tags, not nested, and each note is limited to 1 MB and 5 seconds of rendering.
+// @Tags Export
+// @Accept json
+// @Produce application/zip
+// @Security BearerAuth
+// @Param request body models.MarkdownExportRequest false "Custom template (POST only)"
+// @Success 200 {string} string "ZIP archive"
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /me/export/markdown [get]
+// @Router /me/export/markdown [post]
+func (c *ExportController) ExportMarkdown(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	user := middlewares.CurrentUser(ctx)
+	log.Info("ExportController.ExportMarkdown Begin", zap.Uint("userID", user.ID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.MarkdownExportRequest
+	if ctx.Method() == fiber.MethodPost {
+		if err := ctx.BodyParser(&req); err != nil {
+			log.Warn("Failed to parse request body", zap.Error(err))
+			return apperror.InvalidBody(err)
+		}
+		if err := c.Validate.Struct(req); err != nil {
+			log.Warn("Validation failed for markdown export", zap.Error(err))
+			return apperror.Validation(err)
+		}
+	}
+
+	tmpl, err := exporter.ParseMarkdownTemplate(req.Template)
+	if err != nil {
+		log.Warn("Invalid markdown template", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.InvalidField("template", err.Error())
+	}
+
+	library, err := exporter.OpenLibrary(db, user.ID)
+	if err == nil {
+		err = library.IncludeHighlights()
+		if err != nil {
+			library.Close()
+		}
+	}
+	if err != nil {
+		log.Error("Failed to open library for export", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to export library")
+	}
+
+	fileName := fmt.Sprintf("ayo-baca-buku-%s-%s-notes.zip", user.Username, time.Now().Format(statisticDateLayout))
+	streamExport(ctx, log, user.ID, "markdown", "application/zip", fileName, library, func(w io.Writer) error {
+		return exporter.WriteMarkdownZip(w, library, tmpl)
+	})
+	return nil
+}
+
+// GetMarkdownTemplate godoc
+// @Summary Get the default Markdown export template
+// @Description The built-in template, as a starting point for a custom template.
+// @Tags Export
+// @Produce plain
+// @Security BearerAuth
+// @Success 200 {string} string "Go text/template"
+// @Failure 401 {object} apperror.Problem
+// @Router /me/export/markdown/template [get]
+func (c *ExportController) GetMarkdownTemplate(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	return ctx.Status(fiber.StatusOK).SendString(exporter.DefaultMarkdownTemplate)
+}
+
+// streamExport sends the export as an attachment. The body is written after
+// the handler returns, so errors from write can only be logged; the client
+// sees a truncated file. The library is closed when done.
+func streamExport(ctx *fiber.Ctx, log *zap.Logger, userID uint, format, contentType, fileName string, library *exporter.Library, write func(w io.Writer) error) {
+	ctx.Status(fiber.StatusOK)
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer library.Close()
+		if err := write(w); err != nil {
+			log.Error("Library export was interrupted", zap.Error(err), zap.Uint("userID", userID), zap.String("format", format))
+			return
+		}
+		if err := w.Flush(); err != nil {
+			log.Warn("Failed to send library export", zap.Error(err), zap.Uint("userID", userID))
+			return
+		}
+		log.Info("Library exported", zap.Uint("userID", userID), zap.String("format", format))
+	})
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/config"
+	"ayo-baca-buku/app/database"
+	"ayo-baca-buku/app/util/health"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/storage"
+	"ayo-baca-buku/app/util/version"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// readyTimeout bounds a readiness request, well below the usual probe timeout.
+const readyTimeout = 3 * time.Second
+
+type HealthController struct {
+	DB     *gorm.DB
+	Checks []health.Check
+}
+
+func NewHealthController(DB *gorm.DB, files *storage.Local, mail config.MailConfig) *HealthController {
+	return &HealthController{
+		DB: DB,
+		Checks: []health.Check{
+			health.Database(DB),
+			health.Migrations(DB),
+			health.Storage(files),
+			health.Mail(mail),
+		},
+	}
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Answers as long as the server process handles requests. Dependencies are not checked, see /readyz.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} fiber.Map{status=string}
+// @Router /healthz [get]
+func (c *HealthController) Liveness(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{"status": health.StatusOK})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Checks the database connection, pending migrations, the storage directory and the SMTP server. Every check reports its status (ok, fail or disabled), latency and error. Responds 503 when any check fails.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (c *HealthController) Readiness(ctx *fiber.Ctx) error {
+	report := health.Run(ctx.UserContext(), c.Checks, readyTimeout)
+	if report.Status != health.StatusOK {
+		logger.FromContext(ctx.UserContext()).Warn("Readiness check failed", zap.Any("checks", report.Checks))
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+	return ctx.JSON(report)
+}
+
+// GetVersion godoc
+// @Summary Build information
+// @Description Version, git commit and build time of the server, set at build time, with the schema version of the build and of the database. database_schema_version is null when the database cannot be read.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} fiber.Map{version=string, commit=string, build_time=string, go_version=string, schema_version=int, database_schema_version=int}
+// @Router /version [get]
+func (c *HealthController) GetVersion(ctx *fiber.Ctx) error {
+	info := version.Get()
+	response := fiber.Map{
+		"version":                 info.Version,
+		"commit":                  info.Commit,
+		"build_time":              info.BuildTime,
+		"go_version":              info.GoVersion,
+		"schema_version":          nil,
+		"database_schema_version": nil,
+	}
+	if info.Modified {
+		response["modified"] = true
+	}
+
+	migrator, err := database.NewMigrator(c.DB.WithContext(ctx.UserContext()))
+	if err != nil {
+		logger.FromContext(ctx.UserContext()).Error("Failed to load migrations", zap.Error(err))
+		return ctx.JSON(response)
+	}
+	if n := len(migrator.Migrations); n > 0 {
+		response["schema_version"] = migrator.Migrations[n-1].Version
+	}
+	if applied, err := migrator.Version(); err == nil {
+		response["database_schema_version"] = applied
+	} else {
+		logger.FromContext(ctx.UserContext()).Warn("Failed to read database schema version", zap.Error(err))
+	}
+	return ctx.JSON(response)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// paramID parses the ID in the given path parameter. IDs must reach GORM as
+// numbers: a string passed as an inline condition, e.g. First(&user, id), is
+// inserted into the query as SQL.
+func paramID(ctx *fiber.Ctx, param string) (uint, error) {
+	id, err := strconv.ParseUint(ctx.Params(param), 10, 64)
+	if err != nil || id == 0 {
+		return 0, apperror.InvalidField(param, param+" must be a positive number")
+	}
+	return uint(id), nil
+}
+
+// findUserByParam loads the user whose ID is in the given path parameter. The
+// error is an *apperror.Error the handler can return as it is.
+func findUserByParam(ctx *fiber.Ctx, db *gorm.DB, log *zap.Logger, param string) (*models.User, error) {
+	userID, err := paramID(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("User not found", zap.Uint("userID", userID))
+			return nil, apperror.NotFound("User not found")
+		}
+		log.Error("Failed to fetch user", zap.Error(err), zap.Uint("userID", userID))
+		return nil, apperror.Internal("Failed to fetch user")
+	}
+	return &user, nil
+}
+
+// isOwner reports whether the request is authenticated as the user userID.
+func isOwner(ctx *fiber.Ctx, userID uint) bool {
+	user := middlewares.CurrentUser(ctx)
+	return user != nil && user.ID == userID
+}
@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/validation"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HighlightController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewHighlightController(DB *gorm.DB) *HighlightController {
+	return &HighlightController{
+		DB:       DB,
+		Validate: validation.New(),
+	}
+}
+
+func (c *HighlightController) findHighlight(ctx *fiber.Ctx, log *zap.Logger) (*models.Highlight, error) {
+	db := c.DB.WithContext(ctx.UserContext())
+
+	highlightID, err := paramID(ctx, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	var highlight models.Highlight
+	if err := db.Preload("UserBook").Where("id = ?", highlightID).First(&highlight).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("Highlight not found", zap.Uint("highlightID", highlightID))
+			return nil, apperror.NotFound("Highlight not found")
+		}
+		log.Error("Failed to fetch Highlight", zap.Error(err), zap.Uint("highlightID", highlightID))
+		return nil, apperror.Internal("Failed to fetch highlight")
+	}
+	return &highlight, nil
+}
+
+// CreateHighlight godoc
+// @Summary Save a highlight
+// @Description Save a quote from a user book with its page or e-reader location, an optional comment, colour and tag.
+// @Tags Highlight
+// @Accept json
+// @Produce json
+// @Param highlight body models.HighlightCreateRequest true "Highlight Create Payload"
+// @Success 201 {object} fiber.Map{message=string, data=models.Highlight}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /highlights [post]
+func (c *HighlightController) CreateHighlight(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("HighlightController.CreateHighlight Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.HighlightCreateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	req.Tag = normalizeTagName(req.Tag)
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Highlight creation", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	var userBook models.UserBook
+	if err := db.First(&userBook, req.UserBookID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("UserBook not found for Highlight creation", zap.Uint("userBookID", req.UserBookID))
+			return apperror.InvalidField("user_book_id", "User book not found")
+		}
+		log.Error("Failed to fetch UserBook for Highlight creation", zap.Error(err), zap.Uint("userBookID", req.UserBookID))
+		return apperror.Internal("Failed to fetch user book")
+	}
+
+	// TODO: Authorization check: Does the authenticated user own this UserBook?
+
+	if req.Page != nil && *req.Page > userBook.TotalPages {
+		return apperror.InvalidField("page", "page must not exceed the book's total pages")
+	}
+
+	highlight := models.Highlight{
+		UserBookID:    userBook.ID,
+		UserID:        userBook.UserID,
+		Kind:          req.Kind,
+		Source:        "manual",
+		Page:          req.Page,
+		Location:      req.Location,
+		Quote:         req.Quote,
+		Comment:       req.Comment,
+		Color:         req.Color,
+		Tag:           req.Tag,
+		HighlightedAt: time.Now(),
+		CreatedBy:     int64(userBook.UserID), // Placeholder for actor ID
+		UpdatedBy:     int64(userBook.UserID), // Placeholder for actor ID
+	}
+	if highlight.Kind == "" {
+		highlight.Kind = "highlight"
+	}
+	if highlight.Color == "" {
+		highlight.Color = "yellow"
+	}
+	if req.HighlightedAt != nil {
+		highlight.HighlightedAt = *req.HighlightedAt
+	}
+
+	if err := db.Create(&highlight).Error; err != nil {
+		log.Error("Failed to create Highlight in database", zap.Error(err))
+		return apperror.Internal("Failed to create highlight")
+	}
+
+	log.Info("Highlight created successfully", zap.Uint("highlightID", highlight.ID))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Highlight created successfully",
+		"data":    highlight,
+	})
+}
+
+// GetAllHighlights godoc
+// @Summary List and search highlights
+// @Description List highlights of a user or of one book, filtered by tag, colour and date. With q the quotes, comments and tags are searched and results are ranked by relevance.
+// @Tags Highlight
+// @Accept json
+// @Produce json
+// @Param user_id query int false "Filter by User ID (user_id or user_book_id is required)"
+// @Param user_book_id query int false "Filter by UserBook ID"
+// @Param tag query string false "Filter by tag"
+// @Param kind query string false "Filter by kind" Enums(highlight, note, bookmark)
+// @Param color query string false "Filter by colour"
+// @Param from query string false "Highlighted on or after this date (YYYY-MM-DD)"
+// @Param to query string false "Highlighted on or before this date (YYYY-MM-DD)"
+// @Param q query string false "Full-text search, supports \"quoted phrases\", OR and -exclusions"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.Highlight}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /highlights [get]
+func (c *HighlightController) GetAllHighlights(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("HighlightController.GetAllHighlights Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	userID := ctx.QueryInt("user_id")
+	userBookID := ctx.QueryInt("user_book_id")
+	if userID <= 0 && userBookID <= 0 {
+		return apperror.InvalidField("user_id", "user_id or user_book_id is required")
+	}
+
+	query := db.Model(&models.Highlight{})
+	if userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if userBookID > 0 {
+		query = query.Where("user_book_id = ?", userBookID)
+	}
+	if tag := normalizeTagName(ctx.Query("tag")); tag != "" {
+		query = query.Where("tag = ?", tag)
+	}
+	if kind := ctx.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if color := ctx.Query("color"); color != "" {
+		query = query.Where("color = ?", color)
+	}
+	if raw := ctx.Query("from"); raw != "" {
+		from, err := time.Parse(statisticDateLayout, raw)
+		if err != nil {
+			return apperror.InvalidField("from", "from must use the YYYY-MM-DD format")
+		}
+		query = query.Where("highlighted_at >= ?", from)
+	}
+	if raw := ctx.Query("to"); raw != "" {
+		to, err := time.Parse(statisticDateLayout, raw)
+		if err != nil {
+			return apperror.InvalidField("to", "to must use the YYYY-MM-DD format")
+		}
+		query = query.Where("highlighted_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	if q := strings.TrimSpace(ctx.Query("q")); q != "" {
+		query = query.Where("search_vector @@ websearch_to_tsquery('simple', ?)", q).
+			Clauses(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "ts_rank(search_vector, websearch_to_tsquery('simple', ?)) DESC",
+				Vars: []interface{}{q},
+			}})
+	}
+
+	highlights := []models.Highlight{}
+	if err := query.Preload("UserBook").Order("highlighted_at DESC, id DESC").Find(&highlights).Error; err != nil {
+		log.Error("Failed to fetch highlights", zap.Error(err))
+		return apperror.Internal("Failed to fetch highlights")
+	}
+
+	log.Info("Highlights fetched successfully", zap.Int("count", len(highlights)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Highlights fetched successfully",
+		"data":    highlights,
+	})
+}
+
+// GetHighlightByID godoc
+// @Summary Get a highlight
+// @Description Get a highlight with its book.
+// @Tags Highlight
+// @Accept json
+// @Produce json
+// @Param id path int true "Highlight ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.Highlight}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /highlights/{id} [get]
+func (c *HighlightController) GetHighlightByID(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("HighlightController.GetHighlightByID Begin", zap.String("highlightID", ctx.Params("id")))
+
+	highlight, err := c.findHighlight(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Highlight fetched successfully", zap.Uint("highlightID", highlight.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Highlight fetched successfully",
+		"data":    highlight,
+	})
+}
+
+// UpdateHighlight godoc
+// @Summary Update a highlight
+// @Description Edit the quote, comment, location, colour or tag of a highlight.
+// @Tags Highlight
+// @Accept json
+// @Produce json
+// @Param id path int true "Highlight ID"
+// @Param highlight body models.HighlightUpdateRequest true "Highlight Update Payload"
+// @Success 200 {object} fiber.Map{message=string, data=models.Highlight}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /highlights/{id} [put]
+func (c *HighlightController) UpdateHighlight(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("HighlightController.UpdateHighlight Begin", zap.String("highlightID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.HighlightUpdateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body for Highlight update", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if req.Tag != nil {
+		tag := normalizeTagName(*req.Tag)
+		req.Tag = &tag
+	}
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Highlight update", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	highlight, err := c.findHighlight(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	// TODO: Authorization check: Does the authenticated user own this highlight?
+
+	if req.Page != nil {
+		if highlight.UserBook != nil && *req.Page > highlight.UserBook.TotalPages {
+			return apperror.InvalidField("page", "page must not exceed the book's total pages")
+		}
+		highlight.Page = req.Page
+	}
+	if req.Location != nil {
+		highlight.Location = *req.Location
+	}
+	if req.Quote != "" {
+		highlight.Quote = req.Quote
+	}
+	if req.Comment != nil {
+		highlight.Comment = *req.Comment
+	}
+	if req.Color != "" {
+		highlight.Color = req.Color
+	}
+	if req.Tag != nil {
+		highlight.Tag = *req.Tag
+	}
+	if req.HighlightedAt != nil {
+		highlight.HighlightedAt = *req.HighlightedAt
+	}
+	highlight.UpdatedBy = int64(highlight.UserID) // Placeholder
+
+	if err := db.Omit("UserBook").Save(highlight).Error; err != nil {
+		log.Error("Failed to update Highlight in database", zap.Error(err), zap.Uint("highlightID", highlight.ID))
+		return apperror.Internal("Failed to update highlight")
+	}
+
+	log.Info("Highlight updated successfully", zap.Uint("highlightID", highlight.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Highlight updated successfully",
+		"data":    highlight,
+	})
+}
+
+// DeleteHighlight godoc
+// @Summary Soft delete a highlight
+// @Description Soft delete a highlight.
+// @Tags Highlight
+// @Accept json
+// @Produce json
+// @Param id path int true "Highlight ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /highlights/{id} [delete]
+func (c *HighlightController) DeleteHighlight(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("HighlightController.DeleteHighlight Begin", zap.String("highlightID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	highlight, err := c.findHighlight(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Model(highlight).Update("DeletedBy", int64(highlight.UserID)).Error; err != nil {
+		log.Error("Failed to set DeletedBy for Highlight", zap.Error(err), zap.Uint("highlightID", highlight.ID))
+		return apperror.Internal("Failed to delete highlight")
+	}
+	if err := db.Delete(highlight).Error; err != nil {
+		log.Error("Failed to soft delete Highlight", zap.Error(err), zap.Uint("highlightID", highlight.ID))
+		return apperror.Internal("Failed to delete highlight")
+	}
+
+	log.Info("Highlight soft deleted successfully", zap.Uint("highlightID", highlight.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Highlight deleted successfully"})
+}
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"archive/zip"
+	"ayo-baca-buku/app/importer"
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/calibre"
+	"ayo-baca-buku/app/util/clippings"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/storage"
+	"ayo-baca-buku/app/util/validation"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ImportController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Jobs     *importer.Runner
+	Files    *storage.Local
+}
+
+func NewImportController(DB *gorm.DB, jobs *importer.Runner, files *storage.Local) *ImportController {
+	return &ImportController{
+		DB:       DB,
+		Validate: validation.New(),
+		Jobs:     jobs,
+		Files:    files,
+	}
+}
+
+// maxLibraryExportSize limits uploaded CSV exports; a library of tens of
+// thousands of books is still only a few megabytes.
+const maxLibraryExportSize = 20 << 20
+
+// maxCalibreDatabaseSize limits uploaded Calibre metadata.db files, which also
+// hold descriptions and are larger than CSV exports.
+const maxCalibreDatabaseSize = 100 << 20
+
+// withJobProgress fills the derived fields of an import job.
+func withJobProgress(job *models.ImportJob) *models.ImportJob {
+	job.HasErrors = job.ErrorReport != ""
+	switch {
+	case job.Status == models.ImportJobCompleted:
+		job.Progress = 100
+	case job.TotalRows > 0:
+		job.Progress = roundTo(float64(job.ProcessedRows)/float64(job.TotalRows)*100, 1)
+	}
+	return job
+}
+
+func (c *ImportController) findImportJob(ctx *fiber.Ctx, log *zap.Logger, user *models.User) (*models.ImportJob, error) {
+	db := c.DB.WithContext(ctx.UserContext())
+
+	jobID, err := paramID(ctx, "jobId")
+	if err != nil {
+		return nil, err
+	}
+
+	var job models.ImportJob
+	if err := db.Where("id = ? AND user_id = ?", jobID, user.ID).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("ImportJob not found", zap.Uint("jobID", jobID))
+			return nil, apperror.NotFound("Import job not found")
+		}
+		log.Error("Failed to fetch ImportJob", zap.Error(err), zap.Uint("jobID", jobID))
+		return nil, apperror.Internal("Failed to fetch import job")
+	}
+	return withJobProgress(&job), nil
+}
+
+// ImportKindleClippings godoc
+// @Summary Import Kindle highlights
+// @Description Upload a Kindle "My Clippings.txt" file. Highlights, notes and bookmarks are stored on the matching user book, which is created when missing. Re-importing the same file does not create duplicates.
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param file formData file true "My Clippings.txt"
+// @Success 200 {object} fiber.Map{message=string, data=models.ImportReport}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/imports/kindle [post]
+func (c *ImportController) ImportKindleClippings(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ImportController.ImportKindleClippings Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		log.Warn("Clippings file missing", zap.Error(err))
+		return apperror.InvalidField("file", "My Clippings.txt must be uploaded as the file field")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error("Failed to open uploaded clippings file", zap.Error(err))
+		return apperror.Internal("Failed to read uploaded file")
+	}
+	defer file.Close()
+
+	entries, invalid, err := clippings.Parse(file, userLocation(user))
+	if err != nil {
+		log.Warn("Failed to parse clippings file", zap.Error(err))
+		return apperror.InvalidField("file", "File is not a readable My Clippings.txt")
+	}
+	if len(entries) == 0 && len(invalid) > 0 {
+		return apperror.InvalidField("file", "File does not contain any Kindle clippings")
+	}
+
+	report, err := importer.Kindle(db, user, entries, invalid)
+	if err != nil {
+		log.Error("Failed to import Kindle clippings", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to import Kindle clippings")
+	}
+
+	log.Info("Kindle clippings imported successfully",
+		zap.Uint("userID", user.ID),
+		zap.Int("created", report.Created),
+		zap.Int("updated", report.Updated),
+		zap.Int("skipped", report.Skipped))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Kindle clippings imported successfully",
+		"data":    report,
+	})
+}
+
+// ImportCalibreLibrary godoc
+// @Summary Import a Calibre library
+// @Description Upload a Calibre metadata.db. Titles, authors, publishers, ISBNs, tags (as tags), series (as shelves) and page counts (from a "pages" custom column, e.g. the Count Pages plugin) are imported. Covers are imported from an optional ZIP of the Calibre library folder. Books already in the library are matched by ISBN or title and author and only get missing details.
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param file formData file true "metadata.db"
+// @Param covers formData file false "ZIP of the Calibre library folder with the cover.jpg files"
+// @Success 200 {object} fiber.Map{message=string, data=models.ImportReport}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/imports/calibre [post]
+func (c *ImportController) ImportCalibreLibrary(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ImportController.ImportCalibreLibrary Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		log.Warn("Calibre database missing", zap.Error(err))
+		return apperror.InvalidField("file", "metadata.db must be uploaded as the file field")
+	}
+	if fileHeader.Size > maxCalibreDatabaseSize {
+		return apperror.InvalidField("file", fmt.Sprintf("File must not be larger than %d MB", maxCalibreDatabaseSize>>20))
+	}
+
+	// SQLite needs a file on disk.
+	tmp, err := os.CreateTemp("", "calibre-*.db")
+	if err != nil {
+		log.Error("Failed to create temporary file for Calibre database", zap.Error(err))
+		return apperror.Internal("Failed to read uploaded file")
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := ctx.SaveFile(fileHeader, tmp.Name()); err != nil {
+		log.Error("Failed to save uploaded Calibre database", zap.Error(err))
+		return apperror.Internal("Failed to read uploaded file")
+	}
+
+	books, err := calibre.Read(tmp.Name())
+	if err != nil {
+		if errors.Is(err, calibre.ErrNotCalibreLibrary) {
+			return apperror.InvalidField("file", "File is not a Calibre metadata.db")
+		}
+		log.Error("Failed to read Calibre database", zap.Error(err))
+		return apperror.Internal("Failed to read Calibre library")
+	}
+
+	var covers calibre.Covers
+	if coversHeader, err := ctx.FormFile("covers"); err == nil {
+		coversFile, err := coversHeader.Open()
+		if err != nil {
+			log.Error("Failed to open uploaded covers archive", zap.Error(err))
+			return apperror.Internal("Failed to read uploaded file")
+		}
+		defer coversFile.Close()
+
+		archive, err := zip.NewReader(coversFile, coversHeader.Size)
+		if err != nil {
+			log.Warn("Invalid covers archive", zap.Error(err))
+			return apperror.InvalidField("covers", "Covers must be a ZIP archive of the Calibre library folder")
+		}
+		covers = calibre.ZipCovers(archive)
+	}
+
+	report := importer.Calibre(db, c.Files, user.ID, books, covers)
+
+	log.Info("Calibre library imported successfully",
+		zap.Uint("userID", user.ID),
+		zap.Int("created", report.Created),
+		zap.Int("updated", report.Updated),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("failed", report.Failed))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Calibre library imported successfully",
+		"data":    report,
+	})
+}
+
+// CreateGoodreadsImport godoc
+// @Summary Import a Goodreads library export
+// @Description Upload the CSV from Goodreads' "Export Library". The import runs in the background; poll the returned job for progress.
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param file formData file true "goodreads_library_export.csv"
+// @Success 202 {object} fiber.Map{message=string, data=models.ImportJob}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 503 {object} apperror.Problem
+// @Router /users/{userId}/imports/goodreads [post]
+func (c *ImportController) CreateGoodreadsImport(ctx *fiber.Ctx) error {
+	return c.createLibraryImport(ctx, importer.SourceGoodreads)
+}
+
+// CreateStoryGraphImport godoc
+// @Summary Import a StoryGraph library export
+// @Description Upload the CSV from StoryGraph's "Export StoryGraph Library". The import runs in the background; poll the returned job for progress.
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param file formData file true "StoryGraph export CSV"
+// @Success 202 {object} fiber.Map{message=string, data=models.ImportJob}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 503 {object} apperror.Problem
+// @Router /users/{userId}/imports/storygraph [post]
+func (c *ImportController) CreateStoryGraphImport(ctx *fiber.Ctx) error {
+	return c.createLibraryImport(ctx, importer.SourceStoryGraph)
+}
+
+func (c *ImportController) createLibraryImport(ctx *fiber.Ctx, source string) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ImportController.createLibraryImport Begin", zap.String("userID", ctx.Params("userId")), zap.String("source", source))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		log.Warn("Library export file missing", zap.Error(err))
+		return apperror.InvalidField("file", "The CSV export must be uploaded as the file field")
+	}
+	if fileHeader.Size > maxLibraryExportSize {
+		return apperror.InvalidField("file", fmt.Sprintf("File must not be larger than %d MB", maxLibraryExportSize>>20))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error("Failed to open uploaded library export", zap.Error(err))
+		return apperror.Internal("Failed to read uploaded file")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		log.Error("Failed to read uploaded library export", zap.Error(err))
+		return apperror.Internal("Failed to read uploaded file")
+	}
+
+	job := models.ImportJob{
+		UserID:   user.ID,
+		Source:   source,
+		FileName: fileHeader.Filename,
+		Status:   models.ImportJobQueued,
+	}
+	if err := db.Create(&job).Error; err != nil {
+		log.Error("Failed to create ImportJob", zap.Error(err))
+		return apperror.Internal("Failed to start import")
+	}
+
+	if err := c.Jobs.Enqueue(&job, data); err != nil {
+		log.Warn("Failed to enqueue ImportJob", zap.Error(err), zap.Uint("jobID", job.ID))
+		db.Model(&job).Updates(map[string]interface{}{"status": models.ImportJobFailed, "error": err.Error()})
+		return apperror.Unavailable("Import queue is busy, please try again later")
+	}
+
+	log.Info("ImportJob queued", zap.Uint("jobID", job.ID), zap.String("source", source))
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Import started",
+		"data":    withJobProgress(&job),
+	})
+}
+
+// GetImportJobs godoc
+// @Summary List a user's import jobs
+// @Description List library imports, newest first.
+// @Tags Import
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.ImportJob}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/imports [get]
+func (c *ImportController) GetImportJobs(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ImportController.GetImportJobs Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	jobs := []models.ImportJob{}
+	if err := db.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&jobs).Error; err != nil {
+		log.Error("Failed to fetch import jobs", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch import jobs")
+	}
+	for i := range jobs {
+		withJobProgress(&jobs[i])
+	}
+
+	log.Info("Import jobs fetched successfully", zap.Uint("userID", user.ID), zap.Int("count", len(jobs)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Import jobs fetched successfully",
+		"data":    jobs,
+	})
+}
+
+// GetImportJob godoc
+// @Summary Get the status of an import job
+// @Description Get the state, progress and counters of a library import.
+// @Tags Import
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param jobId path int true "Import Job ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.ImportJob}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/imports/{jobId} [get]
+func (c *ImportController) GetImportJob(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ImportController.GetImportJob Begin", zap.String("userID", ctx.Params("userId")), zap.String("jobID", ctx.Params("jobId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+	job, err := c.findImportJob(ctx, log, user)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Import job fetched successfully", zap.Uint("jobID", job.ID), zap.String("status", job.Status))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Import job fetched successfully",
+		"data":    job,
+	})
+}
+
+// GetImportJobErrors godoc
+// @Summary Download the error report of an import job
+// @Description CSV of the rows that could not be imported, with the reason for each.
+// @Tags Import
+// @Produce text/csv
+// @Param userId path int true "User ID"
+// @Param jobId path int true "Import Job ID"
+// @Success 200 {string} string "CSV error report"
+// @Failure 404 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/imports/{jobId}/errors [get]
+func (c *ImportController) GetImportJobErrors(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ImportController.GetImportJobErrors Begin", zap.String("userID", ctx.Params("userId")), zap.String("jobID", ctx.Params("jobId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+	job, err := c.findImportJob(ctx, log, user)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != models.ImportJobCompleted {
+		return apperror.Conflict("Import job has not completed yet")
+	}
+	if !job.HasErrors {
+		return apperror.NotFound("Every row was imported; there is no error report")
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="import-%d-errors.csv"`, job.ID))
+	return ctx.Status(fiber.StatusOK).SendString(job.ErrorReport)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/validation"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type LogController struct {
+	Validate *validator.Validate
+}
+
+func NewLogController() *LogController {
+	return &LogController{
+		Validate: validation.New(),
+	}
+}
+
+// LogLevelRequest changes the level of the application log.
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error"`
+}
+
+// GetLogLevel godoc
+// @Summary Get the log level
+// @Description Returns the current level of the application log. Admins only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string, data=LogLevelRequest}
+// @Failure 401 {object} apperror.Problem
+// @Failure 403 {object} apperror.Problem
+// @Router /admin/log-level [get]
+func (c *LogController) GetLogLevel(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{
+		"message": "Log level fetched successfully",
+		"data":    LogLevelRequest{Level: logger.Level().String()},
+	})
+}
+
+// UpdateLogLevel godoc
+// @Summary Change the log level
+// @Description Changes the level of the application log until the server restarts, e.g. to debug a problem in production without a redeploy. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body LogLevelRequest true "New level"
+// @Success 200 {object} fiber.Map{message=string, data=LogLevelRequest}
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 403 {object} apperror.Problem
+// @Router /admin/log-level [put]
+func (c *LogController) UpdateLogLevel(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("LogController.UpdateLogLevel Begin")
+
+	var req LogLevelRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Warn("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+	req.Level = strings.ToLower(strings.TrimSpace(req.Level))
+	if err := c.Validate.Struct(&req); err != nil {
+		return apperror.InvalidField("level", "level must be one of debug, info, warn or error")
+	}
+
+	previous := logger.Level()
+	if err := logger.SetLevel(req.Level); err != nil {
+		return apperror.InvalidField("level", err.Error())
+	}
+	// Logged at Warn so the change shows up at every level.
+	log.Warn("Log level changed", zap.Stringer("from", previous), zap.String("to", req.Level))
+	return ctx.JSON(fiber.Map{
+		"message": "Log level updated successfully",
+		"data":    LogLevelRequest{Level: req.Level},
+	})
+}
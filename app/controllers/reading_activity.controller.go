@@ -2,9 +2,10 @@ package controllers
 
 import (
 	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
 	"ayo-baca-buku/app/util/logger"
-	"strings"
-	"time"
+	"ayo-baca-buku/app/util/metrics"
+	"ayo-baca-buku/app/util/validation"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -20,7 +21,7 @@ type ReadingActivityController struct {
 func NewReadingActivityController(DB *gorm.DB) *ReadingActivityController {
 	return &ReadingActivityController{
 		DB:       DB,
-		Validate: validator.New(),
+		Validate: validation.New(),
 	}
 }
 
@@ -32,44 +33,35 @@ func NewReadingActivityController(DB *gorm.DB) *ReadingActivityController {
 // @Produce json
 // @Param reading_activity body models.ReadingActivityCreateRequest true "Reading Activity Create Payload"
 // @Success 201 {object} fiber.Map{message=string, data=models.ReadingActivity}
-// @Failure 400 {object} fiber.Map{message=string, errors=map[string]string}
-// @Failure 404 {object} fiber.Map{message=string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /reading-activities [post]
 func (c *ReadingActivityController) CreateReadingActivity(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
+	log := logger.FromContext(ctx.UserContext())
 	log.Info("ReadingActivityController.CreateReadingActivity Begin")
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var req models.ReadingActivityCreateRequest
 	if err := ctx.BodyParser(&req); err != nil {
 		log.Error("Failed to parse request body", zap.Error(err))
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid Request",
-			"errors":  map[string]string{"body": "Failed to parse request body"},
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	if err := c.Validate.Struct(&req); err != nil {
 		log.Error("Validation failed for ReadingActivity creation", zap.Error(err))
-		validationErrors := make(map[string]string)
-		for _, vErr := range err.(validator.ValidationErrors) {
-			validationErrors[strings.ToLower(vErr.Field())] = vErr.Error()
-		}
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Validation failed",
-			"errors":  validationErrors,
-		})
+		return apperror.Validation(err)
 	}
 
 	// Verify the UserBook exists
 	var userBook models.UserBook
-	if err := c.DB.First(&userBook, req.UserBookID).Error; err != nil {
+	if err := db.First(&userBook, req.UserBookID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			log.Warn("UserBook not found for ReadingActivity creation", zap.Uint("userBookID", req.UserBookID))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User book not found"})
+			return apperror.NotFound("User book not found")
 		}
 		log.Error("Failed to check UserBook existence", zap.Error(err), zap.Uint("userBookID", req.UserBookID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Error checking user book"})
+		return apperror.Internal("Error checking user book")
 	}
 
 	// TODO: Authorization check: Does the authenticated user own this UserBook?
@@ -79,12 +71,13 @@ func (c *ReadingActivityController) CreateReadingActivity(ctx *fiber.Ctx) error
 		PagesRead:   req.PagesRead,
 		StartPage:   req.StartPage,
 		EndPage:     req.EndPage,
+		Duration:    req.Duration,
 		Notes:       req.Notes,
 		ReadingDate: req.ReadingDate,
 	}
 
 	// Use a transaction to ensure both activity creation and book update succeed or fail together.
-	err := c.DB.Transaction(func(tx *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
 		// 1. Create the reading activity
 		if err := tx.Create(&activity).Error; err != nil {
 			return err
@@ -102,11 +95,10 @@ func (c *ReadingActivityController) CreateReadingActivity(ctx *fiber.Ctx) error
 
 	if err != nil {
 		log.Error("Failed to create ReadingActivity and update UserBook", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to create reading activity",
-		})
+		return apperror.Internal("Failed to create reading activity")
 	}
 
+	metrics.ActivitiesLogged.Inc()
 	log.Info("ReadingActivity created successfully", zap.Uint("activityID", activity.ID))
 	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Reading activity created successfully",
@@ -123,44 +115,38 @@ func (c *ReadingActivityController) CreateReadingActivity(ctx *fiber.Ctx) error
 // @Param activityId path int true "Reading Activity ID"
 // @Param reading_activity_update body models.ReadingActivityUpdateRequest true "Reading Activity Update Payload"
 // @Success 200 {object} fiber.Map{message=string, data=models.ReadingActivity}
-// @Failure 400 {object} fiber.Map{message=string, errors=map[string]string}
-// @Failure 404 {object} fiber.Map{message=string} "ReadingActivity not found"
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem "ReadingActivity not found"
+// @Failure 500 {object} apperror.Problem
 // @Router /reading-activities/{activityId} [put]
 func (c *ReadingActivityController) UpdateReadingActivity(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
-	activityIDStr := ctx.Params("activityId")
-	log.Info("ReadingActivityController.UpdateReadingActivity Begin", zap.String("activityID", activityIDStr))
+	log := logger.FromContext(ctx.UserContext())
+	activityID, err := paramID(ctx, "activityId")
+	if err != nil {
+		return err
+	}
+	log.Info("ReadingActivityController.UpdateReadingActivity Begin", zap.Uint("activityID", activityID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var req models.ReadingActivityUpdateRequest
 	if err := ctx.BodyParser(&req); err != nil {
 		log.Error("Failed to parse request body for ReadingActivity update", zap.Error(err))
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid Request",
-			"errors":  map[string]string{"body": "Failed to parse request body"},
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	if err := c.Validate.Struct(&req); err != nil {
 		log.Error("Validation failed for ReadingActivity update", zap.Error(err))
-		validationErrors := make(map[string]string)
-		for _, vErr := range err.(validator.ValidationErrors) {
-			validationErrors[strings.ToLower(vErr.Field())] = vErr.Error()
-		}
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Validation failed",
-			"errors":  validationErrors,
-		})
+		return apperror.Validation(err)
 	}
 
 	var activity models.ReadingActivity
-	if err := c.DB.First(&activity, activityIDStr).Error; err != nil {
+	if err := db.Where("id = ?", activityID).First(&activity).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Warn("ReadingActivity not found for update", zap.String("activityID", activityIDStr))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Reading activity not found"})
+			log.Warn("ReadingActivity not found for update", zap.Uint("activityID", activityID))
+			return apperror.NotFound("Reading activity not found")
 		}
-		log.Error("Failed to fetch ReadingActivity for update", zap.Error(err), zap.String("activityID", activityIDStr))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch reading activity"})
+		log.Error("Failed to fetch ReadingActivity for update", zap.Error(err), zap.Uint("activityID", activityID))
+		return apperror.Internal("Failed to fetch reading activity")
 	}
 
 	// TODO: Authorization check.
@@ -175,6 +161,9 @@ func (c *ReadingActivityController) UpdateReadingActivity(ctx *fiber.Ctx) error
 	if req.EndPage != nil {
 		activity.EndPage = *req.EndPage
 	}
+	if req.Duration != nil {
+		activity.Duration = *req.Duration
+	}
 	if req.Notes != "" { // Assuming empty string means "not provided"
 		activity.Notes = req.Notes
 	}
@@ -186,11 +175,9 @@ func (c *ReadingActivityController) UpdateReadingActivity(ctx *fiber.Ctx) error
 	// as this can have complex side-effects (e.g., if this is not the latest activity).
 	// This would require more complex business logic.
 
-	if err := c.DB.Save(&activity).Error; err != nil {
+	if err := db.Save(&activity).Error; err != nil {
 		log.Error("Failed to update ReadingActivity in database", zap.Error(err), zap.Uint("activityID", activity.ID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to update reading activity",
-		})
+		return apperror.Internal("Failed to update reading activity")
 	}
 
 	log.Info("ReadingActivity updated successfully", zap.Uint("activityID", activity.ID))
@@ -208,32 +195,34 @@ func (c *ReadingActivityController) UpdateReadingActivity(ctx *fiber.Ctx) error
 // @Produce json
 // @Param activityId path int true "Reading Activity ID"
 // @Success 200 {object} fiber.Map{message=string}
-// @Failure 404 {object} fiber.Map{message=string} "ReadingActivity not found"
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem "ReadingActivity not found"
+// @Failure 500 {object} apperror.Problem
 // @Router /reading-activities/{activityId} [delete]
 func (c *ReadingActivityController) DeleteReadingActivity(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
-	activityIDStr := ctx.Params("activityId")
-	log.Info("ReadingActivityController.DeleteReadingActivity Begin", zap.String("activityID", activityIDStr))
+	log := logger.FromContext(ctx.UserContext())
+	activityID, err := paramID(ctx, "activityId")
+	if err != nil {
+		return err
+	}
+	log.Info("ReadingActivityController.DeleteReadingActivity Begin", zap.Uint("activityID", activityID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var activity models.ReadingActivity
-	if err := c.DB.First(&activity, activityIDStr).Error; err != nil {
+	if err := db.Where("id = ?", activityID).First(&activity).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Warn("ReadingActivity not found for deletion", zap.String("activityID", activityIDStr))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Reading activity not found"})
+			log.Warn("ReadingActivity not found for deletion", zap.Uint("activityID", activityID))
+			return apperror.NotFound("Reading activity not found")
 		}
-		log.Error("Failed to fetch ReadingActivity for deletion", zap.Error(err), zap.String("activityID", activityIDStr))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch reading activity"})
+		log.Error("Failed to fetch ReadingActivity for deletion", zap.Error(err), zap.Uint("activityID", activityID))
+		return apperror.Internal("Failed to fetch reading activity")
 	}
 
 	// TODO: Authorization check.
 
 	// Perform hard delete
-	if err := c.DB.Unscoped().Delete(&activity).Error; err != nil {
+	if err := db.Unscoped().Delete(&activity).Error; err != nil {
 		log.Error("Failed to delete ReadingActivity from database", zap.Error(err), zap.Uint("activityID", activity.ID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to delete reading activity",
-		})
+		return apperror.Internal("Failed to delete reading activity")
 	}
 
 	// Note: Deleting an activity does not automatically adjust the UserBook's CurrentPage.
@@ -250,33 +239,35 @@ func (c *ReadingActivityController) DeleteReadingActivity(ctx *fiber.Ctx) error
 // @Produce json
 // @Param userBookId path int true "UserBook ID"
 // @Success 200 {object} fiber.Map{message=string, data=[]models.ReadingActivity}
-// @Failure 404 {object} fiber.Map{message=string} "UserBook not found"
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem "UserBook not found"
+// @Failure 500 {object} apperror.Problem
 // @Router /userbooks/{userBookId}/activities [get]
 func (c *ReadingActivityController) GetAllReadingActivitiesForUserBook(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
-	userBookIDStr := ctx.Params("userBookId")
-	log.Info("ReadingActivityController.GetAllReadingActivitiesForUserBook Begin", zap.String("userBookID", userBookIDStr))
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "userBookId")
+	if err != nil {
+		return err
+	}
+	log.Info("ReadingActivityController.GetAllReadingActivitiesForUserBook Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	// Validate UserBookID exists
 	var userBook models.UserBook
-	if err := c.DB.First(&userBook, userBookIDStr).Error; err != nil {
+	if err := db.Where("id = ?", userBookID).First(&userBook).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Warn("UserBook not found when listing activities", zap.String("userBookID", userBookIDStr))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User book not found"})
+			log.Warn("UserBook not found when listing activities", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
 		}
-		log.Error("Failed to verify UserBook existence for listing activities", zap.Error(err), zap.String("userBookID", userBookIDStr))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Error verifying user book"})
+		log.Error("Failed to verify UserBook existence for listing activities", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Error verifying user book")
 	}
 
 	// TODO: Authorization check: Does the authenticated user own this UserBook?
 
 	var activities []models.ReadingActivity
-	if err := c.DB.Where("user_book_id = ?", userBook.ID).Order("reading_date DESC, created_at DESC").Find(&activities).Error; err != nil {
+	if err := db.Where("user_book_id = ?", userBook.ID).Order("reading_date DESC, created_at DESC").Find(&activities).Error; err != nil {
 		log.Error("Failed to fetch reading activities from database", zap.Error(err), zap.Uint("userBookID", userBook.ID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to fetch reading activities",
-		})
+		return apperror.Internal("Failed to fetch reading activities")
 	}
 
 	log.Info("Reading activities fetched successfully for UserBook", zap.Uint("userBookID", userBook.ID), zap.Int("count", len(activities)))
@@ -294,33 +285,35 @@ func (c *ReadingActivityController) GetAllReadingActivitiesForUserBook(ctx *fibe
 // @Produce json
 // @Param activityId path int true "Reading Activity ID"
 // @Success 200 {object} fiber.Map{message=string, data=models.ReadingActivity}
-// @Failure 404 {object} fiber.Map{message=string} "ReadingActivity not found"
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem "ReadingActivity not found"
+// @Failure 500 {object} apperror.Problem
 // @Router /reading-activities/{activityId} [get]
 func (c *ReadingActivityController) GetReadingActivityByID(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
-	activityIDStr := ctx.Params("activityId")
-	log.Info("ReadingActivityController.GetReadingActivityByID Begin", zap.String("activityID", activityIDStr))
+	log := logger.FromContext(ctx.UserContext())
+	activityID, err := paramID(ctx, "activityId")
+	if err != nil {
+		return err
+	}
+	log.Info("ReadingActivityController.GetReadingActivityByID Begin", zap.Uint("activityID", activityID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var activity models.ReadingActivity
 	// Preload UserBook to provide context.
-	if err := c.DB.Preload("UserBook").First(&activity, activityIDStr).Error; err != nil {
+	if err := db.Preload("UserBook").Where("id = ?", activityID).First(&activity).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Warn("ReadingActivity not found by ID", zap.String("activityID", activityIDStr))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Reading activity not found"})
+			log.Warn("ReadingActivity not found by ID", zap.Uint("activityID", activityID))
+			return apperror.NotFound("Reading activity not found")
 		}
-		log.Error("Failed to fetch ReadingActivity by ID from database", zap.Error(err), zap.String("activityID", activityIDStr))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to fetch reading activity",
-		})
+		log.Error("Failed to fetch ReadingActivity by ID from database", zap.Error(err), zap.Uint("activityID", activityID))
+		return apperror.Internal("Failed to fetch reading activity")
 	}
 
 	// TODO: Authorization check: Does the authenticated user own the UserBook associated with this activity?
 	// For example, after fetching activity:
 	// var userBook models.UserBook
-	// if err := c.DB.First(&userBook, activity.UserBookID).Error; err == nil {
+	// if err := db.First(&userBook, activity.UserBookID).Error; err == nil {
 	//   if authenticatedUserID != userBook.UserID && !IsAdmin(authenticatedUser) {
-	//     return ctx.Status(fiber.StatusForbidden).JSON(...)
+	//     return apperror.Forbidden(...)
 	//   }
 	// } else { /* handle error fetching userbook for auth check */ }
 
@@ -0,0 +1,535 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/streak"
+	"ayo-baca-buku/app/util/validation"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ReadingGoalController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewReadingGoalController(DB *gorm.DB) *ReadingGoalController {
+	return &ReadingGoalController{
+		DB:       DB,
+		Validate: validation.New(),
+	}
+}
+
+// goalStates whitelists the state query parameter of GetAllReadingGoals.
+var goalStates = map[string]bool{"all": true, "active": true, "upcoming": true, "past": true}
+
+// recentlyEndedGoals caps how many past goals the summary returns.
+const recentlyEndedGoals = 5
+
+func defaultGoalTitle(goalType string, target int, start, end time.Time) string {
+	period := fmt.Sprintf("%s - %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if start.Month() == time.January && start.Day() == 1 && end.Equal(start.AddDate(1, 0, -1)) {
+		period = fmt.Sprintf("in %d", start.Year())
+	}
+	switch goalType {
+	case models.GoalTypeBooks:
+		return fmt.Sprintf("%d books %s", target, period)
+	case models.GoalTypePages:
+		return fmt.Sprintf("%d pages %s", target, period)
+	default:
+		return fmt.Sprintf("%d pages per day %s", target, period)
+	}
+}
+
+// calculateGoalProgress measures a goal against the user's finished books or
+// reading activities. Goal dates are calendar days in the user's timezone.
+func calculateGoalProgress(db *gorm.DB, user *models.User, goal *models.ReadingGoal, now time.Time) (models.ReadingGoalProgress, error) {
+	loc := userLocation(user)
+	today := streak.Day(now, loc)
+	start := streak.Day(goal.StartDate, time.UTC)
+	end := streak.Day(goal.EndDate, time.UTC)
+
+	progress := models.ReadingGoalProgress{
+		TotalDays:   int(end.Sub(start).Hours()/24) + 1,
+		TargetTotal: int64(goal.Target),
+	}
+	switch {
+	case today.Before(start):
+		progress.ElapsedDays = 0
+	case today.After(end):
+		progress.ElapsedDays = progress.TotalDays
+	default:
+		progress.ElapsedDays = int(today.Sub(start).Hours()/24) + 1
+	}
+	progress.RemainingDays = progress.TotalDays - progress.ElapsedDays
+
+	from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	to := time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, loc)
+
+	if goal.Type == models.GoalTypeBooks {
+		if err := db.Model(&models.UserBook{}).
+			Where("user_id = ? AND status = ?", goal.UserID, "finished").
+			Where("end_date >= ? AND end_date < ?", from, to).
+			Count(&progress.Current).Error; err != nil {
+			return progress, err
+		}
+	} else {
+		days, err := loadReadingDays(db, user, &from, &to)
+		if err != nil {
+			return progress, err
+		}
+		met := 0
+		for _, day := range days {
+			progress.Current += day.PagesRead
+			if day.PagesRead >= int64(goal.Target) {
+				met++
+			}
+		}
+		if goal.Type == models.GoalTypePagesPerDay {
+			progress.TargetTotal = int64(goal.Target) * int64(progress.TotalDays)
+			progress.DaysMetTarget = &met
+		}
+	}
+
+	if progress.TargetTotal > 0 {
+		progress.PercentComplete = roundTo(math.Min(float64(progress.Current)/float64(progress.TargetTotal)*100, 100), 2)
+	}
+	progress.Expected = roundTo(float64(progress.TargetTotal)*float64(progress.ElapsedDays)/float64(progress.TotalDays), 2)
+	if progress.ElapsedDays > 0 {
+		progress.Projected = roundTo(float64(progress.Current)/float64(progress.ElapsedDays)*float64(progress.TotalDays), 2)
+	}
+
+	remaining := max(progress.TargetTotal-progress.Current, 0)
+	switch {
+	case progress.Current >= progress.TargetTotal:
+		progress.Status = models.GoalStatusCompleted
+	case today.After(end):
+		progress.Status = models.GoalStatusFailed
+	case today.Before(start):
+		progress.Status = models.GoalStatusUpcoming
+		progress.RequiredPerDay = roundTo(float64(remaining)/float64(progress.TotalDays), 2)
+	default:
+		// Today still counts as a day to read on.
+		progress.RequiredPerDay = roundTo(float64(remaining)/float64(progress.RemainingDays+1), 2)
+		if float64(progress.Current) >= progress.Expected {
+			progress.Status = models.GoalStatusOnTrack
+		} else {
+			progress.Status = models.GoalStatusBehind
+		}
+	}
+
+	return progress, nil
+}
+
+func (c *ReadingGoalController) withProgress(db *gorm.DB, user *models.User, goals []models.ReadingGoal) ([]models.ReadingGoalWithProgress, error) {
+	now := time.Now()
+	result := make([]models.ReadingGoalWithProgress, 0, len(goals))
+	for i := range goals {
+		progress, err := calculateGoalProgress(db, user, &goals[i], now)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, models.ReadingGoalWithProgress{ReadingGoal: goals[i], Progress: progress})
+	}
+	return result, nil
+}
+
+// CreateReadingGoal godoc
+// @Summary Create a reading goal
+// @Description Create a goal such as "24 books in 2026" (type=books, year=2026) or "20 pages per day" for a date range.
+// @Tags ReadingGoal
+// @Accept json
+// @Produce json
+// @Param goal body models.ReadingGoalCreateRequest true "Reading Goal Create Payload"
+// @Success 201 {object} fiber.Map{message=string, data=models.ReadingGoalWithProgress}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /goals [post]
+func (c *ReadingGoalController) CreateReadingGoal(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReadingGoalController.CreateReadingGoal Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ReadingGoalCreateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for ReadingGoal creation", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	var startDate, endDate time.Time
+	if req.StartDate != "" {
+		startDate, _ = time.Parse("2006-01-02", req.StartDate)
+		endDate, _ = time.Parse("2006-01-02", req.EndDate)
+	} else {
+		startDate = time.Date(req.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		endDate = startDate.AddDate(1, 0, -1)
+	}
+	if endDate.Before(startDate) {
+		return apperror.InvalidField("end_date", "end_date must not be before start_date")
+	}
+
+	var user models.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("User not found for ReadingGoal creation", zap.Uint("userID", req.UserID))
+			return apperror.InvalidField("user_id", "User not found")
+		}
+		log.Error("Failed to check user existence", zap.Error(err), zap.Uint("userID", req.UserID))
+		return apperror.Internal("Error checking user")
+	}
+
+	title := req.Title
+	if title == "" {
+		title = defaultGoalTitle(req.Type, req.Target, startDate, endDate)
+	}
+
+	goal := models.ReadingGoal{
+		UserID:    req.UserID,
+		Title:     title,
+		Type:      req.Type,
+		Target:    req.Target,
+		StartDate: startDate,
+		EndDate:   endDate,
+		CreatedBy: int64(req.UserID), // Placeholder for actor ID
+		UpdatedBy: int64(req.UserID), // Placeholder for actor ID
+	}
+	if err := db.Create(&goal).Error; err != nil {
+		log.Error("Failed to create ReadingGoal in database", zap.Error(err))
+		return apperror.Internal("Failed to create reading goal")
+	}
+
+	progress, err := calculateGoalProgress(db, &user, &goal, time.Now())
+	if err != nil {
+		log.Error("Failed to calculate ReadingGoal progress", zap.Error(err), zap.Uint("goalID", goal.ID))
+		return apperror.Internal("Failed to calculate goal progress")
+	}
+
+	log.Info("ReadingGoal created successfully", zap.Uint("goalID", goal.ID))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Reading goal created successfully",
+		"data":    models.ReadingGoalWithProgress{ReadingGoal: goal, Progress: progress},
+	})
+}
+
+// GetAllReadingGoals godoc
+// @Summary List a user's reading goals
+// @Description List goals with their progress. Use state=past for the goal history.
+// @Tags ReadingGoal
+// @Accept json
+// @Produce json
+// @Param user_id query int true "User ID"
+// @Param state query string false "Filter by state" Enums(all, active, upcoming, past) default(all)
+// @Success 200 {object} fiber.Map{message=string, data=[]models.ReadingGoalWithProgress}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /goals [get]
+func (c *ReadingGoalController) GetAllReadingGoals(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReadingGoalController.GetAllReadingGoals Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	userID := ctx.QueryInt("user_id")
+	state := ctx.Query("state", "all")
+	invalid := apperror.Invalid()
+	if userID <= 0 {
+		invalid.WithField("user_id", "user_id is required")
+	}
+	if !goalStates[state] {
+		invalid.WithField("state", "state must be one of all, active, upcoming, past")
+	}
+	if len(invalid.Fields) > 0 {
+		return invalid
+	}
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("User not found when listing goals", zap.Int("userID", userID))
+			return apperror.NotFound("User not found")
+		}
+		log.Error("Failed to fetch user for goals", zap.Error(err), zap.Int("userID", userID))
+		return apperror.Internal("Failed to fetch user")
+	}
+
+	today := streak.Day(time.Now(), userLocation(&user))
+	query := db.Where("user_id = ?", user.ID)
+	switch state {
+	case "active":
+		query = query.Where("start_date <= ? AND end_date >= ?", today, today)
+	case "upcoming":
+		query = query.Where("start_date > ?", today)
+	case "past":
+		query = query.Where("end_date < ?", today)
+	}
+
+	var goals []models.ReadingGoal
+	if err := query.Order("start_date DESC, id DESC").Find(&goals).Error; err != nil {
+		log.Error("Failed to fetch reading goals", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading goals")
+	}
+
+	result, err := c.withProgress(db, &user, goals)
+	if err != nil {
+		log.Error("Failed to calculate goal progress", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to calculate goal progress")
+	}
+
+	log.Info("Reading goals fetched successfully", zap.Uint("userID", user.ID), zap.Int("count", len(result)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading goals fetched successfully",
+		"data":    result,
+	})
+}
+
+// GetReadingGoalByID godoc
+// @Summary Get a reading goal
+// @Description Get a single goal with its progress and projection.
+// @Tags ReadingGoal
+// @Accept json
+// @Produce json
+// @Param id path int true "Reading Goal ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingGoalWithProgress}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /goals/{id} [get]
+func (c *ReadingGoalController) GetReadingGoalByID(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	goalID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	log.Info("ReadingGoalController.GetReadingGoalByID Begin", zap.Uint("goalID", goalID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var goal models.ReadingGoal
+	if err := db.Preload("User").Where("id = ?", goalID).First(&goal).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("ReadingGoal not found by ID", zap.Uint("goalID", goalID))
+			return apperror.NotFound("Reading goal not found")
+		}
+		log.Error("Failed to fetch ReadingGoal by ID", zap.Error(err), zap.Uint("goalID", goalID))
+		return apperror.Internal("Failed to fetch reading goal")
+	}
+
+	progress, err := calculateGoalProgress(db, &goal.User, &goal, time.Now())
+	if err != nil {
+		log.Error("Failed to calculate ReadingGoal progress", zap.Error(err), zap.Uint("goalID", goal.ID))
+		return apperror.Internal("Failed to calculate goal progress")
+	}
+
+	log.Info("ReadingGoal fetched successfully by ID", zap.Uint("goalID", goal.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading goal fetched successfully",
+		"data":    models.ReadingGoalWithProgress{ReadingGoal: goal, Progress: progress},
+	})
+}
+
+// UpdateReadingGoal godoc
+// @Summary Update a reading goal
+// @Description Change the title, target or dates of a goal. The goal type cannot be changed.
+// @Tags ReadingGoal
+// @Accept json
+// @Produce json
+// @Param id path int true "Reading Goal ID"
+// @Param goal body models.ReadingGoalUpdateRequest true "Reading Goal Update Payload"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingGoalWithProgress}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /goals/{id} [put]
+func (c *ReadingGoalController) UpdateReadingGoal(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	goalID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	log.Info("ReadingGoalController.UpdateReadingGoal Begin", zap.Uint("goalID", goalID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ReadingGoalUpdateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body for ReadingGoal update", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for ReadingGoal update", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	var goal models.ReadingGoal
+	if err := db.Preload("User").Where("id = ?", goalID).First(&goal).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("ReadingGoal not found for update", zap.Uint("goalID", goalID))
+			return apperror.NotFound("Reading goal not found")
+		}
+		log.Error("Failed to fetch ReadingGoal for update", zap.Error(err), zap.Uint("goalID", goalID))
+		return apperror.Internal("Failed to fetch reading goal")
+	}
+
+	// TODO: Authorization check: Does the authenticated user own this goal?
+
+	if req.Title != "" {
+		goal.Title = req.Title
+	}
+	if req.Target != nil {
+		goal.Target = *req.Target
+	}
+	if req.StartDate != "" {
+		goal.StartDate, _ = time.Parse("2006-01-02", req.StartDate)
+	}
+	if req.EndDate != "" {
+		goal.EndDate, _ = time.Parse("2006-01-02", req.EndDate)
+	}
+	if goal.EndDate.Before(goal.StartDate) {
+		return apperror.InvalidField("end_date", "end_date must not be before start_date")
+	}
+	goal.UpdatedBy = int64(goal.UserID) // Placeholder
+
+	if err := db.Omit("User").Save(&goal).Error; err != nil {
+		log.Error("Failed to update ReadingGoal in database", zap.Error(err), zap.Uint("goalID", goal.ID))
+		return apperror.Internal("Failed to update reading goal")
+	}
+
+	progress, err := calculateGoalProgress(db, &goal.User, &goal, time.Now())
+	if err != nil {
+		log.Error("Failed to calculate ReadingGoal progress", zap.Error(err), zap.Uint("goalID", goal.ID))
+		return apperror.Internal("Failed to calculate goal progress")
+	}
+
+	log.Info("ReadingGoal updated successfully", zap.Uint("goalID", goal.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading goal updated successfully",
+		"data":    models.ReadingGoalWithProgress{ReadingGoal: goal, Progress: progress},
+	})
+}
+
+// DeleteReadingGoal godoc
+// @Summary Soft delete a reading goal
+// @Description Soft delete a reading goal by its ID.
+// @Tags ReadingGoal
+// @Accept json
+// @Produce json
+// @Param id path int true "Reading Goal ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /goals/{id} [delete]
+func (c *ReadingGoalController) DeleteReadingGoal(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	goalID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	log.Info("ReadingGoalController.DeleteReadingGoal Begin", zap.Uint("goalID", goalID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var goal models.ReadingGoal
+	if err := db.Where("id = ?", goalID).First(&goal).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("ReadingGoal not found for deletion", zap.Uint("goalID", goalID))
+			return apperror.NotFound("Reading goal not found")
+		}
+		log.Error("Failed to fetch ReadingGoal for deletion", zap.Error(err), zap.Uint("goalID", goalID))
+		return apperror.Internal("Failed to fetch reading goal")
+	}
+
+	if err := db.Model(&goal).Update("DeletedBy", int64(goal.UserID)).Error; err != nil {
+		log.Error("Failed to update DeletedBy for ReadingGoal soft delete", zap.Error(err), zap.Uint("goalID", goal.ID))
+	}
+
+	if err := db.Delete(&goal).Error; err != nil {
+		log.Error("Failed to soft delete ReadingGoal", zap.Error(err), zap.Uint("goalID", goal.ID))
+		return apperror.Internal("Failed to delete reading goal")
+	}
+
+	log.Info("ReadingGoal soft deleted successfully", zap.Uint("goalID", goal.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Reading goal deleted successfully"})
+}
+
+// GetReadingGoalSummary godoc
+// @Summary Summarise a user's reading goals
+// @Description Active goals with on-track/behind status, upcoming goals, the most recently ended goals and completed/failed counts over the whole history.
+// @Tags ReadingGoal
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingGoalSummary}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/goals/summary [get]
+func (c *ReadingGoalController) GetReadingGoalSummary(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReadingGoalController.GetReadingGoalSummary Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	var goals []models.ReadingGoal
+	if err := db.Where("user_id = ?", user.ID).Order("end_date DESC, id DESC").Find(&goals).Error; err != nil {
+		log.Error("Failed to fetch reading goals", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading goals")
+	}
+
+	all, err := c.withProgress(db, user, goals)
+	if err != nil {
+		log.Error("Failed to calculate goal progress", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to calculate goal progress")
+	}
+
+	summary := models.ReadingGoalSummary{
+		UserID:        user.ID,
+		Active:        []models.ReadingGoalWithProgress{},
+		Upcoming:      []models.ReadingGoalWithProgress{},
+		RecentlyEnded: []models.ReadingGoalWithProgress{},
+	}
+	today := streak.Day(time.Now(), userLocation(user))
+	for _, goal := range all {
+		ended := today.After(streak.Day(goal.EndDate, time.UTC))
+		switch {
+		case goal.Progress.Status == models.GoalStatusUpcoming:
+			summary.Upcoming = append(summary.Upcoming, goal)
+		case ended:
+			if goal.Progress.Status == models.GoalStatusCompleted {
+				summary.CompletedGoals++
+			} else {
+				summary.FailedGoals++
+			}
+			if len(summary.RecentlyEnded) < recentlyEndedGoals {
+				summary.RecentlyEnded = append(summary.RecentlyEnded, goal)
+			}
+		default:
+			summary.ActiveGoals++
+			switch goal.Progress.Status {
+			case models.GoalStatusBehind:
+				summary.Behind++
+			default:
+				summary.OnTrack++
+			}
+			summary.Active = append(summary.Active, goal)
+		}
+	}
+
+	log.Info("Reading goal summary fetched successfully", zap.Uint("userID", user.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading goal summary fetched successfully",
+		"data":    summary,
+	})
+}
@@ -0,0 +1,483 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/planner"
+	"ayo-baca-buku/app/util/streak"
+	"ayo-baca-buku/app/util/validation"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ReadingPlanController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewReadingPlanController(DB *gorm.DB) *ReadingPlanController {
+	return &ReadingPlanController{
+		DB:       DB,
+		Validate: validation.New(),
+	}
+}
+
+// maxReadingPlanYears limits how far the deadline may be after the start date.
+// The schedule has an entry per day, so the span decides its size.
+const maxReadingPlanYears = 2
+
+// loadUserBookPagesPerDay sums the pages read on a UserBook per calendar day
+// in the user's timezone for activities within [from, to).
+func loadUserBookPagesPerDay(db *gorm.DB, user *models.User, userBookID uint, from, to time.Time) (map[time.Time]int64, error) {
+	var rows []struct {
+		Date      time.Time
+		PagesRead int64
+	}
+	if err := db.Model(&models.ReadingActivity{}).
+		Select("(reading_date AT TIME ZONE ?)::date AS date, SUM(pages_read) AS pages_read", userLocation(user).String()).
+		Where("user_book_id = ?", userBookID).
+		Where("reading_date >= ? AND reading_date < ?", from, to).
+		Group("1").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	pages := make(map[time.Time]int64, len(rows))
+	for _, row := range rows {
+		pages[streak.Day(row.Date, time.UTC)] = row.PagesRead
+	}
+	return pages, nil
+}
+
+// buildReadingPlanSchedule derives the schedule of a plan. plan.UserBook and
+// plan.UserBook.User must be loaded. The schedule is recalculated from the
+// book's CurrentPage, so logging activities automatically updates it.
+func buildReadingPlanSchedule(db *gorm.DB, plan *models.ReadingPlan, now time.Time) (*models.ReadingPlanSchedule, error) {
+	book := &plan.UserBook
+	user := &book.User
+	loc := userLocation(user)
+	today := streak.Day(now, loc)
+	start := streak.Day(plan.StartDate, time.UTC)
+	deadline := streak.Day(plan.Deadline, time.UTC)
+
+	restDays, err := planner.ParseRestDays(user.RestDays)
+	if err != nil {
+		restDays = map[time.Weekday]bool{}
+	}
+
+	original := planner.Schedule(start, deadline, plan.StartPage, book.TotalPages, restDays)
+
+	lastActualDay := today
+	if deadline.Before(lastActualDay) {
+		lastActualDay = deadline
+	}
+	actuals, err := loadUserBookPagesPerDay(db, user, book.ID,
+		time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc),
+		time.Date(lastActualDay.Year(), lastActualDay.Month(), lastActualDay.Day()+1, 0, 0, 0, 0, loc))
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &models.ReadingPlanSchedule{
+		ReadingPlan:  *plan,
+		BookTitle:    book.Title,
+		TotalPages:   book.TotalPages,
+		CurrentPage:  book.CurrentPage,
+		RestDays:     planner.FormatRestDays(restDays),
+		ExpectedPage: planner.TargetPageOn(original, today),
+		Completed:    book.Status == "finished" || book.CurrentPage >= book.TotalPages,
+		Days:         []models.ReadingPlanDay{},
+	}
+	if !schedule.Completed && book.CurrentPage < schedule.ExpectedPage {
+		schedule.Behind = true
+		schedule.PagesBehind = schedule.ExpectedPage - book.CurrentPage
+	}
+
+	// Recalculate from today, starting at the page the reader was on this morning
+	// so that today's target does not move while today's sessions are logged.
+	recalcStart := start
+	basePage := book.CurrentPage
+	if today.After(start) {
+		recalcStart = today
+	}
+	if recalcStart.Equal(today) {
+		basePage = max(book.CurrentPage-int(actuals[today]), 0)
+	}
+	recalculated := []planner.Day{}
+	if !schedule.Completed {
+		recalculated = planner.Schedule(recalcStart, deadline, basePage, book.TotalPages, restDays)
+	}
+
+	for _, day := range original {
+		if !day.Date.Before(recalcStart) && !schedule.Completed {
+			break
+		}
+		schedule.Days = append(schedule.Days, models.ReadingPlanDay{
+			Date:       day.Date,
+			Rest:       day.Rest,
+			Pages:      day.Pages,
+			TargetPage: day.TargetPage,
+		})
+	}
+	for _, day := range recalculated {
+		schedule.Days = append(schedule.Days, models.ReadingPlanDay{
+			Date:       day.Date,
+			Rest:       day.Rest,
+			Pages:      day.Pages,
+			TargetPage: day.TargetPage,
+		})
+		if !day.Rest {
+			schedule.ReadingDaysLeft++
+		}
+		if day.Date.Equal(today) {
+			schedule.TodayTarget = day.Pages
+		}
+	}
+	for i := range schedule.Days {
+		if !schedule.Days[i].Date.After(today) {
+			actual := actuals[schedule.Days[i].Date]
+			schedule.Days[i].ActualPages = &actual
+		}
+	}
+	if schedule.ReadingDaysLeft > 0 {
+		schedule.PagesPerReadingDay = roundTo(float64(book.TotalPages-basePage)/float64(schedule.ReadingDaysLeft), 2)
+	}
+
+	return schedule, nil
+}
+
+// findReadingPlan loads the plan of the UserBook in the userBookId path parameter,
+// including the book and its owner. As with findUserByParam, the error can be
+// returned as it is.
+func (c *ReadingPlanController) findReadingPlan(ctx *fiber.Ctx, log *zap.Logger) (*models.ReadingPlan, error) {
+	db := c.DB.WithContext(ctx.UserContext())
+
+	userBookID, err := paramID(ctx, "userBookId")
+	if err != nil {
+		return nil, err
+	}
+
+	var plan models.ReadingPlan
+	if err := db.Preload("UserBook.User").Where("user_book_id = ?", userBookID).First(&plan).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("ReadingPlan not found", zap.Uint("userBookID", userBookID))
+			return nil, apperror.NotFound("Reading plan not found")
+		}
+		log.Error("Failed to fetch ReadingPlan", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return nil, apperror.Internal("Failed to fetch reading plan")
+	}
+	return &plan, nil
+}
+
+// CreateReadingPlan godoc
+// @Summary Create a reading plan for a user book
+// @Description Plan to finish a book by a deadline (e.g. a book club meeting). Pages are spread evenly over the days until the deadline, skipping the user's rest days. The deadline must be within 2 years of the start date.
+// @Tags ReadingPlan
+// @Accept json
+// @Produce json
+// @Param userBookId path int true "UserBook ID"
+// @Param plan body models.ReadingPlanCreateRequest true "Reading Plan Create Payload"
+// @Success 201 {object} fiber.Map{message=string, data=models.ReadingPlanSchedule}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/plan [post]
+func (c *ReadingPlanController) CreateReadingPlan(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "userBookId")
+	if err != nil {
+		return err
+	}
+	log.Info("ReadingPlanController.CreateReadingPlan Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ReadingPlanCreateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for ReadingPlan creation", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	var userBook models.UserBook
+	if err := db.Preload("User").Where("id = ?", userBookID).First(&userBook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("UserBook not found for ReadingPlan creation", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
+		}
+		log.Error("Failed to fetch UserBook for ReadingPlan creation", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to fetch user book")
+	}
+
+	// TODO: Authorization check: Does the authenticated user own this UserBook?
+
+	if userBook.Status == "finished" {
+		return apperror.InvalidField("user_book_id", "User book is already finished")
+	}
+
+	today := streak.Day(time.Now(), userLocation(&userBook.User))
+	startDate := today
+	if req.StartDate != "" {
+		startDate, _ = time.Parse("2006-01-02", req.StartDate)
+	}
+	deadline, _ := time.Parse("2006-01-02", req.Deadline)
+	if deadline.Before(startDate) || deadline.Before(today) {
+		return apperror.InvalidField("deadline", "deadline must not be before start_date or today")
+	}
+	if deadline.After(startDate.AddDate(maxReadingPlanYears, 0, 0)) {
+		return apperror.InvalidField("deadline", fmt.Sprintf("deadline must be within %d years of start_date", maxReadingPlanYears))
+	}
+
+	var count int64
+	if err := db.Model(&models.ReadingPlan{}).Where("user_book_id = ?", userBook.ID).Count(&count).Error; err != nil {
+		log.Error("Failed to check existing ReadingPlan", zap.Error(err), zap.Uint("userBookID", userBook.ID))
+		return apperror.Internal("Failed to create reading plan")
+	}
+	if count > 0 {
+		return apperror.Conflict("User book already has a reading plan")
+	}
+
+	plan := models.ReadingPlan{
+		UserBookID: userBook.ID,
+		Title:      req.Title,
+		StartDate:  startDate,
+		Deadline:   deadline,
+		StartPage:  userBook.CurrentPage,
+		CreatedBy:  int64(userBook.UserID), // Placeholder for actor ID
+		UpdatedBy:  int64(userBook.UserID), // Placeholder for actor ID
+	}
+	if err := db.Create(&plan).Error; err != nil {
+		log.Error("Failed to create ReadingPlan in database", zap.Error(err))
+		return apperror.Internal("Failed to create reading plan")
+	}
+	plan.UserBook = userBook
+
+	schedule, err := buildReadingPlanSchedule(db, &plan, time.Now())
+	if err != nil {
+		log.Error("Failed to build ReadingPlan schedule", zap.Error(err), zap.Uint("planID", plan.ID))
+		return apperror.Internal("Failed to build reading plan schedule")
+	}
+
+	log.Info("ReadingPlan created successfully", zap.Uint("planID", plan.ID))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Reading plan created successfully",
+		"data":    schedule,
+	})
+}
+
+// GetReadingPlan godoc
+// @Summary Get the reading plan of a user book
+// @Description Get the daily schedule. Past days show the original targets and actual pages read; today and later are recalculated from the current page.
+// @Tags ReadingPlan
+// @Accept json
+// @Produce json
+// @Param userBookId path int true "UserBook ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingPlanSchedule}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/plan [get]
+func (c *ReadingPlanController) GetReadingPlan(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReadingPlanController.GetReadingPlan Begin", zap.String("userBookID", ctx.Params("userBookId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	plan, err := c.findReadingPlan(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := buildReadingPlanSchedule(db, plan, time.Now())
+	if err != nil {
+		log.Error("Failed to build ReadingPlan schedule", zap.Error(err), zap.Uint("planID", plan.ID))
+		return apperror.Internal("Failed to build reading plan schedule")
+	}
+
+	log.Info("ReadingPlan fetched successfully", zap.Uint("planID", plan.ID), zap.Bool("behind", schedule.Behind))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading plan fetched successfully",
+		"data":    schedule,
+	})
+}
+
+// UpdateReadingPlan godoc
+// @Summary Update the reading plan of a user book
+// @Description Rename the plan or move its deadline. The original plan is kept so "behind schedule" is still measured against it.
+// @Tags ReadingPlan
+// @Accept json
+// @Produce json
+// @Param userBookId path int true "UserBook ID"
+// @Param plan body models.ReadingPlanUpdateRequest true "Reading Plan Update Payload"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingPlanSchedule}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/plan [put]
+func (c *ReadingPlanController) UpdateReadingPlan(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReadingPlanController.UpdateReadingPlan Begin", zap.String("userBookID", ctx.Params("userBookId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ReadingPlanUpdateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body for ReadingPlan update", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for ReadingPlan update", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	plan, err := c.findReadingPlan(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"updated_by": int64(plan.UserBook.UserID)} // Placeholder for actor ID
+	if req.Title != "" {
+		plan.Title = req.Title
+		updates["title"] = plan.Title
+	}
+	if req.Deadline != "" {
+		deadline, _ := time.Parse("2006-01-02", req.Deadline)
+		startDate := streak.Day(plan.StartDate, time.UTC)
+		if deadline.Before(startDate) {
+			return apperror.InvalidField("deadline", "deadline must not be before the plan's start_date")
+		}
+		if deadline.After(startDate.AddDate(maxReadingPlanYears, 0, 0)) {
+			return apperror.InvalidField("deadline", fmt.Sprintf("deadline must be within %d years of the plan's start_date", maxReadingPlanYears))
+		}
+		plan.Deadline = deadline
+		updates["deadline"] = plan.Deadline
+	}
+
+	if err := db.Model(plan).Updates(updates).Error; err != nil {
+		log.Error("Failed to update ReadingPlan in database", zap.Error(err), zap.Uint("planID", plan.ID))
+		return apperror.Internal("Failed to update reading plan")
+	}
+
+	schedule, err := buildReadingPlanSchedule(db, plan, time.Now())
+	if err != nil {
+		log.Error("Failed to build ReadingPlan schedule", zap.Error(err), zap.Uint("planID", plan.ID))
+		return apperror.Internal("Failed to build reading plan schedule")
+	}
+
+	log.Info("ReadingPlan updated successfully", zap.Uint("planID", plan.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading plan updated successfully",
+		"data":    schedule,
+	})
+}
+
+// DeleteReadingPlan godoc
+// @Summary Delete the reading plan of a user book
+// @Description Soft delete the reading plan of a user book.
+// @Tags ReadingPlan
+// @Accept json
+// @Produce json
+// @Param userBookId path int true "UserBook ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/plan [delete]
+func (c *ReadingPlanController) DeleteReadingPlan(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReadingPlanController.DeleteReadingPlan Begin", zap.String("userBookID", ctx.Params("userBookId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	plan, err := c.findReadingPlan(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Model(plan).Update("DeletedBy", int64(plan.UserBook.UserID)).Error; err != nil {
+		log.Error("Failed to update DeletedBy for ReadingPlan soft delete", zap.Error(err), zap.Uint("planID", plan.ID))
+	}
+
+	if err := db.Delete(plan).Error; err != nil {
+		log.Error("Failed to soft delete ReadingPlan", zap.Error(err), zap.Uint("planID", plan.ID))
+		return apperror.Internal("Failed to delete reading plan")
+	}
+
+	log.Info("ReadingPlan soft deleted successfully", zap.Uint("planID", plan.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Reading plan deleted successfully"})
+}
+
+// ExportReadingPlan godoc
+// @Summary Export the reading plan of a user book
+// @Description Download the daily schedule as CSV (default) or JSON.
+// @Tags ReadingPlan
+// @Produce text/csv
+// @Produce json
+// @Param userBookId path int true "UserBook ID"
+// @Param format query string false "Export format" Enums(csv, json) default(csv)
+// @Success 200 {file} file
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/plan/export [get]
+func (c *ReadingPlanController) ExportReadingPlan(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReadingPlanController.ExportReadingPlan Begin", zap.String("userBookID", ctx.Params("userBookId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	format := ctx.Query("format", "csv")
+	if format != "csv" && format != "json" {
+		return apperror.InvalidField("format", "format must be one of csv, json")
+	}
+
+	plan, err := c.findReadingPlan(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := buildReadingPlanSchedule(db, plan, time.Now())
+	if err != nil {
+		log.Error("Failed to build ReadingPlan schedule", zap.Error(err), zap.Uint("planID", plan.ID))
+		return apperror.Internal("Failed to build reading plan schedule")
+	}
+
+	filename := fmt.Sprintf("reading-plan-%d.%s", plan.UserBookID, format)
+	ctx.Attachment(filename)
+	if format == "json" {
+		return ctx.Status(fiber.StatusOK).JSON(schedule)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	writer := csv.NewWriter(ctx.Response().BodyWriter())
+	writer.Write([]string{"date", "weekday", "rest", "pages", "target_page", "actual_pages"})
+	for _, day := range schedule.Days {
+		actual := ""
+		if day.ActualPages != nil {
+			actual = strconv.FormatInt(*day.ActualPages, 10)
+		}
+		writer.Write([]string{
+			day.Date.Format("2006-01-02"),
+			day.Date.Weekday().String(),
+			strconv.FormatBool(day.Rest),
+			strconv.Itoa(day.Pages),
+			strconv.Itoa(day.TargetPage),
+			actual,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Error("Failed to write ReadingPlan CSV", zap.Error(err), zap.Uint("planID", plan.ID))
+		return err
+	}
+
+	log.Info("ReadingPlan exported successfully", zap.Uint("planID", plan.ID), zap.String("format", format))
+	return nil
+}
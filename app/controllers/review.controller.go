@@ -0,0 +1,544 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/validation"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ReviewController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewReviewController(DB *gorm.DB) *ReviewController {
+	validate := validation.New()
+	validate.RegisterValidation("half_star", validation.HalfStar)
+
+	return &ReviewController{
+		DB:       DB,
+		Validate: validate,
+	}
+}
+
+// reviewerColumns are the user columns shown next to a public review.
+func reviewerColumns(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "name", "username")
+}
+
+// sameBook matches user books with the given title and author, ignoring case
+// and surrounding whitespace. The user_books table must be aliased as ub.
+func sameBook(db *gorm.DB, title, author string) *gorm.DB {
+	return db.Where("LOWER(TRIM(ub.title)) = LOWER(TRIM(?)) AND LOWER(TRIM(ub.author)) = LOWER(TRIM(?))", title, author)
+}
+
+// calculateBookRating aggregates the public ratings of every user's copy of a book.
+func calculateBookRating(db *gorm.DB, title, author string) (*models.BookRating, error) {
+	var rows []struct {
+		Rating      float64
+		RatingCount int64
+		ReviewCount int64
+	}
+	err := sameBook(db.Table("reviews AS r").
+		Select("r.rating, COUNT(*) AS rating_count, COUNT(*) FILTER (WHERE TRIM(r.body) <> '') AS review_count").
+		Joins("JOIN user_books ub ON ub.id = r.user_book_id AND ub.deleted_at IS NULL").
+		Where("r.deleted_at IS NULL AND r.visibility = ?", "public"), title, author).
+		Group("r.rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rating := &models.BookRating{
+		Title:        strings.TrimSpace(title),
+		Author:       strings.TrimSpace(author),
+		Distribution: make(map[string]int64),
+	}
+	for stars := 1; stars <= 10; stars++ {
+		rating.Distribution[strconv.FormatFloat(float64(stars)/2, 'f', -1, 64)] = 0
+	}
+
+	var total float64
+	for _, row := range rows {
+		rating.Distribution[strconv.FormatFloat(row.Rating, 'f', -1, 64)] = row.RatingCount
+		rating.RatingCount += row.RatingCount
+		rating.ReviewCount += row.ReviewCount
+		total += row.Rating * float64(row.RatingCount)
+	}
+	if rating.RatingCount > 0 {
+		rating.AverageRating = roundTo(total/float64(rating.RatingCount), 2)
+	}
+	return rating, nil
+}
+
+// findReview loads the review of the UserBook in the userBookId path parameter.
+// As with findUserByParam, the error can be returned as it is.
+func (c *ReviewController) findReview(ctx *fiber.Ctx, log *zap.Logger) (*models.Review, error) {
+	db := c.DB.WithContext(ctx.UserContext())
+
+	userBookID, err := paramID(ctx, "userBookId")
+	if err != nil {
+		return nil, err
+	}
+
+	var review models.Review
+	if err := db.Where("user_book_id = ?", userBookID).First(&review).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("Review not found", zap.Uint("userBookID", userBookID))
+			return nil, apperror.NotFound("Review not found")
+		}
+		log.Error("Failed to fetch Review", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return nil, apperror.Internal("Failed to fetch review")
+	}
+	return &review, nil
+}
+
+// findOwnReview is findReview for changes: only the author of a review may
+// edit or delete it.
+func (c *ReviewController) findOwnReview(ctx *fiber.Ctx, log *zap.Logger) (*models.Review, error) {
+	review, err := c.findReview(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	if !isOwner(ctx, review.UserID) {
+		log.Warn("Review belongs to another user", zap.Uint("reviewID", review.ID))
+		return nil, apperror.Forbidden("You can only change your own review")
+	}
+	return review, nil
+}
+
+// CreateReview godoc
+// @Summary Review a finished user book
+// @Description Rate a finished book from 0.5 to 5 stars in half-star steps, optionally with a Markdown review. Only the owner of the user book can review it.
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userBookId path int true "UserBook ID"
+// @Param review body models.ReviewCreateRequest true "Review Create Payload"
+// @Success 201 {object} fiber.Map{message=string, data=models.Review}
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 403 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/review [post]
+func (c *ReviewController) CreateReview(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "userBookId")
+	if err != nil {
+		return err
+	}
+	log.Info("ReviewController.CreateReview Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ReviewCreateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Review creation", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	var userBook models.UserBook
+	if err := db.Where("id = ?", userBookID).First(&userBook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("UserBook not found for Review creation", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
+		}
+		log.Error("Failed to fetch UserBook for Review creation", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to fetch user book")
+	}
+
+	if !isOwner(ctx, userBook.UserID) {
+		log.Warn("UserBook belongs to another user", zap.Uint("userBookID", userBook.ID))
+		return apperror.Forbidden("You can only review your own books")
+	}
+
+	if userBook.Status != "finished" {
+		return apperror.InvalidField("user_book_id", "Only finished books can be reviewed")
+	}
+
+	var count int64
+	if err := db.Model(&models.Review{}).Where("user_book_id = ?", userBook.ID).Count(&count).Error; err != nil {
+		log.Error("Failed to check existing Review", zap.Error(err), zap.Uint("userBookID", userBook.ID))
+		return apperror.Internal("Failed to create review")
+	}
+	if count > 0 {
+		return apperror.Conflict("User book already has a review")
+	}
+
+	review := models.Review{
+		UserBookID: userBook.ID,
+		UserID:     userBook.UserID,
+		Rating:     req.Rating,
+		Body:       req.Body,
+		Spoiler:    req.Spoiler,
+		Visibility: req.Visibility,
+		CreatedBy:  int64(userBook.UserID), // Placeholder for actor ID
+		UpdatedBy:  int64(userBook.UserID), // Placeholder for actor ID
+	}
+	if review.Visibility == "" {
+		review.Visibility = "public"
+	}
+	if err := db.Create(&review).Error; err != nil {
+		log.Error("Failed to create Review in database", zap.Error(err))
+		return apperror.Internal("Failed to create review")
+	}
+
+	log.Info("Review created successfully", zap.Uint("reviewID", review.ID))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Review created successfully",
+		"data":    review,
+	})
+}
+
+// GetReview godoc
+// @Summary Get the review of a user book
+// @Description Get the current version of the review of a user book. Private reviews are only found by their author.
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userBookId path int true "UserBook ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.Review}
+// @Failure 401 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/review [get]
+func (c *ReviewController) GetReview(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReviewController.GetReview Begin", zap.String("userBookID", ctx.Params("userBookId")))
+
+	review, err := c.findReview(ctx, log)
+	if err != nil {
+		return err
+	}
+	if review.Visibility == "private" && !isOwner(ctx, review.UserID) {
+		log.Warn("Review is private", zap.Uint("reviewID", review.ID))
+		return apperror.NotFound("Review not found")
+	}
+
+	log.Info("Review fetched successfully", zap.Uint("reviewID", review.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Review fetched successfully",
+		"data":    review,
+	})
+}
+
+// UpdateReview godoc
+// @Summary Edit the review of a user book
+// @Description Edit a review. The previous version is kept in the review's edit history. Only the author can edit a review.
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userBookId path int true "UserBook ID"
+// @Param review body models.ReviewUpdateRequest true "Review Update Payload"
+// @Success 200 {object} fiber.Map{message=string, data=models.Review}
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 403 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/review [put]
+func (c *ReviewController) UpdateReview(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReviewController.UpdateReview Begin", zap.String("userBookID", ctx.Params("userBookId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ReviewUpdateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body for Review update", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Review update", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	review, err := c.findOwnReview(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	revision := models.ReviewRevision{
+		ReviewID:   review.ID,
+		Rating:     review.Rating,
+		Body:       review.Body,
+		Spoiler:    review.Spoiler,
+		Visibility: review.Visibility,
+		WrittenAt:  review.UpdatedAt,
+		CreatedBy:  int64(review.UserID), // Placeholder for actor ID
+	}
+
+	if req.Rating != nil {
+		review.Rating = *req.Rating
+	}
+	if req.Body != nil {
+		review.Body = *req.Body
+	}
+	if req.Spoiler != nil {
+		review.Spoiler = *req.Spoiler
+	}
+	if req.Visibility != "" {
+		review.Visibility = req.Visibility
+	}
+
+	changed := review.Rating != revision.Rating || review.Body != revision.Body ||
+		review.Spoiler != revision.Spoiler || review.Visibility != revision.Visibility
+	if !changed {
+		log.Info("Review unchanged", zap.Uint("reviewID", review.ID))
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Review updated successfully",
+			"data":    review,
+		})
+	}
+
+	review.EditCount++
+	review.UpdatedBy = int64(review.UserID) // Placeholder
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		return tx.Save(review).Error
+	})
+	if err != nil {
+		log.Error("Failed to update Review in database", zap.Error(err), zap.Uint("reviewID", review.ID))
+		return apperror.Internal("Failed to update review")
+	}
+
+	log.Info("Review updated successfully", zap.Uint("reviewID", review.ID), zap.Int("editCount", review.EditCount))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Review updated successfully",
+		"data":    review,
+	})
+}
+
+// DeleteReview godoc
+// @Summary Soft delete the review of a user book
+// @Description Soft delete a review. The rating no longer counts towards the book's aggregate rating. Only the author can delete a review.
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userBookId path int true "UserBook ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 401 {object} apperror.Problem
+// @Failure 403 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/review [delete]
+func (c *ReviewController) DeleteReview(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReviewController.DeleteReview Begin", zap.String("userBookID", ctx.Params("userBookId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	review, err := c.findOwnReview(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Model(review).Update("DeletedBy", int64(review.UserID)).Error; err != nil {
+		log.Error("Failed to set DeletedBy for Review", zap.Error(err), zap.Uint("reviewID", review.ID))
+		return apperror.Internal("Failed to delete review")
+	}
+	if err := db.Delete(review).Error; err != nil {
+		log.Error("Failed to soft delete Review", zap.Error(err), zap.Uint("reviewID", review.ID))
+		return apperror.Internal("Failed to delete review")
+	}
+
+	log.Info("Review soft deleted successfully", zap.Uint("reviewID", review.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Review deleted successfully"})
+}
+
+// GetReviewHistory godoc
+// @Summary Get the edit history of a review
+// @Description Get the review together with its previous versions, newest first. Private reviews, and versions written while the review was private, are only shown to the author.
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userBookId path int true "UserBook ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.Review}
+// @Failure 401 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{userBookId}/review/history [get]
+func (c *ReviewController) GetReviewHistory(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReviewController.GetReviewHistory Begin", zap.String("userBookID", ctx.Params("userBookId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	review, err := c.findReview(ctx, log)
+	if err != nil {
+		return err
+	}
+	if review.Visibility == "private" && !isOwner(ctx, review.UserID) {
+		log.Warn("Review is private", zap.Uint("reviewID", review.ID))
+		return apperror.NotFound("Review not found")
+	}
+
+	// Versions written while the review was private stay with its owner
+	query := db.Where("review_id = ?", review.ID)
+	if !isOwner(ctx, review.UserID) {
+		query = query.Where("visibility = ?", "public")
+	}
+
+	review.Revisions = []models.ReviewRevision{}
+	if err := query.Order("created_at DESC, id DESC").Find(&review.Revisions).Error; err != nil {
+		log.Error("Failed to fetch Review history", zap.Error(err), zap.Uint("reviewID", review.ID))
+		return apperror.Internal("Failed to fetch review history")
+	}
+
+	log.Info("Review history fetched successfully", zap.Uint("reviewID", review.ID), zap.Int("count", len(review.Revisions)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Review history fetched successfully",
+		"data":    review,
+	})
+}
+
+// GetUserReviews godoc
+// @Summary List a user's reviews
+// @Description List the reviews written by a user with the reviewed books, newest first. Private reviews are only listed for their author.
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userId path int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.Review}
+// @Failure 401 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/reviews [get]
+func (c *ReviewController) GetUserReviews(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ReviewController.GetUserReviews Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	query := db.Where("user_id = ?", user.ID)
+	if !isOwner(ctx, user.ID) {
+		query = query.Where("visibility = ?", "public")
+	}
+
+	reviews := []models.Review{}
+	if err := query.Preload("UserBook").Order("created_at DESC").Find(&reviews).Error; err != nil {
+		log.Error("Failed to fetch user reviews", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reviews")
+	}
+
+	log.Info("User reviews fetched successfully", zap.Uint("userID", user.ID), zap.Int("count", len(reviews)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reviews fetched successfully",
+		"data":    reviews,
+	})
+}
+
+// GetBookReviews godoc
+// @Summary List the reviews of a title
+// @Description List the public reviews of a book (matched by title and author, case-insensitive) with its aggregate rating.
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Param title query string true "Book title"
+// @Param author query string true "Book author"
+// @Param sort query string false "Sort order" Enums(recent, rating)
+// @Success 200 {object} fiber.Map{message=string, data=fiber.Map{rating=models.BookRating, reviews=[]models.Review}}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /reviews [get]
+func (c *ReviewController) GetBookReviews(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	title := ctx.Query("title")
+	author := ctx.Query("author")
+	log.Info("ReviewController.GetBookReviews Begin", zap.String("title", title), zap.String("author", author))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	if strings.TrimSpace(title) == "" || strings.TrimSpace(author) == "" {
+		return apperror.InvalidField("title", "title and author are required")
+	}
+
+	order := "reviews.created_at DESC"
+	if ctx.Query("sort") == "rating" {
+		order = "reviews.rating DESC, reviews.created_at DESC"
+	}
+
+	reviews := []models.Review{}
+	if err := sameBook(db.Joins("JOIN user_books ub ON ub.id = reviews.user_book_id AND ub.deleted_at IS NULL"), title, author).
+		Where("reviews.visibility = ?", "public").
+		Preload("User", reviewerColumns).
+		Order(order).
+		Find(&reviews).Error; err != nil {
+		log.Error("Failed to fetch book reviews", zap.Error(err), zap.String("title", title))
+		return apperror.Internal("Failed to fetch reviews")
+	}
+
+	rating, err := calculateBookRating(db, title, author)
+	if err != nil {
+		log.Error("Failed to calculate book rating", zap.Error(err), zap.String("title", title))
+		return apperror.Internal("Failed to fetch reviews")
+	}
+
+	log.Info("Book reviews fetched successfully", zap.String("title", title), zap.Int("count", len(reviews)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reviews fetched successfully",
+		"data": fiber.Map{
+			"rating":  rating,
+			"reviews": reviews,
+		},
+	})
+}
+
+// GetBookRating godoc
+// @Summary Get the aggregate rating of a title
+// @Description Average and distribution of the public ratings of a book, matched by title and author (case-insensitive).
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Param title query string true "Book title"
+// @Param author query string true "Book author"
+// @Success 200 {object} fiber.Map{message=string, data=models.BookRating}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /reviews/rating [get]
+func (c *ReviewController) GetBookRating(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	title := ctx.Query("title")
+	author := ctx.Query("author")
+	log.Info("ReviewController.GetBookRating Begin", zap.String("title", title), zap.String("author", author))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	if strings.TrimSpace(title) == "" || strings.TrimSpace(author) == "" {
+		return apperror.InvalidField("title", "title and author are required")
+	}
+
+	rating, err := calculateBookRating(db, title, author)
+	if err != nil {
+		log.Error("Failed to calculate book rating", zap.Error(err), zap.String("title", title))
+		return apperror.Internal("Failed to fetch book rating")
+	}
+
+	log.Info("Book rating fetched successfully", zap.String("title", title), zap.Int64("ratings", rating.RatingCount))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Book rating fetched successfully",
+		"data":    rating,
+	})
+}
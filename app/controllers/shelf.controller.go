@@ -0,0 +1,684 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/validation"
+	"slices"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ShelfController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewShelfController(DB *gorm.DB) *ShelfController {
+	return &ShelfController{
+		DB:       DB,
+		Validate: validation.New(),
+	}
+}
+
+// movePosition returns ids with id moved to the given index (clamped to the
+// end of the list). id is inserted when it is not in ids yet.
+func movePosition(ids []uint, id uint, position int) []uint {
+	result := make([]uint, 0, len(ids)+1)
+	for _, current := range ids {
+		if current != id {
+			result = append(result, current)
+		}
+	}
+	position = min(max(position, 0), len(result))
+	return slices.Insert(result, position, id)
+}
+
+// sameIDs reports whether a and b contain the same IDs, ignoring order.
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := slices.Clone(a)
+	sortedB := slices.Clone(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
+
+func userShelfIDs(tx *gorm.DB, userID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&models.Shelf{}).Where("user_id = ?", userID).Order("position, id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func shelfBookIDs(tx *gorm.DB, shelfID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&models.UserBookShelf{}).Where("shelf_id = ?", shelfID).Order("position, created_at").Pluck("user_book_id", &ids).Error
+	return ids, err
+}
+
+// applyShelfPositions stores the index of every shelf ID as its position.
+func applyShelfPositions(tx *gorm.DB, ids []uint) error {
+	for position, id := range ids {
+		if err := tx.Model(&models.Shelf{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyShelfBookPositions stores the index of every user book ID as its position within the shelf.
+func applyShelfBookPositions(tx *gorm.DB, shelfID uint, ids []uint) error {
+	for position, id := range ids {
+		if err := tx.Model(&models.UserBookShelf{}).Where("shelf_id = ? AND user_book_id = ?", shelfID, id).Update("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadShelfBookCounts fills BookCount for every shelf, ignoring deleted books.
+func loadShelfBookCounts(db *gorm.DB, shelves []models.Shelf) error {
+	if len(shelves) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(shelves))
+	for _, shelf := range shelves {
+		ids = append(ids, shelf.ID)
+	}
+
+	var counts []struct {
+		ShelfID   uint
+		BookCount int64
+	}
+	if err := db.Table("user_book_shelves AS ubs").
+		Select("ubs.shelf_id, COUNT(*) AS book_count").
+		Joins("JOIN user_books ub ON ub.id = ubs.user_book_id AND ub.deleted_at IS NULL").
+		Where("ubs.shelf_id IN ?", ids).
+		Group("ubs.shelf_id").
+		Scan(&counts).Error; err != nil {
+		return err
+	}
+
+	byShelf := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		byShelf[count.ShelfID] = count.BookCount
+	}
+	for i := range shelves {
+		shelves[i].BookCount = byShelf[shelves[i].ID]
+	}
+	return nil
+}
+
+// visibleShelves limits a shelf query to public shelves and the shelves of the
+// authenticated user. Private shelves are nobody else's business.
+func visibleShelves(ctx *fiber.Ctx) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if user := middlewares.CurrentUser(ctx); user != nil {
+			return db.Where("(shelves.visibility = ? OR shelves.user_id = ?)", "public", user.ID)
+		}
+		return db.Where("shelves.visibility = ?", "public")
+	}
+}
+
+func (c *ShelfController) findShelf(ctx *fiber.Ctx, log *zap.Logger) (*models.Shelf, error) {
+	db := c.DB.WithContext(ctx.UserContext())
+
+	shelfID, err := paramID(ctx, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	var shelf models.Shelf
+	if err := db.Where("id = ?", shelfID).First(&shelf).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("Shelf not found", zap.Uint("shelfID", shelfID))
+			return nil, apperror.NotFound("Shelf not found")
+		}
+		log.Error("Failed to fetch Shelf", zap.Error(err), zap.Uint("shelfID", shelfID))
+		return nil, apperror.Internal("Failed to fetch shelf")
+	}
+	return &shelf, nil
+}
+
+// CreateShelf godoc
+// @Summary Create a shelf
+// @Description Create a user-defined shelf. Without a position the shelf is appended after the user's last shelf.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Param shelf body models.ShelfCreateRequest true "Shelf Create Payload"
+// @Success 201 {object} fiber.Map{message=string, data=models.Shelf}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves [post]
+func (c *ShelfController) CreateShelf(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ShelfController.CreateShelf Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ShelfCreateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Shelf creation", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	var user models.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("User not found for Shelf creation", zap.Uint("userID", req.UserID))
+			return apperror.InvalidField("user_id", "User not found")
+		}
+		log.Error("Failed to check user existence", zap.Error(err), zap.Uint("userID", req.UserID))
+		return apperror.Internal("Error checking user")
+	}
+
+	shelf := models.Shelf{
+		UserID:      req.UserID,
+		Name:        req.Name,
+		Description: req.Description,
+		Visibility:  req.Visibility,
+		CreatedBy:   int64(req.UserID), // Placeholder for actor ID
+		UpdatedBy:   int64(req.UserID), // Placeholder for actor ID
+	}
+	if shelf.Visibility == "" {
+		shelf.Visibility = "public"
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		ids, err := userShelfIDs(tx, req.UserID)
+		if err != nil {
+			return err
+		}
+		shelf.Position = len(ids)
+		if err := tx.Create(&shelf).Error; err != nil {
+			return err
+		}
+		if req.Position == nil {
+			return nil
+		}
+		ids = movePosition(ids, shelf.ID, *req.Position)
+		shelf.Position = slices.Index(ids, shelf.ID)
+		return applyShelfPositions(tx, ids)
+	})
+	if err != nil {
+		log.Error("Failed to create Shelf in database", zap.Error(err))
+		return apperror.Internal("Failed to create shelf")
+	}
+
+	log.Info("Shelf created successfully", zap.Uint("shelfID", shelf.ID))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Shelf created successfully",
+		"data":    shelf,
+	})
+}
+
+// GetAllShelves godoc
+// @Summary List a user's shelves
+// @Description List a user's shelves in their display order with the number of books on each. Private shelves are only listed for their owner.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.Shelf}
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves [get]
+func (c *ShelfController) GetAllShelves(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ShelfController.GetAllShelves Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	userID := ctx.QueryInt("user_id")
+	if userID <= 0 {
+		return apperror.InvalidField("user_id", "user_id is required")
+	}
+
+	query := db.Where("user_id = ?", userID).Scopes(visibleShelves(ctx))
+
+	shelves := []models.Shelf{}
+	if err := query.Order("position, id").Find(&shelves).Error; err != nil {
+		log.Error("Failed to fetch shelves", zap.Error(err), zap.Int("userID", userID))
+		return apperror.Internal("Failed to fetch shelves")
+	}
+	if err := loadShelfBookCounts(db, shelves); err != nil {
+		log.Error("Failed to count books per shelf", zap.Error(err), zap.Int("userID", userID))
+		return apperror.Internal("Failed to fetch shelves")
+	}
+
+	log.Info("Shelves fetched successfully", zap.Int("userID", userID), zap.Int("count", len(shelves)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Shelves fetched successfully",
+		"data":    shelves,
+	})
+}
+
+// GetShelfByID godoc
+// @Summary Get a shelf
+// @Description Get a shelf with its books in shelf order. Private shelves are only found by their owner.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Shelf ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.Shelf}
+// @Failure 401 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves/{id} [get]
+func (c *ShelfController) GetShelfByID(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ShelfController.GetShelfByID Begin", zap.String("shelfID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	shelf, err := c.findShelf(ctx, log)
+	if err != nil {
+		return err
+	}
+	if shelf.Visibility == "private" && !isOwner(ctx, shelf.UserID) {
+		log.Warn("Shelf is private", zap.Uint("shelfID", shelf.ID))
+		return apperror.NotFound("Shelf not found")
+	}
+
+	shelf.UserBooks = []models.UserBook{}
+	if err := db.Joins("JOIN user_book_shelves ubs ON ubs.user_book_id = user_books.id").
+		Where("ubs.shelf_id = ?", shelf.ID).
+		Order("ubs.position, ubs.created_at").
+		Find(&shelf.UserBooks).Error; err != nil {
+		log.Error("Failed to fetch shelf books", zap.Error(err), zap.Uint("shelfID", shelf.ID))
+		return apperror.Internal("Failed to fetch shelf")
+	}
+	shelf.BookCount = int64(len(shelf.UserBooks))
+
+	log.Info("Shelf fetched successfully", zap.Uint("shelfID", shelf.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Shelf fetched successfully",
+		"data":    shelf,
+	})
+}
+
+// UpdateShelf godoc
+// @Summary Update a shelf
+// @Description Rename, describe, change visibility or move a shelf to another position.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Param id path int true "Shelf ID"
+// @Param shelf body models.ShelfUpdateRequest true "Shelf Update Payload"
+// @Success 200 {object} fiber.Map{message=string, data=models.Shelf}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves/{id} [put]
+func (c *ShelfController) UpdateShelf(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ShelfController.UpdateShelf Begin", zap.String("shelfID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ShelfUpdateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body for Shelf update", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Shelf update", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	shelf, err := c.findShelf(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	// TODO: Authorization check: Does the authenticated user own this shelf?
+
+	if req.Name != "" {
+		shelf.Name = req.Name
+	}
+	if req.Description != nil {
+		shelf.Description = *req.Description
+	}
+	if req.Visibility != "" {
+		shelf.Visibility = req.Visibility
+	}
+	shelf.UpdatedBy = int64(shelf.UserID) // Placeholder
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(shelf).Error; err != nil {
+			return err
+		}
+		if req.Position == nil {
+			return nil
+		}
+		ids, err := userShelfIDs(tx, shelf.UserID)
+		if err != nil {
+			return err
+		}
+		ids = movePosition(ids, shelf.ID, *req.Position)
+		shelf.Position = slices.Index(ids, shelf.ID)
+		return applyShelfPositions(tx, ids)
+	})
+	if err != nil {
+		log.Error("Failed to update Shelf in database", zap.Error(err), zap.Uint("shelfID", shelf.ID))
+		return apperror.Internal("Failed to update shelf")
+	}
+
+	log.Info("Shelf updated successfully", zap.Uint("shelfID", shelf.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Shelf updated successfully",
+		"data":    shelf,
+	})
+}
+
+// DeleteShelf godoc
+// @Summary Soft delete a shelf
+// @Description Soft delete a shelf. The books themselves are kept; only their shelf membership is removed.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Param id path int true "Shelf ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves/{id} [delete]
+func (c *ShelfController) DeleteShelf(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ShelfController.DeleteShelf Begin", zap.String("shelfID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	shelf, err := c.findShelf(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shelf_id = ?", shelf.ID).Delete(&models.UserBookShelf{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(shelf).Update("DeletedBy", int64(shelf.UserID)).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(shelf).Error; err != nil {
+			return err
+		}
+		ids, err := userShelfIDs(tx, shelf.UserID)
+		if err != nil {
+			return err
+		}
+		return applyShelfPositions(tx, ids)
+	})
+	if err != nil {
+		log.Error("Failed to soft delete Shelf", zap.Error(err), zap.Uint("shelfID", shelf.ID))
+		return apperror.Internal("Failed to delete shelf")
+	}
+
+	log.Info("Shelf soft deleted successfully", zap.Uint("shelfID", shelf.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Shelf deleted successfully"})
+}
+
+// ReorderShelves godoc
+// @Summary Reorder a user's shelves
+// @Description Persist a drag-and-drop result. shelf_ids must list every shelf of the user exactly once. The response lists private shelves only for their owner.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order body models.ShelfReorderRequest true "Shelf Reorder Payload"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.Shelf}
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves/reorder [put]
+func (c *ShelfController) ReorderShelves(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ShelfController.ReorderShelves Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ShelfReorderRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Shelf reorder", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	errInvalidOrder := apperror.InvalidField("shelf_ids", "shelf_ids must list every shelf of the user exactly once")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		ids, err := userShelfIDs(tx, req.UserID)
+		if err != nil {
+			return err
+		}
+		if !sameIDs(ids, req.ShelfIDs) {
+			return errInvalidOrder
+		}
+		return applyShelfPositions(tx, req.ShelfIDs)
+	})
+	if err == errInvalidOrder {
+		return err
+	}
+	if err != nil {
+		log.Error("Failed to reorder shelves", zap.Error(err), zap.Uint("userID", req.UserID))
+		return apperror.Internal("Failed to reorder shelves")
+	}
+
+	shelves := []models.Shelf{}
+	if err := db.Where("user_id = ?", req.UserID).Scopes(visibleShelves(ctx)).Order("position, id").Find(&shelves).Error; err != nil {
+		log.Error("Failed to fetch shelves", zap.Error(err), zap.Uint("userID", req.UserID))
+		return apperror.Internal("Failed to fetch shelves")
+	}
+
+	log.Info("Shelves reordered successfully", zap.Uint("userID", req.UserID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Shelves reordered successfully",
+		"data":    shelves,
+	})
+}
+
+// AddBookToShelf godoc
+// @Summary Put a user book on a shelf
+// @Description Add a user book to a shelf at the given position, or at the end when no position is given.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Param id path int true "Shelf ID"
+// @Param book body models.ShelfBookAddRequest true "Shelf Book Add Payload"
+// @Success 201 {object} fiber.Map{message=string, data=models.UserBookShelf}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves/{id}/books [post]
+func (c *ShelfController) AddBookToShelf(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ShelfController.AddBookToShelf Begin", zap.String("shelfID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ShelfBookAddRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for adding book to shelf", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	shelf, err := c.findShelf(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	var userBook models.UserBook
+	if err := db.First(&userBook, req.UserBookID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return apperror.InvalidField("user_book_id", "User book not found")
+		}
+		log.Error("Failed to fetch UserBook for shelf", zap.Error(err), zap.Uint("userBookID", req.UserBookID))
+		return apperror.Internal("Failed to fetch user book")
+	}
+	if userBook.UserID != shelf.UserID {
+		return apperror.InvalidField("user_book_id", "User book belongs to another user")
+	}
+
+	errAlreadyOnShelf := apperror.Conflict("User book is already on this shelf")
+	entry := models.UserBookShelf{ShelfID: shelf.ID, UserBookID: userBook.ID}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		ids, err := shelfBookIDs(tx, shelf.ID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(ids, userBook.ID) {
+			return errAlreadyOnShelf
+		}
+		position := len(ids)
+		if req.Position != nil {
+			position = *req.Position
+		}
+		ids = movePosition(ids, userBook.ID, position)
+		entry.Position = slices.Index(ids, userBook.ID)
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return applyShelfBookPositions(tx, shelf.ID, ids)
+	})
+	if err == errAlreadyOnShelf {
+		return err
+	}
+	if err != nil {
+		log.Error("Failed to add book to shelf", zap.Error(err), zap.Uint("shelfID", shelf.ID))
+		return apperror.Internal("Failed to add book to shelf")
+	}
+
+	log.Info("Book added to shelf successfully", zap.Uint("shelfID", shelf.ID), zap.Uint("userBookID", userBook.ID))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Book added to shelf successfully",
+		"data":    entry,
+	})
+}
+
+// ReorderShelfBooks godoc
+// @Summary Reorder the books on a shelf
+// @Description Persist a drag-and-drop result. user_book_ids must list every book on the shelf exactly once.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Param id path int true "Shelf ID"
+// @Param order body models.ShelfBookReorderRequest true "Shelf Book Reorder Payload"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves/{id}/books/reorder [put]
+func (c *ShelfController) ReorderShelfBooks(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("ShelfController.ReorderShelfBooks Begin", zap.String("shelfID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ShelfBookReorderRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for shelf book reorder", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	shelf, err := c.findShelf(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	errInvalidOrder := apperror.InvalidField("user_book_ids", "user_book_ids must list every book on the shelf exactly once")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		ids, err := shelfBookIDs(tx, shelf.ID)
+		if err != nil {
+			return err
+		}
+		if !sameIDs(ids, req.UserBookIDs) {
+			return errInvalidOrder
+		}
+		return applyShelfBookPositions(tx, shelf.ID, req.UserBookIDs)
+	})
+	if err == errInvalidOrder {
+		return err
+	}
+	if err != nil {
+		log.Error("Failed to reorder shelf books", zap.Error(err), zap.Uint("shelfID", shelf.ID))
+		return apperror.Internal("Failed to reorder shelf books")
+	}
+
+	log.Info("Shelf books reordered successfully", zap.Uint("shelfID", shelf.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Shelf books reordered successfully"})
+}
+
+// RemoveBookFromShelf godoc
+// @Summary Take a user book off a shelf
+// @Description Remove a user book from a shelf; the book itself is kept.
+// @Tags Shelf
+// @Accept json
+// @Produce json
+// @Param id path int true "Shelf ID"
+// @Param userBookId path int true "UserBook ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /shelves/{id}/books/{userBookId} [delete]
+func (c *ShelfController) RemoveBookFromShelf(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "userBookId")
+	if err != nil {
+		return err
+	}
+	log.Info("ShelfController.RemoveBookFromShelf Begin", zap.String("shelfID", ctx.Params("id")), zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	shelf, err := c.findShelf(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	var removed int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("shelf_id = ? AND user_book_id = ?", shelf.ID, userBookID).Delete(&models.UserBookShelf{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected
+		ids, err := shelfBookIDs(tx, shelf.ID)
+		if err != nil {
+			return err
+		}
+		return applyShelfBookPositions(tx, shelf.ID, ids)
+	})
+	if err != nil {
+		log.Error("Failed to remove book from shelf", zap.Error(err), zap.Uint("shelfID", shelf.ID))
+		return apperror.Internal("Failed to remove book from shelf")
+	}
+	if removed == 0 {
+		log.Warn("User book is not on shelf", zap.Uint("shelfID", shelf.ID), zap.Uint("userBookID", userBookID))
+		return apperror.NotFound("User book is not on this shelf")
+	}
+
+	log.Info("Book removed from shelf successfully", zap.Uint("shelfID", shelf.ID), zap.Uint("userBookID", userBookID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Book removed from shelf successfully"})
+}
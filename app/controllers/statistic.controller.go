@@ -0,0 +1,447 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type StatisticController struct {
+	DB *gorm.DB
+}
+
+func NewStatisticController(DB *gorm.DB) *StatisticController {
+	return &StatisticController{
+		DB: DB,
+	}
+}
+
+// statisticDateLayout is the layout accepted by the from/to query parameters.
+const statisticDateLayout = "2006-01-02"
+
+// statisticPeriods whitelists the values accepted by the period query parameter;
+// the value is passed to PostgreSQL's date_trunc.
+var statisticPeriods = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+	"year":  true,
+}
+
+// statisticBreakdowns maps the "by" query parameter to the grouped column.
+var statisticBreakdowns = map[string]string{
+	"author": "ub.author",
+	"genre":  "COALESCE(NULLIF(ub.genre, ''), 'Uncategorized')",
+}
+
+// parseStatisticRange reads the optional from/to query parameters (inclusive dates)
+// and returns a half-open [from, to) range. defaultFrom is used when from is omitted.
+func parseStatisticRange(ctx *fiber.Ctx, defaultFrom func(to time.Time) time.Time) (time.Time, time.Time, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today
+	if raw := ctx.Query("to"); raw != "" {
+		parsed, err := time.Parse(statisticDateLayout, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must use the YYYY-MM-DD format")
+		}
+		to = parsed
+	}
+
+	from := defaultFrom(to)
+	if raw := ctx.Query("from"); raw != "" {
+		parsed, err := time.Parse(statisticDateLayout, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must use the YYYY-MM-DD format")
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// defaultStatisticFrom picks a sensible window for each period granularity.
+func defaultStatisticFrom(period string) func(time.Time) time.Time {
+	return func(to time.Time) time.Time {
+		switch period {
+		case "day":
+			return to.AddDate(0, 0, -29)
+		case "week":
+			return to.AddDate(0, 0, -7*11)
+		case "year":
+			return to.AddDate(-4, 0, 0)
+		default:
+			return to.AddDate(0, -11, 0)
+		}
+	}
+}
+
+// pagesPerHour returns nil when no timed activity exists, so clients can tell
+// "unknown" apart from "zero".
+func pagesPerHour(timedPages int64, minutes int64) *float64 {
+	if minutes <= 0 {
+		return nil
+	}
+	speed := roundTo(float64(timedPages)/(float64(minutes)/60), 2)
+	return &speed
+}
+
+func roundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
+
+// GetUserSummary godoc
+// @Summary Get a user's reading summary
+// @Description Aggregate totals over all reading activities of a user: sessions, pages, minutes, average pages per session, reading speed and book counts.
+// @Tags Statistic
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingSummary}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /statistics/users/{userId} [get]
+func (c *StatisticController) GetUserSummary(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StatisticController.GetUserSummary Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	var activityTotals struct {
+		TotalSessions          int64
+		TotalPagesRead         int64
+		TotalMinutes           int64
+		TimedPages             int64
+		AveragePagesPerSession float64
+	}
+	if err := db.Table("reading_activities AS ra").
+		Select(`COUNT(*) AS total_sessions,
+			COALESCE(SUM(ra.pages_read), 0) AS total_pages_read,
+			COALESCE(SUM(ra.duration), 0) AS total_minutes,
+			COALESCE(SUM(ra.pages_read) FILTER (WHERE ra.duration > 0), 0) AS timed_pages,
+			COALESCE(AVG(ra.pages_read), 0) AS average_pages_per_session`).
+		Joins("JOIN user_books ub ON ub.id = ra.user_book_id AND ub.deleted_at IS NULL").
+		Where("ub.user_id = ? AND ra.deleted_at IS NULL", user.ID).
+		Scan(&activityTotals).Error; err != nil {
+		log.Error("Failed to aggregate reading activities", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading summary")
+	}
+
+	var bookTotals struct {
+		BooksReading  int64
+		BooksFinished int64
+	}
+	if err := db.Model(&models.UserBook{}).
+		Select(`COUNT(*) FILTER (WHERE status = 'reading') AS books_reading,
+			COUNT(*) FILTER (WHERE status = 'finished') AS books_finished`).
+		Where("user_id = ?", user.ID).
+		Scan(&bookTotals).Error; err != nil {
+		log.Error("Failed to aggregate user books", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading summary")
+	}
+
+	summary := models.ReadingSummary{
+		UserID:                 user.ID,
+		TotalSessions:          activityTotals.TotalSessions,
+		TotalPagesRead:         activityTotals.TotalPagesRead,
+		TotalMinutes:           activityTotals.TotalMinutes,
+		AveragePagesPerSession: roundTo(activityTotals.AveragePagesPerSession, 2),
+		PagesPerHour:           pagesPerHour(activityTotals.TimedPages, activityTotals.TotalMinutes),
+		BooksReading:           bookTotals.BooksReading,
+		BooksFinished:          bookTotals.BooksFinished,
+	}
+
+	log.Info("Reading summary fetched successfully", zap.Uint("userID", user.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading summary fetched successfully",
+		"data":    summary,
+	})
+}
+
+// GetPagesRead godoc
+// @Summary Get pages read per period
+// @Description Pages read, sessions and minutes grouped by day, week, month or year. Empty periods are omitted.
+// @Tags Statistic
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param period query string false "Grouping period" Enums(day, week, month, year) default(day)
+// @Param from query string false "Start date (YYYY-MM-DD, inclusive)"
+// @Param to query string false "End date (YYYY-MM-DD, inclusive), defaults to today"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.PagesReadPerPeriod}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /statistics/users/{userId}/pages [get]
+func (c *StatisticController) GetPagesRead(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StatisticController.GetPagesRead Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	period := ctx.Query("period", "day")
+	if !statisticPeriods[period] {
+		return apperror.InvalidField("period", "period must be one of day, week, month, year")
+	}
+
+	from, to, err := parseStatisticRange(ctx, defaultStatisticFrom(period))
+	if err != nil {
+		return apperror.InvalidField("range", err.Error())
+	}
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	rows := []models.PagesReadPerPeriod{}
+	if err := db.Table("reading_activities AS ra").
+		Select(`date_trunc(?, ra.reading_date) AS period,
+			SUM(ra.pages_read) AS pages_read,
+			COUNT(*) AS sessions,
+			SUM(ra.duration) AS minutes`, period).
+		Joins("JOIN user_books ub ON ub.id = ra.user_book_id AND ub.deleted_at IS NULL").
+		Where("ub.user_id = ? AND ra.deleted_at IS NULL", user.ID).
+		Where("ra.reading_date >= ? AND ra.reading_date < ?", from, to).
+		Group("1").
+		Order("1").
+		Scan(&rows).Error; err != nil {
+		log.Error("Failed to aggregate pages read", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch pages read")
+	}
+
+	log.Info("Pages read fetched successfully", zap.Uint("userID", user.ID), zap.Int("count", len(rows)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Pages read fetched successfully",
+		"data":    rows,
+	})
+}
+
+// GetBooksFinished godoc
+// @Summary Get books finished per period
+// @Description Number of books whose end date falls in each day, week, month or year.
+// @Tags Statistic
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param period query string false "Grouping period" Enums(day, week, month, year) default(month)
+// @Param from query string false "Start date (YYYY-MM-DD, inclusive)"
+// @Param to query string false "End date (YYYY-MM-DD, inclusive), defaults to today"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.BooksFinishedPerPeriod}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /statistics/users/{userId}/finished [get]
+func (c *StatisticController) GetBooksFinished(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StatisticController.GetBooksFinished Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	period := ctx.Query("period", "month")
+	if !statisticPeriods[period] {
+		return apperror.InvalidField("period", "period must be one of day, week, month, year")
+	}
+
+	from, to, err := parseStatisticRange(ctx, defaultStatisticFrom(period))
+	if err != nil {
+		return apperror.InvalidField("range", err.Error())
+	}
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	rows := []models.BooksFinishedPerPeriod{}
+	if err := db.Model(&models.UserBook{}).
+		Select("date_trunc(?, end_date) AS period, COUNT(*) AS books_finished", period).
+		Where("user_id = ? AND status = ?", user.ID, "finished").
+		Where("end_date >= ? AND end_date < ?", from, to).
+		Group("1").
+		Order("1").
+		Scan(&rows).Error; err != nil {
+		log.Error("Failed to aggregate finished books", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch finished books")
+	}
+
+	log.Info("Finished books fetched successfully", zap.Uint("userID", user.ID), zap.Int("count", len(rows)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Finished books fetched successfully",
+		"data":    rows,
+	})
+}
+
+// GetBreakdown godoc
+// @Summary Get a user's reading breakdown
+// @Description Books, finished books and pages read grouped by author or genre, ordered by pages read.
+// @Tags Statistic
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param by query string false "Grouping" Enums(author, genre) default(author)
+// @Success 200 {object} fiber.Map{message=string, data=[]models.ReadingBreakdown}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /statistics/users/{userId}/breakdown [get]
+func (c *StatisticController) GetBreakdown(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StatisticController.GetBreakdown Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	column, ok := statisticBreakdowns[ctx.Query("by", "author")]
+	if !ok {
+		return apperror.InvalidField("by", "by must be one of author, genre")
+	}
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	// Pages are summed in a subquery so that books without activities still count
+	// and books with many activities are not counted more than once.
+	rows := []models.ReadingBreakdown{}
+	if err := db.Table("user_books AS ub").
+		Select(column+` AS name,
+			COUNT(*) AS books,
+			COUNT(*) FILTER (WHERE ub.status = 'finished') AS books_finished,
+			COALESCE(SUM(ra.pages_read), 0) AS pages_read`).
+		Joins(`LEFT JOIN (
+			SELECT user_book_id, SUM(pages_read) AS pages_read
+			FROM reading_activities
+			WHERE deleted_at IS NULL
+			GROUP BY user_book_id
+		) ra ON ra.user_book_id = ub.id`).
+		Where("ub.user_id = ? AND ub.deleted_at IS NULL", user.ID).
+		Group("1").
+		Order("pages_read DESC, books DESC, name").
+		Scan(&rows).Error; err != nil {
+		log.Error("Failed to aggregate reading breakdown", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading breakdown")
+	}
+
+	log.Info("Reading breakdown fetched successfully", zap.Uint("userID", user.ID), zap.Int("count", len(rows)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading breakdown fetched successfully",
+		"data":    rows,
+	})
+}
+
+// GetUserBookProgress godoc
+// @Summary Get progress of a user book
+// @Description Percentage complete, reading pace and an estimated finish date based on the average pages read per day since the book was started.
+// @Tags Statistic
+// @Accept json
+// @Produce json
+// @Param userBookId path int true "UserBook ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.UserBookProgress}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /statistics/userbooks/{userBookId} [get]
+func (c *StatisticController) GetUserBookProgress(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "userBookId")
+	if err != nil {
+		return err
+	}
+	log.Info("StatisticController.GetUserBookProgress Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var userBook models.UserBook
+	if err := db.Where("id = ?", userBookID).First(&userBook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("UserBook not found for progress", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
+		}
+		log.Error("Failed to fetch UserBook for progress", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to fetch user book")
+	}
+
+	progress, err := calculateUserBookProgress(db, &userBook, time.Now())
+	if err != nil {
+		log.Error("Failed to aggregate UserBook progress", zap.Error(err), zap.Uint("userBookID", userBook.ID))
+		return apperror.Internal("Failed to fetch user book progress")
+	}
+
+	log.Info("UserBook progress fetched successfully", zap.Uint("userBookID", userBook.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "User book progress fetched successfully",
+		"data":    progress,
+	})
+}
+
+// calculateUserBookProgress aggregates the activities of a single UserBook and
+// projects a finish date from the average pages per day since StartDate.
+func calculateUserBookProgress(db *gorm.DB, userBook *models.UserBook, now time.Time) (*models.UserBookProgress, error) {
+	var totals struct {
+		Sessions         int64
+		PagesRead        int64
+		Minutes          int64
+		TimedPages       int64
+		FirstReadingDate *time.Time
+		LastReadingDate  *time.Time
+	}
+	if err := db.Model(&models.ReadingActivity{}).
+		Select(`COUNT(*) AS sessions,
+			COALESCE(SUM(pages_read), 0) AS pages_read,
+			COALESCE(SUM(duration), 0) AS minutes,
+			COALESCE(SUM(pages_read) FILTER (WHERE duration > 0), 0) AS timed_pages,
+			MIN(reading_date) AS first_reading_date,
+			MAX(reading_date) AS last_reading_date`).
+		Where("user_book_id = ?", userBook.ID).
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	progress := &models.UserBookProgress{
+		UserBookID:       userBook.ID,
+		Status:           userBook.Status,
+		TotalPages:       userBook.TotalPages,
+		CurrentPage:      userBook.CurrentPage,
+		PagesRemaining:   max(userBook.TotalPages-userBook.CurrentPage, 0),
+		Sessions:         totals.Sessions,
+		PagesRead:        totals.PagesRead,
+		PagesPerHour:     pagesPerHour(totals.TimedPages, totals.Minutes),
+		FirstReadingDate: totals.FirstReadingDate,
+		LastReadingDate:  totals.LastReadingDate,
+	}
+	if userBook.TotalPages > 0 {
+		progress.PercentComplete = roundTo(math.Min(float64(userBook.CurrentPage)/float64(userBook.TotalPages)*100, 100), 2)
+	}
+	if totals.Sessions > 0 {
+		progress.AveragePagesPerSession = roundTo(float64(totals.PagesRead)/float64(totals.Sessions), 2)
+	}
+
+	// The pace is measured from the book's start date (or the first activity when
+	// that is earlier) up to today, so idle days slow the estimate down.
+	started := userBook.StartDate
+	if totals.FirstReadingDate != nil && (started.IsZero() || totals.FirstReadingDate.Before(started)) {
+		started = *totals.FirstReadingDate
+	}
+	if !started.IsZero() {
+		days := math.Max(math.Ceil(now.Sub(started).Hours()/24), 1)
+		progress.AveragePagesPerDay = roundTo(float64(totals.PagesRead)/days, 2)
+	}
+
+	if userBook.Status != "finished" && progress.PagesRemaining > 0 && progress.AveragePagesPerDay > 0 {
+		daysLeft := int(math.Ceil(float64(progress.PagesRemaining) / progress.AveragePagesPerDay))
+		estimate := now.Truncate(24*time.Hour).AddDate(0, 0, daysLeft)
+		progress.EstimatedFinishDate = &estimate
+	}
+
+	return progress, nil
+}
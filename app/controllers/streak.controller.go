@@ -0,0 +1,442 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/planner"
+	"ayo-baca-buku/app/util/streak"
+	"ayo-baca-buku/app/util/validation"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type StreakController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewStreakController(DB *gorm.DB) *StreakController {
+	return &StreakController{
+		DB:       DB,
+		Validate: validation.New(),
+	}
+}
+
+// userLocation returns the user's configured timezone, falling back to UTC.
+func userLocation(user *models.User) *time.Location {
+	if user.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func habitSetting(user *models.User) models.ReadingHabitSetting {
+	return models.ReadingHabitSetting{
+		Timezone:            userLocation(user).String(),
+		DailyMinimumPages:   user.DailyMinimumPages,
+		DailyMinimumMinutes: user.DailyMinimumMinutes,
+		RestDays:            user.RestDays,
+	}
+}
+
+// loadReadingDays aggregates a user's activities per calendar day in the user's
+// timezone. from and to are optional instants bounding ReadingDate as [from, to).
+func loadReadingDays(db *gorm.DB, user *models.User, from, to *time.Time) ([]models.ReadingDay, error) {
+	query := db.Table("reading_activities AS ra").
+		Select(`(ra.reading_date AT TIME ZONE ?)::date AS date,
+			SUM(ra.pages_read) AS pages_read,
+			SUM(ra.duration) AS minutes,
+			COUNT(*) AS sessions`, userLocation(user).String()).
+		Joins("JOIN user_books ub ON ub.id = ra.user_book_id AND ub.deleted_at IS NULL").
+		Where("ub.user_id = ? AND ra.deleted_at IS NULL", user.ID)
+	if from != nil {
+		query = query.Where("ra.reading_date >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("ra.reading_date < ?", *to)
+	}
+
+	days := []models.ReadingDay{}
+	if err := query.Group("1").Order("1").Scan(&days).Error; err != nil {
+		return nil, err
+	}
+	for i := range days {
+		days[i].ReadDay = streak.IsReadDay(days[i].PagesRead, days[i].Minutes, user.DailyMinimumPages, user.DailyMinimumMinutes)
+	}
+	return days, nil
+}
+
+func loadFreezeDays(db *gorm.DB, userID uint) ([]models.StreakFreeze, error) {
+	freezes := []models.StreakFreeze{}
+	err := db.Where("user_id = ?", userID).Order("freeze_date").Find(&freezes).Error
+	return freezes, err
+}
+
+// GetStreak godoc
+// @Summary Get a user's reading streak
+// @Description Current and longest streak of consecutive read days, computed in the user's timezone. Streak-freeze days keep a streak alive without extending it.
+// @Tags Streak
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingStreak}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/streak [get]
+func (c *StreakController) GetStreak(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StreakController.GetStreak Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	days, err := loadReadingDays(db, user, nil, nil)
+	if err != nil {
+		log.Error("Failed to aggregate reading days", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading streak")
+	}
+	freezes, err := loadFreezeDays(db, user.ID)
+	if err != nil {
+		log.Error("Failed to fetch streak freezes", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading streak")
+	}
+
+	readDays := []time.Time{}
+	for _, day := range days {
+		if day.ReadDay {
+			readDays = append(readDays, day.Date)
+		}
+	}
+	freezeDays := make([]time.Time, 0, len(freezes))
+	for _, freeze := range freezes {
+		freezeDays = append(freezeDays, streak.Day(freeze.FreezeDate, time.UTC))
+	}
+
+	result := streak.Calculate(readDays, freezeDays, streak.Day(time.Now(), userLocation(user)))
+
+	log.Info("Reading streak fetched successfully", zap.Uint("userID", user.ID), zap.Int("current", result.Current))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading streak fetched successfully",
+		"data": models.ReadingStreak{
+			ReadingHabitSetting: habitSetting(user),
+			CurrentStreak:       result.Current,
+			CurrentStreakStart:  result.CurrentStart,
+			LongestStreak:       result.Longest,
+			LongestStreakStart:  result.LongestStart,
+			LongestStreakEnd:    result.LongestEnd,
+			ReadToday:           result.ReadToday,
+			LastReadDate:        result.LastReadDate,
+			TotalReadDays:       result.TotalDays,
+		},
+	})
+}
+
+// GetHeatmap godoc
+// @Summary Get a reading calendar heatmap
+// @Description Per-day totals for every day of a year in the user's timezone, including days without activity.
+// @Tags Streak
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param year query int false "Calendar year, defaults to the current year"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingHeatmap}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/heatmap [get]
+func (c *StreakController) GetHeatmap(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StreakController.GetHeatmap Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	loc := userLocation(user)
+	year := ctx.QueryInt("year", time.Now().In(loc).Year())
+	if year < 1970 || year > 9999 {
+		return apperror.InvalidField("year", "year must be between 1970 and 9999")
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(1, 0, 0)
+	days, err := loadReadingDays(db, user, &from, &to)
+	if err != nil {
+		log.Error("Failed to aggregate reading days", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading heatmap")
+	}
+	freezes, err := loadFreezeDays(db, user.ID)
+	if err != nil {
+		log.Error("Failed to fetch streak freezes", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch reading heatmap")
+	}
+
+	totals := make(map[time.Time]models.ReadingDay, len(days))
+	for _, day := range days {
+		totals[day.Date] = day
+	}
+	frozen := make(map[time.Time]bool, len(freezes))
+	for _, freeze := range freezes {
+		frozen[streak.Day(freeze.FreezeDate, time.UTC)] = true
+	}
+
+	heatmap := models.ReadingHeatmap{Year: year, Timezone: loc.String()}
+	end := time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC); day.Before(end); day = day.AddDate(0, 0, 1) {
+		entry, ok := totals[day]
+		if !ok {
+			entry = models.ReadingDay{Date: day}
+		}
+		entry.Frozen = frozen[day]
+		heatmap.Days = append(heatmap.Days, entry)
+	}
+
+	log.Info("Reading heatmap fetched successfully", zap.Uint("userID", user.ID), zap.Int("year", year))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading heatmap fetched successfully",
+		"data":    heatmap,
+	})
+}
+
+// GetHabitSetting godoc
+// @Summary Get reading habit settings
+// @Description Timezone and daily minimums used for streaks and the heatmap.
+// @Tags Streak
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingHabitSetting}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/habit [get]
+func (c *StreakController) GetHabitSetting(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StreakController.GetHabitSetting Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading habit settings fetched successfully",
+		"data":    habitSetting(user),
+	})
+}
+
+// UpdateHabitSetting godoc
+// @Summary Update reading habit settings
+// @Description Set the timezone (IANA name), the daily minimum pages or minutes that make a day count as a read day, and the weekdays reading plans skip.
+// @Tags Streak
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param habit body models.ReadingHabitUpdateRequest true "Reading Habit Update Payload"
+// @Success 200 {object} fiber.Map{message=string, data=models.ReadingHabitSetting}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/habit [put]
+func (c *StreakController) UpdateHabitSetting(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StreakController.UpdateHabitSetting Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.ReadingHabitUpdateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for habit settings", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	if req.Timezone != "" {
+		user.Timezone = req.Timezone
+	}
+	if req.DailyMinimumPages != nil {
+		user.DailyMinimumPages = *req.DailyMinimumPages
+	}
+	if req.DailyMinimumMinutes != nil {
+		user.DailyMinimumMinutes = *req.DailyMinimumMinutes
+	}
+	if req.RestDays != nil {
+		restDays, err := planner.ParseRestDays(*req.RestDays)
+		if err != nil {
+			return apperror.InvalidField("rest_days", err.Error())
+		}
+		user.RestDays = planner.FormatRestDays(restDays)
+	}
+
+	if err := db.Model(user).Updates(map[string]interface{}{
+		"timezone":              user.Timezone,
+		"daily_minimum_pages":   user.DailyMinimumPages,
+		"daily_minimum_minutes": user.DailyMinimumMinutes,
+		"rest_days":             user.RestDays,
+	}).Error; err != nil {
+		log.Error("Failed to update habit settings", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to update reading habit settings")
+	}
+
+	log.Info("Reading habit settings updated successfully", zap.Uint("userID", user.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reading habit settings updated successfully",
+		"data":    habitSetting(user),
+	})
+}
+
+// GetStreakFreezes godoc
+// @Summary List streak-freeze days
+// @Description List all streak-freeze days of a user, oldest first.
+// @Tags Streak
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.StreakFreeze}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/streak-freezes [get]
+func (c *StreakController) GetStreakFreezes(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StreakController.GetStreakFreezes Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	freezes, err := loadFreezeDays(db, user.ID)
+	if err != nil {
+		log.Error("Failed to fetch streak freezes", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to fetch streak freezes")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Streak freezes fetched successfully",
+		"data":    freezes,
+	})
+}
+
+// CreateStreakFreeze godoc
+// @Summary Add a streak-freeze day
+// @Description Mark a calendar day (in the user's timezone) on which missing a session does not break the streak.
+// @Tags Streak
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param freeze body models.StreakFreezeCreateRequest true "Streak Freeze Create Payload"
+// @Success 201 {object} fiber.Map{message=string, data=models.StreakFreeze}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/streak-freezes [post]
+func (c *StreakController) CreateStreakFreeze(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("StreakController.CreateStreakFreeze Begin", zap.String("userID", ctx.Params("userId")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.StreakFreezeCreateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for streak freeze", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	user, err := findUserByParam(ctx, db, log, "userId")
+	if err != nil {
+		return err
+	}
+
+	freezeDate, _ := time.Parse("2006-01-02", req.FreezeDate)
+	var count int64
+	if err := db.Model(&models.StreakFreeze{}).Where("user_id = ? AND freeze_date = ?", user.ID, freezeDate).Count(&count).Error; err != nil {
+		log.Error("Failed to check existing streak freeze", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to create streak freeze")
+	}
+	if count > 0 {
+		return apperror.Conflict("Streak freeze already exists for this date")
+	}
+
+	freeze := models.StreakFreeze{
+		UserID:     user.ID,
+		FreezeDate: freezeDate,
+		Reason:     req.Reason,
+	}
+	if err := db.Create(&freeze).Error; err != nil {
+		log.Error("Failed to create streak freeze", zap.Error(err), zap.Uint("userID", user.ID))
+		return apperror.Internal("Failed to create streak freeze")
+	}
+
+	log.Info("Streak freeze created successfully", zap.Uint("freezeID", freeze.ID))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Streak freeze created successfully",
+		"data":    freeze,
+	})
+}
+
+// DeleteStreakFreeze godoc
+// @Summary Remove a streak-freeze day
+// @Description Permanently delete a streak-freeze day.
+// @Tags Streak
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param freezeId path int true "Streak Freeze ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /users/{userId}/streak-freezes/{freezeId} [delete]
+func (c *StreakController) DeleteStreakFreeze(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	userID, err := paramID(ctx, "userId")
+	if err != nil {
+		return err
+	}
+	freezeID, err := paramID(ctx, "freezeId")
+	if err != nil {
+		return err
+	}
+	log.Info("StreakController.DeleteStreakFreeze Begin", zap.Uint("userID", userID), zap.Uint("freezeID", freezeID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	result := db.Where("id = ? AND user_id = ?", freezeID, userID).Delete(&models.StreakFreeze{})
+	if result.Error != nil {
+		log.Error("Failed to delete streak freeze", zap.Error(result.Error), zap.Uint("freezeID", freezeID))
+		return apperror.Internal("Failed to delete streak freeze")
+	}
+	if result.RowsAffected == 0 {
+		log.Warn("Streak freeze not found for deletion", zap.Uint("freezeID", freezeID))
+		return apperror.NotFound("Streak freeze not found")
+	}
+
+	log.Info("Streak freeze deleted successfully", zap.Uint("freezeID", freezeID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Streak freeze deleted successfully"})
+}
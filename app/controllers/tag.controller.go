@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/validation"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type TagController struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewTagController(DB *gorm.DB) *TagController {
+	return &TagController{
+		DB:       DB,
+		Validate: validation.New(),
+	}
+}
+
+// normalizeTagName makes "Re-read Later " and "re-read later" the same tag.
+func normalizeTagName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func (c *TagController) findTag(ctx *fiber.Ctx, log *zap.Logger) (*models.Tag, error) {
+	db := c.DB.WithContext(ctx.UserContext())
+
+	tagID, err := paramID(ctx, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	var tag models.Tag
+	if err := db.Where("id = ?", tagID).First(&tag).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("Tag not found", zap.Uint("tagID", tagID))
+			return nil, apperror.NotFound("Tag not found")
+		}
+		log.Error("Failed to fetch Tag", zap.Error(err), zap.Uint("tagID", tagID))
+		return nil, apperror.Internal("Failed to fetch tag")
+	}
+	return &tag, nil
+}
+
+func (c *TagController) tagNameTaken(db *gorm.DB, userID uint, name string, exceptID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.Tag{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// CreateTag godoc
+// @Summary Create a tag
+// @Description Create a free-form tag. Names are trimmed and lower-cased and must be unique per user.
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Param tag body models.TagCreateRequest true "Tag Create Payload"
+// @Success 201 {object} fiber.Map{message=string, data=models.Tag}
+// @Failure 400 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /tags [post]
+func (c *TagController) CreateTag(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("TagController.CreateTag Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.TagCreateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	req.Name = normalizeTagName(req.Name)
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Tag creation", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	var user models.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("User not found for Tag creation", zap.Uint("userID", req.UserID))
+			return apperror.InvalidField("user_id", "User not found")
+		}
+		log.Error("Failed to check user existence", zap.Error(err), zap.Uint("userID", req.UserID))
+		return apperror.Internal("Error checking user")
+	}
+
+	taken, err := c.tagNameTaken(db, req.UserID, req.Name, 0)
+	if err != nil {
+		log.Error("Failed to check tag name", zap.Error(err))
+		return apperror.Internal("Failed to create tag")
+	}
+	if taken {
+		return apperror.Conflict("Tag already exists")
+	}
+
+	tag := models.Tag{UserID: req.UserID, Name: req.Name}
+	if err := db.Create(&tag).Error; err != nil {
+		log.Error("Failed to create Tag in database", zap.Error(err))
+		return apperror.Internal("Failed to create tag")
+	}
+
+	log.Info("Tag created successfully", zap.Uint("tagID", tag.ID))
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Tag created successfully",
+		"data":    tag,
+	})
+}
+
+// GetAllTags godoc
+// @Summary List a user's tags
+// @Description List tags alphabetically with the number of books carrying each tag.
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Param user_id query int true "User ID"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.Tag}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /tags [get]
+func (c *TagController) GetAllTags(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("TagController.GetAllTags Begin")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	userID := ctx.QueryInt("user_id")
+	if userID <= 0 {
+		return apperror.InvalidField("user_id", "user_id is required")
+	}
+
+	tags := []models.Tag{}
+	if err := db.Model(&models.Tag{}).
+		Select("tags.*, COUNT(ub.id) AS book_count").
+		Joins("LEFT JOIN user_book_tags ubt ON ubt.tag_id = tags.id").
+		Joins("LEFT JOIN user_books ub ON ub.id = ubt.user_book_id AND ub.deleted_at IS NULL").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id").
+		Order("tags.name").
+		Find(&tags).Error; err != nil {
+		log.Error("Failed to fetch tags", zap.Error(err), zap.Int("userID", userID))
+		return apperror.Internal("Failed to fetch tags")
+	}
+
+	log.Info("Tags fetched successfully", zap.Int("userID", userID), zap.Int("count", len(tags)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Tags fetched successfully",
+		"data":    tags,
+	})
+}
+
+// UpdateTag godoc
+// @Summary Rename a tag
+// @Description Rename a tag; the books carrying it keep it.
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Param id path int true "Tag ID"
+// @Param tag body models.TagUpdateRequest true "Tag Update Payload"
+// @Success 200 {object} fiber.Map{message=string, data=models.Tag}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /tags/{id} [put]
+func (c *TagController) UpdateTag(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("TagController.UpdateTag Begin", zap.String("tagID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.TagUpdateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body for Tag update", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	req.Name = normalizeTagName(req.Name)
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for Tag update", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	tag, err := c.findTag(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	taken, err := c.tagNameTaken(db, tag.UserID, req.Name, tag.ID)
+	if err != nil {
+		log.Error("Failed to check tag name", zap.Error(err))
+		return apperror.Internal("Failed to update tag")
+	}
+	if taken {
+		return apperror.Conflict("Tag already exists")
+	}
+
+	tag.Name = req.Name
+	if err := db.Save(tag).Error; err != nil {
+		log.Error("Failed to update Tag in database", zap.Error(err), zap.Uint("tagID", tag.ID))
+		return apperror.Internal("Failed to update tag")
+	}
+
+	log.Info("Tag updated successfully", zap.Uint("tagID", tag.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Tag updated successfully",
+		"data":    tag,
+	})
+}
+
+// DeleteTag godoc
+// @Summary Delete a tag
+// @Description Permanently delete a tag and remove it from every book.
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Param id path int true "Tag ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /tags/{id} [delete]
+func (c *TagController) DeleteTag(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	log.Info("TagController.DeleteTag Begin", zap.String("tagID", ctx.Params("id")))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	tag, err := c.findTag(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(tag).Association("UserBooks").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+	if err != nil {
+		log.Error("Failed to delete Tag", zap.Error(err), zap.Uint("tagID", tag.ID))
+		return apperror.Internal("Failed to delete tag")
+	}
+
+	log.Info("Tag deleted successfully", zap.Uint("tagID", tag.ID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Tag deleted successfully"})
+}
+
+// AddUserBookTags godoc
+// @Summary Tag a user book
+// @Description Attach tags to a user book by name. Tags the user does not have yet are created.
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Param id path int true "UserBook ID"
+// @Param tags body models.UserBookTagRequest true "User Book Tag Payload"
+// @Success 200 {object} fiber.Map{message=string, data=[]models.Tag}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{id}/tags [post]
+func (c *TagController) AddUserBookTags(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	log.Info("TagController.AddUserBookTags Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	var req models.UserBookTagRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		log.Error("Failed to parse request body", zap.Error(err))
+		return apperror.InvalidBody(err)
+	}
+
+	for i := range req.Tags {
+		req.Tags[i] = normalizeTagName(req.Tags[i])
+	}
+	if err := c.Validate.Struct(&req); err != nil {
+		log.Error("Validation failed for tagging UserBook", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	var userBook models.UserBook
+	if err := db.Where("id = ?", userBookID).First(&userBook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("UserBook not found for tagging", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
+		}
+		log.Error("Failed to fetch UserBook for tagging", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to fetch user book")
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		tags := make([]models.Tag, 0, len(req.Tags))
+		for _, name := range req.Tags {
+			tag := models.Tag{UserID: userBook.UserID, Name: name}
+			if err := tx.Where(models.Tag{UserID: userBook.UserID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+				return err
+			}
+			tags = append(tags, tag)
+		}
+		return tx.Model(&userBook).Association("Tags").Append(tags)
+	})
+	if err != nil {
+		log.Error("Failed to tag UserBook", zap.Error(err), zap.Uint("userBookID", userBook.ID))
+		return apperror.Internal("Failed to tag user book")
+	}
+
+	tags := []models.Tag{}
+	if err := db.Model(&userBook).Order("name").Association("Tags").Find(&tags); err != nil {
+		log.Error("Failed to fetch UserBook tags", zap.Error(err), zap.Uint("userBookID", userBook.ID))
+		return apperror.Internal("Failed to fetch user book tags")
+	}
+
+	log.Info("UserBook tagged successfully", zap.Uint("userBookID", userBook.ID), zap.Int("count", len(tags)))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "User book tagged successfully",
+		"data":    tags,
+	})
+}
+
+// RemoveUserBookTag godoc
+// @Summary Untag a user book
+// @Description Remove a tag from a user book; the tag itself is kept.
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Param id path int true "UserBook ID"
+// @Param tagId path int true "Tag ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
+// @Router /userbooks/{id}/tags/{tagId} [delete]
+func (c *TagController) RemoveUserBookTag(ctx *fiber.Ctx) error {
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	tagID, err := paramID(ctx, "tagId")
+	if err != nil {
+		return err
+	}
+	log.Info("TagController.RemoveUserBookTag Begin", zap.Uint("userBookID", userBookID), zap.Uint("tagID", tagID))
+	db := c.DB.WithContext(ctx.UserContext())
+
+	result := db.Exec("DELETE FROM user_book_tags WHERE user_book_id = ? AND tag_id = ?", userBookID, tagID)
+	if result.Error != nil {
+		log.Error("Failed to untag UserBook", zap.Error(result.Error), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to remove tag from user book")
+	}
+	if result.RowsAffected == 0 {
+		log.Warn("Tag not found on UserBook", zap.Uint("userBookID", userBookID), zap.Uint("tagID", tagID))
+		return apperror.NotFound("Tag not found on this user book")
+	}
+
+	log.Info("Tag removed from UserBook successfully", zap.Uint("userBookID", userBookID), zap.Uint("tagID", tagID))
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Tag removed from user book successfully"})
+}
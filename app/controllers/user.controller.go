@@ -2,12 +2,11 @@ package controllers
 
 import (
 	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
 	"ayo-baca-buku/app/util/jwt"
 	"ayo-baca-buku/app/util/logger"
 	"ayo-baca-buku/app/util/validation"
-	"strings"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -32,15 +31,15 @@ func NewUserController(DB *gorm.DB) *UserController {
 // @Success 200 {object} models.User
 // @Router /users [get]
 func (c *UserController) GetAllUsers(ctx *fiber.Ctx) error {
-	logger := logger.GetLogger() // Global logger instance
+	db := c.DB.WithContext(ctx.UserContext())
+
+	logger := logger.FromContext(ctx.UserContext()) // Global logger instance
 
 	logger.Info("Fetching all users")
 	var users []*models.User
-	if err := c.DB.Find(&users).Error; err != nil {
+	if err := db.Find(&users).Error; err != nil {
 		logger.Error("Failed to fetch users", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to fetch users",
-		})
+		return apperror.Internal("Failed to fetch users")
 	}
 
 	logger.Info("Fetched users successfully")
@@ -58,30 +57,31 @@ func (c *UserController) GetAllUsers(ctx *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} fiber.Map{message=string, data=models.User}
-// @Failure 404 {object} fiber.Map{message=string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /users/{id} [get]
 func (c *UserController) GetUserById(ctx *fiber.Ctx) error {
-	logger := logger.GetLogger()
-	userID := ctx.Params("id")
+	db := c.DB.WithContext(ctx.UserContext())
+
+	logger := logger.FromContext(ctx.UserContext())
+	userID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
 
-	logger.Info("Fetching user by ID", zap.String("userID", userID))
+	logger.Info("Fetching user by ID", zap.Uint("userID", userID))
 
 	var user models.User
-	if err := c.DB.First(&user, userID).Error; err != nil {
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			logger.Warn("User not found", zap.String("userID", userID))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"message": "User not found",
-			})
+			logger.Warn("User not found", zap.Uint("userID", userID))
+			return apperror.NotFound("User not found")
 		}
-		logger.Error("Failed to fetch user", zap.Error(err), zap.String("userID", userID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to fetch user",
-		})
+		logger.Error("Failed to fetch user", zap.Error(err), zap.Uint("userID", userID))
+		return apperror.Internal("Failed to fetch user")
 	}
 
-	logger.Info("User fetched successfully", zap.String("userID", userID))
+	logger.Info("User fetched successfully", zap.Uint("userID", userID))
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "success",
 		"data":    user,
@@ -90,53 +90,43 @@ func (c *UserController) GetUserById(ctx *fiber.Ctx) error {
 
 // CreateUser godoc
 // @Summary Create a new user
-// @Description Create a new user with the input payload
+// @Description Create a new user with the input payload. Admins only; users sign up with /register.
 // @Tags User
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param user body models.UserCreateRequest true "User Create Payload"
 // @Success 201 {object} fiber.Map{message=string, data=models.User}
-// @Failure 400 {object} fiber.Map{message=string, errors=map[string]string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 403 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /users [post]
 func (c *UserController) CreateUser(ctx *fiber.Ctx) error {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(ctx.UserContext())
 	logger.Info("UserController.CreateUser Begin")
+	db := c.DB.WithContext(ctx.UserContext())
 
 	// Initialize validator and register custom validations
-	validate := validator.New()
-	validate.RegisterValidation("unique_username", validation.UniqueUsername(c.DB, 0))
-	validate.RegisterValidation("unique_email", validation.UniqueEmail(c.DB, 0))
+	validate := validation.New()
+	validate.RegisterValidation("unique_username", validation.UniqueUsername(db, 0))
+	validate.RegisterValidation("unique_email", validation.UniqueEmail(db, 0))
 
 	var req models.UserCreateRequest // Assuming UserCreateRequest is defined in models package
 	if err := ctx.BodyParser(&req); err != nil {
 		logger.Error("Failed to parse request body", zap.Error(err))
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid Request",
-			"errors": map[string]string{
-				"body": "Failed to parse request body",
-			},
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	if err := validate.Struct(&req); err != nil {
 		logger.Error("Validation failed", zap.Error(err))
-		validationErrors := make(map[string]string)
-		for _, vErr := range err.(validator.ValidationErrors) {
-			validationErrors[strings.ToLower(vErr.Field())] = vErr.Error()
-		}
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Validation failed",
-			"errors":  validationErrors,
-		})
+		return apperror.Validation(err)
 	}
 
 	hash, err := jwt.HashPassword(req.Password)
 	if err != nil {
 		logger.Error("Failed to hash password", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to hash password",
-		})
+		return apperror.Internal("Failed to hash password")
 	}
 
 	user := models.User{
@@ -147,11 +137,9 @@ func (c *UserController) CreateUser(ctx *fiber.Ctx) error {
 		Role:     "user", // Default role, or get from request if applicable
 	}
 
-	if err := c.DB.Create(&user).Error; err != nil {
+	if err := db.Create(&user).Error; err != nil {
 		logger.Error("Failed to create user", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to create user",
-		})
+		return apperror.Internal("Failed to create user")
 	}
 
 	// It's good practice to not return the password hash in the response.
@@ -159,7 +147,7 @@ func (c *UserController) CreateUser(ctx *fiber.Ctx) error {
 	// For simplicity, returning the user object (excluding password).
 	user.Password = "" // Clear password before sending response
 
-	logger.Info("User created successfully", zap.Uint("userID", user.UID))
+	logger.Info("User created successfully", zap.Uint("userID", user.ID))
 	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "User created successfully",
 		"data":    user,
@@ -168,57 +156,54 @@ func (c *UserController) CreateUser(ctx *fiber.Ctx) error {
 
 // UpdateUser godoc
 // @Summary Update an existing user
-// @Description Update an existing user with the input payload
+// @Description Update an existing user, including their role, with the input payload. Admins only.
 // @Tags User
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "User ID"
 // @Param user body models.UserUpdateRequest true "User Update Payload"
 // @Success 200 {object} fiber.Map{message=string, data=models.User}
-// @Failure 400 {object} fiber.Map{message=string, errors=map[string]string}
-// @Failure 404 {object} fiber.Map{message=string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 400 {object} apperror.Problem
+// @Failure 401 {object} apperror.Problem
+// @Failure 403 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /users/{id} [put]
 func (c *UserController) UpdateUser(ctx *fiber.Ctx) error {
-	logger := logger.GetLogger()
-	userID := ctx.Params("id")
-	logger.Info("UserController.UpdateUser Begin", zap.String("userID", userID))
+	logger := logger.FromContext(ctx.UserContext())
+	userID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	logger.Info("UserController.UpdateUser Begin", zap.Uint("userID", userID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var user models.User
-	if err := c.DB.First(&user, userID).Error; err != nil {
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			logger.Warn("User not found for update", zap.String("userID", userID))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User not found"})
+			logger.Warn("User not found for update", zap.Uint("userID", userID))
+			return apperror.NotFound("User not found")
 		}
-		logger.Error("Failed to fetch user for update", zap.Error(err), zap.String("userID", userID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch user"})
+		logger.Error("Failed to fetch user for update", zap.Error(err), zap.Uint("userID", userID))
+		return apperror.Internal("Failed to fetch user")
 	}
 
 	// Initialize validator and register custom validations
-	validate := validator.New()
+	validate := validation.New()
 	// Pass user.ID to ignore current user's email/username in unique checks
-	validate.RegisterValidation("unique_username", validation.UniqueUsername(c.DB, user.ID))
-	validate.RegisterValidation("unique_email", validation.UniqueEmail(c.DB, user.ID))
+	validate.RegisterValidation("unique_username", validation.UniqueUsername(db, int64(user.ID)))
+	validate.RegisterValidation("unique_email", validation.UniqueEmail(db, int64(user.ID)))
 
 	var req models.UserUpdateRequest // Assuming UserUpdateRequest is defined in models package
 	if err := ctx.BodyParser(&req); err != nil {
 		logger.Error("Failed to parse request body for update", zap.Error(err))
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid Request",
-			"errors":  map[string]string{"body": "Failed to parse request body"},
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	if err := validate.Struct(&req); err != nil {
 		logger.Error("Validation failed for update", zap.Error(err))
-		validationErrors := make(map[string]string)
-		for _, vErr := range err.(validator.ValidationErrors) {
-			validationErrors[strings.ToLower(vErr.Field())] = vErr.Error()
-		}
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Validation failed",
-			"errors":  validationErrors,
-		})
+		return apperror.Validation(err)
 	}
 
 	// Update fields
@@ -238,28 +223,25 @@ func (c *UserController) UpdateUser(ctx *fiber.Ctx) error {
 	// Update password if provided and valid
 	if req.Password != "" {
 		if req.PasswordConfirmation == "" || req.Password != req.PasswordConfirmation {
-			logger.Warn("Password confirmation does not match for update", zap.String("userID", userID))
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "Validation failed",
-				"errors":  map[string]string{"password_confirmation": "Password confirmation does not match"},
-			})
+			logger.Warn("Password confirmation does not match for update", zap.Uint("userID", userID))
+			return apperror.InvalidField("password_confirmation", "Password confirmation does not match")
 		}
 		hashedPassword, err := jwt.HashPassword(req.Password)
 		if err != nil {
-			logger.Error("Failed to hash new password for update", zap.Error(err), zap.String("userID", userID))
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update password"})
+			logger.Error("Failed to hash new password for update", zap.Error(err), zap.Uint("userID", userID))
+			return apperror.Internal("Failed to update password")
 		}
 		user.Password = hashedPassword
 	}
 
 	// Save updates
-	if err := c.DB.Save(&user).Error; err != nil {
-		logger.Error("Failed to update user", zap.Error(err), zap.String("userID", userID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update user"})
+	if err := db.Save(&user).Error; err != nil {
+		logger.Error("Failed to update user", zap.Error(err), zap.Uint("userID", userID))
+		return apperror.Internal("Failed to update user")
 	}
 
 	user.Password = "" // Clear password before sending response
-	logger.Info("User updated successfully", zap.String("userID", userID))
+	logger.Info("User updated successfully", zap.Uint("userID", userID))
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "User updated successfully",
 		"data":    user,
@@ -267,47 +249,58 @@ func (c *UserController) UpdateUser(ctx *fiber.Ctx) error {
 }
 
 func (c *UserController) DeleteUser(ctx *fiber.Ctx) error {
-	logger := logger.GetLogger()
-	userID := ctx.Params("id")
-	logger.Info("UserController.DeleteUser Begin", zap.String("userID", userID))
+	logger := logger.FromContext(ctx.UserContext())
+	userID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	logger.Info("UserController.DeleteUser Begin", zap.Uint("userID", userID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var user models.User
 	// First, check if the user exists
-	if err := c.DB.First(&user, userID).Error; err != nil {
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			logger.Warn("User not found for hard delete", zap.String("userID", userID))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User not found"})
+			logger.Warn("User not found for hard delete", zap.Uint("userID", userID))
+			return apperror.NotFound("User not found")
 		}
-		logger.Error("Failed to fetch user for hard delete", zap.Error(err), zap.String("userID", userID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch user"})
+		logger.Error("Failed to fetch user for hard delete", zap.Error(err), zap.Uint("userID", userID))
+		return apperror.Internal("Failed to fetch user")
 	}
 
 	// Perform hard delete
 	// Use Unscoped to permanently delete the record, bypassing GORM's soft delete
-	if err := c.DB.Unscoped().Delete(&models.User{}, userID).Error; err != nil {
-		logger.Error("Failed to hard delete user", zap.Error(err), zap.String("userID", userID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to delete user"})
+	if err := db.Unscoped().Where("id = ?", userID).Delete(&models.User{}).Error; err != nil {
+		logger.Error("Failed to hard delete user", zap.Error(err), zap.Uint("userID", userID))
+		return apperror.Internal("Failed to delete user")
 	}
 
-	logger.Info("User hard deleted successfully", zap.String("userID", userID))
+	logger.Info("User hard deleted successfully", zap.Uint("userID", userID))
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "User deleted successfully"})
 }
 
 // SoftDeleteUser godoc
 // @Summary Soft delete a user
-// @Description Soft delete a user by their ID (sets DeletedAt and DeletedBy fields)
+// @Description Soft delete a user by their ID (sets DeletedAt and DeletedBy fields). Admins only.
 // @Tags User
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "User ID"
 // @Success 200 {object} fiber.Map{message=string}
-// @Failure 404 {object} fiber.Map{message=string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 401 {object} apperror.Problem
+// @Failure 403 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /users/{id}/soft-delete [patch]
 func (c *UserController) SoftDeleteUser(ctx *fiber.Ctx) error {
-	logger := logger.GetLogger()
-	userID := ctx.Params("id") // ID of the user to be soft-deleted
-	logger.Info("UserController.SoftDeleteUser Begin", zap.String("userID", userID))
+	logger := logger.FromContext(ctx.UserContext())
+	userID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	logger.Info("UserController.SoftDeleteUser Begin", zap.Uint("userID", userID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	// In a real application, you would get the ID of the user performing the action
 	// from JWT claims or session. For now, let's use a placeholder.
@@ -315,27 +308,27 @@ func (c *UserController) SoftDeleteUser(ctx *fiber.Ctx) error {
 	adminUserID := int64(1) // Placeholder admin/system user ID
 
 	var user models.User
-	if err := c.DB.First(&user, userID).Error; err != nil {
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			logger.Warn("User not found for soft delete", zap.String("userID", userID))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User not found"})
+			logger.Warn("User not found for soft delete", zap.Uint("userID", userID))
+			return apperror.NotFound("User not found")
 		}
-		logger.Error("Failed to fetch user for soft delete", zap.Error(err), zap.String("userID", userID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch user"})
+		logger.Error("Failed to fetch user for soft delete", zap.Error(err), zap.Uint("userID", userID))
+		return apperror.Internal("Failed to fetch user")
 	}
 
 	// Update DeletedBy and then perform GORM's soft delete
 	// GORM's Delete method will automatically set DeletedAt if the model has gorm.DeletedAt field
-	if err := c.DB.Model(&user).Update("DeletedBy", adminUserID).Error; err != nil {
-		logger.Error("Failed to update DeletedBy for soft delete", zap.Error(err), zap.String("userID", userID))
+	if err := db.Model(&user).Update("DeletedBy", adminUserID).Error; err != nil {
+		logger.Error("Failed to update DeletedBy for soft delete", zap.Error(err), zap.Uint("userID", userID))
 		// Proceed with soft delete even if DeletedBy update fails, or handle as critical error
 	}
 
-	if err := c.DB.Delete(&user).Error; err != nil {
-		logger.Error("Failed to soft delete user", zap.Error(err), zap.String("userID", userID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to soft delete user"})
+	if err := db.Delete(&user).Error; err != nil {
+		logger.Error("Failed to soft delete user", zap.Error(err), zap.Uint("userID", userID))
+		return apperror.Internal("Failed to soft delete user")
 	}
 
-	logger.Info("User soft deleted successfully", zap.String("userID", userID))
+	logger.Info("User soft deleted successfully", zap.Uint("userID", userID))
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "User soft deleted successfully"})
 }
@@ -2,9 +2,11 @@ package controllers
 
 import (
 	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/isbn"
 	"ayo-baca-buku/app/util/logger"
-	"ayo-baca-buku/app/util/validation" // Assuming validation utilities might be needed
-	"strings"
+	"ayo-baca-buku/app/util/metrics"
+	"ayo-baca-buku/app/util/validation"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -19,12 +21,22 @@ type UserBookController struct {
 }
 
 func NewUserBookController(DB *gorm.DB) *UserBookController {
+	validate := validation.New()
+	// Replaces the built-in isbn rule, which also accepts non-Bookland EAN-13 codes
+	validate.RegisterValidation("isbn", validation.ISBN)
+
 	return &UserBookController{
 		DB:       DB,
-		Validate: validator.New(),
+		Validate: validate,
 	}
 }
 
+// isbnOf returns the ISBN-13 form of a validated ISBN.
+func isbnOf(value string) string {
+	normalized, _ := isbn.Normalize(value)
+	return normalized
+}
+
 // CreateUserBook godoc
 // @Summary Create a new user book entry
 // @Description Add a new book to a user's reading list.
@@ -33,49 +45,37 @@ func NewUserBookController(DB *gorm.DB) *UserBookController {
 // @Produce json
 // @Param userbook body models.UserBookCreateRequest true "User Book Create Payload"
 // @Success 201 {object} fiber.Map{message=string, data=models.UserBook}
-// @Failure 400 {object} fiber.Map{message=string, errors=map[string]string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /userbooks [post]
 func (c *UserBookController) CreateUserBook(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
+	log := logger.FromContext(ctx.UserContext())
 	log.Info("UserBookController.CreateUserBook Begin")
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var req models.UserBookCreateRequest
 	if err := ctx.BodyParser(&req); err != nil {
 		log.Error("Failed to parse request body", zap.Error(err))
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid Request",
-			"errors":  map[string]string{"body": "Failed to parse request body"},
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	// TODO: In a real app, UserID might come from JWT token/auth context.
 	// For now, it's in the request. We should validate if this user exists.
 	var user models.User
-	if err := c.DB.First(&user, req.UserID).Error; err != nil {
+	if err := db.First(&user, req.UserID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			log.Warn("User not found for UserBook creation", zap.Uint("userID", req.UserID))
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "Validation failed",
-				"errors":  map[string]string{"user_id": "User not found"},
-			})
+			return apperror.InvalidField("user_id", "User not found")
 		}
 		log.Error("Failed to check user existence", zap.Error(err), zap.Uint("userID", req.UserID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Error checking user"})
+		return apperror.Internal("Error checking user")
 	}
 
 	// Initialize validator (could be part of controller struct if reused often without per-handler registration)
-	// c.Validate.RegisterValidation("custom_validation_if_any", validation.CustomValidationFunction(c.DB))
+	// c.Validate.RegisterValidation("custom_validation_if_any", validation.CustomValidationFunction(db))
 	if err := c.Validate.Struct(&req); err != nil {
 		log.Error("Validation failed for UserBook creation", zap.Error(err))
-		validationErrors := make(map[string]string)
-		for _, vErr := range err.(validator.ValidationErrors) {
-			validationErrors[strings.ToLower(vErr.Field())] = vErr.Error()
-		}
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Validation failed",
-			"errors":  validationErrors,
-		})
+		return apperror.Validation(err)
 	}
 
 	userBook := models.UserBook{
@@ -83,6 +83,8 @@ func (c *UserBookController) CreateUserBook(ctx *fiber.Ctx) error {
 		Title:          req.Title,
 		Author:         req.Author,
 		Publisher:      req.Publisher,
+		Genre:          req.Genre,
+		ISBN:           isbnOf(req.ISBN),
 		Cover:          req.Cover,
 		TotalPages:     req.TotalPages,
 		MotivationRead: req.MotivationRead,
@@ -94,11 +96,9 @@ func (c *UserBookController) CreateUserBook(ctx *fiber.Ctx) error {
 		UpdatedBy:   int64(req.UserID), // Placeholder for actor ID
 	}
 
-	if err := c.DB.Create(&userBook).Error; err != nil {
+	if err := db.Create(&userBook).Error; err != nil {
 		log.Error("Failed to create UserBook in database", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to create user book entry",
-		})
+		return apperror.Internal("Failed to create user book entry")
 	}
 
 	log.Info("UserBook created successfully", zap.Uint("userBookID", userBook.ID))
@@ -117,49 +117,43 @@ func (c *UserBookController) CreateUserBook(ctx *fiber.Ctx) error {
 // @Param id path int true "UserBook ID"
 // @Param userbook_update body models.UserBookUpdateRequest true "User Book Update Payload"
 // @Success 200 {object} fiber.Map{message=string, data=models.UserBook}
-// @Failure 400 {object} fiber.Map{message=string, errors=map[string]string}
-// @Failure 404 {object} fiber.Map{message=string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /userbooks/{id} [put]
 func (c *UserBookController) UpdateUserBook(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
-	userBookID := ctx.Params("id")
-	log.Info("UserBookController.UpdateUserBook Begin", zap.String("userBookID", userBookID))
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	log.Info("UserBookController.UpdateUserBook Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var req models.UserBookUpdateRequest
 	if err := ctx.BodyParser(&req); err != nil {
 		log.Error("Failed to parse request body for UserBook update", zap.Error(err))
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid Request",
-			"errors":  map[string]string{"body": "Failed to parse request body"},
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	// Validate the request payload
 	if err := c.Validate.Struct(&req); err != nil {
 		log.Error("Validation failed for UserBook update", zap.Error(err))
-		validationErrors := make(map[string]string)
-		for _, vErr := range err.(validator.ValidationErrors) {
-			validationErrors[strings.ToLower(vErr.Field())] = vErr.Error()
-		}
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Validation failed",
-			"errors":  validationErrors,
-		})
+		return apperror.Validation(err)
 	}
 
 	var userBook models.UserBook
-	if err := c.DB.First(&userBook, userBookID).Error; err != nil {
+	if err := db.Where("id = ?", userBookID).First(&userBook).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Warn("UserBook not found for update", zap.String("userBookID", userBookID))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User book not found"})
+			log.Warn("UserBook not found for update", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
 		}
-		log.Error("Failed to fetch UserBook for update", zap.Error(err), zap.String("userBookID", userBookID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch user book"})
+		log.Error("Failed to fetch UserBook for update", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to fetch user book")
 	}
 
 	// TODO: Authorization check: Does the authenticated user own this book or have rights to update it?
-	// For example: if authenticatedUserID != userBook.UserID && !isAdmin(authenticatedUser) { return ctx.Status(fiber.StatusForbidden).JSON(...) }
+	// For example: if authenticatedUserID != userBook.UserID && !isAdmin(authenticatedUser) { return apperror.Forbidden(...) }
 	// For now, assume actorID for UpdatedBy can be derived or is a placeholder
 	actorID := userBook.UserID // Placeholder for who is performing the update
 
@@ -173,6 +167,12 @@ func (c *UserBookController) UpdateUserBook(ctx *fiber.Ctx) error {
 	if req.Publisher != "" { // omitempty means empty string is a valid "not provided"
 		userBook.Publisher = req.Publisher
 	}
+	if req.Genre != "" {
+		userBook.Genre = req.Genre
+	}
+	if req.ISBN != "" {
+		userBook.ISBN = isbnOf(req.ISBN)
+	}
 	if req.Cover != "" { // omitempty means empty string is a valid "not provided"
 		userBook.Cover = req.Cover
 	}
@@ -185,6 +185,7 @@ func (c *UserBookController) UpdateUserBook(ctx *fiber.Ctx) error {
 	if req.MotivationRead != "" { // omitempty means empty string is a valid "not provided"
 		userBook.MotivationRead = req.MotivationRead
 	}
+	wasFinished := userBook.Status == "finished"
 	if req.Status != "" {
 		userBook.Status = req.Status
 	}
@@ -201,11 +202,13 @@ func (c *UserBookController) UpdateUserBook(ctx *fiber.Ctx) error {
 
 	userBook.UpdatedBy = int64(actorID) // Placeholder
 
-	if err := c.DB.Save(&userBook).Error; err != nil {
+	if err := db.Save(&userBook).Error; err != nil {
 		log.Error("Failed to update UserBook in database", zap.Error(err), zap.Uint("userBookID", userBook.ID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to update user book entry",
-		})
+		return apperror.Internal("Failed to update user book entry")
+	}
+
+	if !wasFinished && userBook.Status == "finished" {
+		metrics.BooksFinished.Inc()
 	}
 
 	log.Info("UserBook updated successfully", zap.Uint("userBookID", userBook.ID))
@@ -223,23 +226,27 @@ func (c *UserBookController) UpdateUserBook(ctx *fiber.Ctx) error {
 // @Produce json
 // @Param id path int true "UserBook ID"
 // @Success 200 {object} fiber.Map{message=string}
-// @Failure 403 {object} fiber.Map{message=string} // Forbidden if user doesn't own the book
-// @Failure 404 {object} fiber.Map{message=string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 403 {object} apperror.Problem // Forbidden if user doesn't own the book
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /userbooks/{id} [delete]
 func (c *UserBookController) DeleteUserBook(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
-	userBookID := ctx.Params("id")
-	log.Info("UserBookController.DeleteUserBook Begin", zap.String("userBookID", userBookID))
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	log.Info("UserBookController.DeleteUserBook Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var userBook models.UserBook
-	if err := c.DB.First(&userBook, userBookID).Error; err != nil {
+	if err := db.Where("id = ?", userBookID).First(&userBook).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Warn("UserBook not found for deletion", zap.String("userBookID", userBookID))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User book not found"})
+			log.Warn("UserBook not found for deletion", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
 		}
-		log.Error("Failed to fetch UserBook for deletion", zap.Error(err), zap.String("userBookID", userBookID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch user book"})
+		log.Error("Failed to fetch UserBook for deletion", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to fetch user book")
 	}
 
 	// TODO: Authorization check: Does the authenticated user own this book or have rights to delete it?
@@ -247,23 +254,21 @@ func (c *UserBookController) DeleteUserBook(ctx *fiber.Ctx) error {
 	// authenticatedUserID := GetAuthenticatedUserID(ctx) // Implement this helper
 	// if authenticatedUserID != userBook.UserID && !IsAdmin(authenticatedUserID) {
 	//     log.Warn("User not authorized to delete UserBook", zap.Uint("userBookID", userBook.ID), zap.Uint("attemptedByUserID", authenticatedUserID))
-	//     return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "You are not authorized to delete this book entry"})
+	//     return apperror.Forbidden("You are not authorized to delete this book entry")
 	// }
 	actorID := userBook.UserID // Placeholder for who is performing the delete
 
 	// Update DeletedBy before soft deleting
 	// GORM's Delete will set DeletedAt automatically
-	if err := c.DB.Model(&userBook).Update("DeletedBy", int64(actorID)).Error; err != nil {
+	if err := db.Model(&userBook).Update("DeletedBy", int64(actorID)).Error; err != nil {
 		// Log the error but proceed with delete, as setting DeletedBy is audit info.
 		// Depending on requirements, this could be a critical failure.
 		log.Error("Failed to update DeletedBy for UserBook soft delete", zap.Error(err), zap.Uint("userBookID", userBook.ID))
 	}
 
-	if err := c.DB.Delete(&userBook).Error; err != nil {
+	if err := db.Delete(&userBook).Error; err != nil {
 		log.Error("Failed to soft delete UserBook in database", zap.Error(err), zap.Uint("userBookID", userBook.ID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to delete user book entry",
-		})
+		return apperror.Internal("Failed to delete user book entry")
 	}
 
 	log.Info("UserBook soft deleted successfully", zap.Uint("userBookID", userBook.ID))
@@ -272,36 +277,57 @@ func (c *UserBookController) DeleteUserBook(ctx *fiber.Ctx) error {
 
 // GetAllUserBooks godoc
 // @Summary Get all user books
-// @Description Get a list of all user books, optionally filtered by user_id.
+// @Description Get a list of all user books, optionally filtered by user_id, shelf and tag. Private shelves, and filtering by them, are only available to their owner.
 // @Tags UserBook
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param user_id query int false "Filter by User ID"
+// @Param shelf_id query int false "Only books on this shelf, in shelf order"
+// @Param tag query string false "Only books carrying this tag name"
 // @Success 200 {object} fiber.Map{message=string, data=[]models.UserBook}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 401 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /userbooks [get]
 func (c *UserBookController) GetAllUserBooks(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
+	log := logger.FromContext(ctx.UserContext())
 	log.Info("UserBookController.GetAllUserBooks Begin")
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var userBooks []models.UserBook
-	query := c.DB
+	query := db
 
 	// Optional filtering by user_id
 	userID := ctx.QueryInt("user_id")
 	if userID > 0 {
 		log.Info("Filtering UserBooks by UserID", zap.Int("userID", userID))
-		query = query.Where("user_id = ?", userID)
+		query = query.Where("user_books.user_id = ?", userID)
+	}
+
+	if shelfID := ctx.QueryInt("shelf_id"); shelfID > 0 {
+		log.Info("Filtering UserBooks by ShelfID", zap.Int("shelfID", shelfID))
+		// A private shelf is only browsable by its owner
+		query = query.Joins("JOIN user_book_shelves ubs ON ubs.user_book_id = user_books.id").
+			Joins("JOIN shelves ON shelves.id = ubs.shelf_id AND shelves.deleted_at IS NULL").
+			Where("ubs.shelf_id = ?", shelfID).
+			Scopes(visibleShelves(ctx)).
+			Order("ubs.position, ubs.created_at")
+	}
+
+	if tag := normalizeTagName(ctx.Query("tag")); tag != "" {
+		log.Info("Filtering UserBooks by Tag", zap.String("tag", tag))
+		query = query.Where("user_books.id IN (?)", db.Table("user_book_tags ubt").
+			Select("ubt.user_book_id").
+			Joins("JOIN tags t ON t.id = ubt.tag_id").
+			Where("t.name = ?", tag))
 	}
 
 	// Preload ReadingActivities and User for richer data. Consider if this is always needed.
 	// For now, let's preload User to show who the book belongs to if not filtering.
 	// ReadingActivities might be too much for a general list, better for GetUserBookByID.
-	if err := query.Preload("User").Find(&userBooks).Error; err != nil {
+	if err := query.Preload("User").Preload("Shelves", visibleShelves(ctx)).Preload("Tags").Find(&userBooks).Error; err != nil {
 		log.Error("Failed to fetch user books from database", zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to fetch user books",
-		})
+		return apperror.Internal("Failed to fetch user books")
 	}
 
 	log.Info("UserBooks fetched successfully", zap.Int("count", len(userBooks)))
@@ -313,19 +339,25 @@ func (c *UserBookController) GetAllUserBooks(ctx *fiber.Ctx) error {
 
 // GetUserBookByID godoc
 // @Summary Get a user book by its ID
-// @Description Get details of a specific user book, including its owner and reading activities.
+// @Description Get details of a specific user book, including its owner and reading activities. Private shelves are only listed for their owner.
 // @Tags UserBook
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "UserBook ID"
 // @Success 200 {object} fiber.Map{message=string, data=models.UserBook}
-// @Failure 404 {object} fiber.Map{message=string}
-// @Failure 500 {object} fiber.Map{message=string}
+// @Failure 401 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /userbooks/{id} [get]
 func (c *UserBookController) GetUserBookByID(ctx *fiber.Ctx) error {
-	log := logger.GetLogger()
-	userBookID := ctx.Params("id") // This will be a string, needs conversion if your ID is int
-	log.Info("UserBookController.GetUserBookByID Begin", zap.String("userBookID", userBookID))
+	log := logger.FromContext(ctx.UserContext())
+	userBookID, err := paramID(ctx, "id")
+	if err != nil {
+		return err
+	}
+	log.Info("UserBookController.GetUserBookByID Begin", zap.Uint("userBookID", userBookID))
+	db := c.DB.WithContext(ctx.UserContext())
 
 	var userBook models.UserBook
 
@@ -333,21 +365,17 @@ func (c *UserBookController) GetUserBookByID(ctx *fiber.Ctx) error {
 	// Convert userBookID to appropriate type for GORM if necessary (e.g., to uint)
 	// For now, GORM might handle string-to-int conversion for primary keys, but being explicit is better.
 	// Let's assume ID in path is parseable to uint for the model's ID type.
-	if err := c.DB.Preload("User").Preload("ReadingActivities").First(&userBook, userBookID).Error; err != nil {
+	if err := db.Preload("User").Preload("ReadingActivities").Preload("Shelves", visibleShelves(ctx)).Preload("Tags").Where("id = ?", userBookID).First(&userBook).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Warn("UserBook not found by ID", zap.String("userBookID", userBookID))
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"message": "User book not found",
-			})
+			log.Warn("UserBook not found by ID", zap.Uint("userBookID", userBookID))
+			return apperror.NotFound("User book not found")
 		}
-		log.Error("Failed to fetch UserBook by ID from database", zap.Error(err), zap.String("userBookID", userBookID))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to fetch user book",
-		})
+		log.Error("Failed to fetch UserBook by ID from database", zap.Error(err), zap.Uint("userBookID", userBookID))
+		return apperror.Internal("Failed to fetch user book")
 	}
 
 	// TODO: Authorization check: Does the authenticated user own this book or have rights to view it?
-	// For example: if authenticatedUserID != userBook.UserID && !isAdmin(authenticatedUser) { return ctx.Status(fiber.StatusForbidden).JSON(...) }
+	// For example: if authenticatedUserID != userBook.UserID && !isAdmin(authenticatedUser) { return apperror.Forbidden(...) }
 
 	log.Info("UserBook fetched successfully by ID", zap.Uint("userBookID", userBook.ID))
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
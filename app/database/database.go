@@ -2,10 +2,8 @@ package database
 
 import (
 	"ayo-baca-buku/app/config"
-	"ayo-baca-buku/app/database/seeders"
 	"ayo-baca-buku/app/models"
 	"ayo-baca-buku/app/util/logger"
-	"log"
 
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
@@ -14,49 +12,47 @@ import (
 	gormLogger "gorm.io/gorm/logger"
 )
 
-func NewDatabase(zLogger *zap.Logger) (*gorm.DB, error) {
-	appConfig, err := config.LoadAppConfig(".")
+func NewDatabase(zLogger *zap.Logger, dbConfig config.DBConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(dbConfig.DSN()), &gorm.Config{})
 	if err != nil {
-		log.Fatal("cannot load config:", err)
+		return nil, err
 	}
 
-	dbSource := appConfig.DB_SOURCE
-	db, err := gorm.Open(postgres.Open(dbSource), &gorm.Config{})
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(dbConfig.ConnMaxIdleTime)
+
+	// DB_DEBUG logs every statement; otherwise only failed and slow ones.
+	gormLevel := gormLogger.Warn
+	if dbConfig.Debug {
+		gormLevel = gormLogger.Info
+	}
+	db.Logger = &logger.GormLogger{
+		ZapLogger:     zLogger,
+		LogLevel:      gormLevel,
+		SlowThreshold: dbConfig.SlowQuery,
+	}
 
-	dbDebug := appConfig.DB_DEBUG
-	gormLevel := gormLogger.Info
-
-	if dbDebug != true {
-		gormLevel = gormLogger.Silent
+	// Shelf membership carries a position, so GORM must use the custom join model
+	if err := db.SetupJoinTable(&models.Shelf{}, "UserBooks", &models.UserBookShelf{}); err != nil {
+		return nil, err
 	}
-	dbLogger := &logger.GormLogger{
-		ZapLogger: zLogger,
-		LogLevel:  gormLogger.Info,
+	if err := db.SetupJoinTable(&models.UserBook{}, "Shelves", &models.UserBookShelf{}); err != nil {
+		return nil, err
 	}
-	db.Logger = dbLogger.LogMode(gormLevel)
 	return db, nil
 }
 
-func RunMigration(DB *gorm.DB) {
-	logger := logger.GetLogger()
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.UserBook{},
-		&models.ReadingActivity{},
-	)
-
+// Close closes the connection pool of DB. Queries still running are waited for.
+func Close(DB *gorm.DB) error {
+	sqlDB, err := DB.DB()
 	if err != nil {
-		logger.Fatal("Failed to migrate...")
+		return err
 	}
-
-	logger.Info("Migrated Successfully")
-}
-
-func RunSeeder(DB *gorm.DB) {
-	logger := logger.GetLogger()
-	seeders.SeedUser(DB)
-	logger.Info("Seeder Successfully")
+	return sqlDB.Close()
 }
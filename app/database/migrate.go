@@ -0,0 +1,302 @@
+package database
+
+import (
+	"ayo-baca-buku/app/database/migrations"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockKey is the Postgres advisory lock held while migrating, so two
+// instances started at the same time do not run the same migration.
+const migrationLockKey int64 = 0x61796f6261636121
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS "schema_migrations" (
+	"version" bigint PRIMARY KEY,
+	"name" varchar(255) NOT NULL,
+	"applied_at" timestamptz NOT NULL DEFAULT now()
+)`
+
+// ErrSchemaOutdated is returned by CheckSchema when migrations are pending.
+var ErrSchemaOutdated = errors.New("database schema is not up to date")
+
+// Migration is one versioned schema change read from the migrations folder.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration is a row of schema_migrations, one per applied migration.
+type SchemaMigration struct {
+	Version   int64 `gorm:"primaryKey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// MigrationStatus tells whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// LoadMigrations reads the <version>_<name>.up.sql and .down.sql pairs of
+// fsys, ordered by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, fileName := range names {
+		base, direction := strings.TrimSuffix(fileName, ".sql"), ""
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			base, direction = strings.TrimSuffix(base, ".up"), "up"
+		case strings.HasSuffix(base, ".down"):
+			base, direction = strings.TrimSuffix(base, ".down"), "down"
+		default:
+			return nil, fmt.Errorf("migration %s must end in .up.sql or .down.sql", fileName)
+		}
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s must start with a version number", fileName)
+		}
+
+		data, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migrations %d_%s and %d_%s share a version", version, migration.Name, version, name)
+		}
+		if direction == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		list = append(list, *migration)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Migrator applies and reverts the embedded migrations.
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+// NewMigrator creates a migrator for the migrations embedded in the binary.
+func NewMigrator(DB *gorm.DB) (*Migrator, error) {
+	list, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: DB, Migrations: list}, nil
+}
+
+// Up applies all pending migrations in order and returns the ones it applied.
+// Each migration runs in its own transaction.
+func (m *Migrator) Up() ([]Migration, error) {
+	var done []Migration
+	for _, migration := range m.Migrations {
+		ran := false
+		err := m.locked(func(tx *gorm.DB, applied map[int64]SchemaMigration) error {
+			// Another instance may have applied it while we waited for the lock.
+			if _, ok := applied[migration.Version]; ok {
+				return nil
+			}
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			ran = true
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, migration)
+		}
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	known := make(map[int64]Migration, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		known[migration.Version] = migration
+	}
+
+	var done []Migration
+	for i := 0; i < steps; i++ {
+		var reverted *Migration
+		err := m.locked(func(tx *gorm.DB, applied map[int64]SchemaMigration) error {
+			var latest int64
+			for version := range applied {
+				if version > latest {
+					latest = version
+				}
+			}
+			if latest == 0 {
+				return nil
+			}
+			migration, ok := known[latest]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but not part of this build", latest)
+			}
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return fmt.Errorf("revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = &migration
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		if reverted == nil {
+			break
+		}
+		done = append(done, *reverted)
+	}
+	return done, nil
+}
+
+// Status lists the migrations of this build and whether they are applied. It
+// does not change the database.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied(m.DB)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		list = append(list, status)
+	}
+	return list, nil
+}
+
+// Version returns the highest applied migration, 0 for an empty database.
+func (m *Migrator) Version() (int64, error) {
+	applied, err := m.applied(m.DB)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied(m.DB)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// locked runs fn in a transaction holding the migration lock, with the
+// migrations applied at the time the lock was taken.
+func (m *Migrator) locked(fn func(tx *gorm.DB, applied map[int64]SchemaMigration) error) error {
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("lock migrations: %w", err)
+		}
+		if err := tx.Exec(createSchemaMigrations).Error; err != nil {
+			return fmt.Errorf("create schema_migrations: %w", err)
+		}
+		applied, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		return fn(tx, applied)
+	})
+}
+
+// applied reads schema_migrations. A database without the table has no
+// migrations applied.
+func (m *Migrator) applied(db *gorm.DB) (map[int64]SchemaMigration, error) {
+	applied := make(map[int64]SchemaMigration)
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// CheckSchema verifies that the database has exactly the migrations of this
+// build applied, without changing it. Migrations are applied with the migrate
+// command.
+func CheckSchema(DB *gorm.DB) error {
+	migrator, err := NewMigrator(DB)
+	if err != nil {
+		return err
+	}
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	if latest := migrator.Migrations[len(migrator.Migrations)-1].Version; version > latest {
+		return fmt.Errorf("database schema version %d is newer than this build (%d)", version, latest)
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		names := make([]string, len(pending))
+		for i, migration := range pending {
+			names[i] = fmt.Sprintf("%d_%s", migration.Version, migration.Name)
+		}
+		return fmt.Errorf("%w: %s pending, run \"go run ./cmd migrate up\"", ErrSchemaOutdated, strings.Join(names, ", "))
+	}
+	return nil
+}
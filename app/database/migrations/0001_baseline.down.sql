@@ -0,0 +1,5 @@
+-- Drops every table of the baseline, and with it all data.
+
+DROP TABLE IF EXISTS "reading_activities";
+DROP TABLE IF EXISTS "user_books";
+DROP TABLE IF EXISTS "users";
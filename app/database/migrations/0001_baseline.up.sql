@@ -0,0 +1,66 @@
+-- Baseline: the schema GORM's AutoMigrate created before versioned
+-- migrations, for the users, user_books and reading_activities tables. Every
+-- statement is guarded so databases that were set up by AutoMigrate are
+-- adopted unchanged.
+
+CREATE TABLE IF NOT EXISTS "users" (
+	"id" bigserial,
+	"uid" uuid DEFAULT gen_random_uuid(),
+	"name" varchar(255) NOT NULL,
+	"username" varchar(100) NOT NULL,
+	"email" varchar(255) NOT NULL,
+	"token" varchar(255),
+	"password" varchar(255) NOT NULL,
+	"role" varchar(255),
+	"created_at" timestamptz,
+	"created_by" bigint,
+	"updated_at" timestamptz,
+	"updated_by" bigint,
+	"deleted_at" timestamptz,
+	"deleted_by" bigint,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "user_books" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"title" varchar(255) NOT NULL,
+	"author" varchar(255) NOT NULL,
+	"publisher" varchar(255),
+	"cover" varchar(255),
+	"total_pages" bigint NOT NULL,
+	"current_page" bigint DEFAULT 0,
+	"motivation_read" text,
+	"status" varchar(20) DEFAULT 'reading',
+	"start_date" timestamptz NOT NULL,
+	"end_date" timestamptz,
+	"created_at" timestamptz,
+	"created_by" bigint,
+	"updated_at" timestamptz,
+	"updated_by" bigint,
+	"deleted_at" timestamptz,
+	"deleted_by" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_users_user_books" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+	CONSTRAINT "chk_user_books_status" CHECK (status IN ('reading', 'finished'))
+);
+CREATE INDEX IF NOT EXISTS "idx_user_books_deleted_at" ON "user_books" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "reading_activities" (
+	"id" bigserial,
+	"user_book_id" bigint NOT NULL,
+	"pages_read" bigint NOT NULL,
+	"start_page" bigint NOT NULL,
+	"end_page" bigint NOT NULL,
+	"notes" text,
+	"reading_date" timestamptz NOT NULL,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_user_books_reading_activities" FOREIGN KEY ("user_book_id") REFERENCES "user_books"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_reading_activities_deleted_at" ON "reading_activities" ("deleted_at");
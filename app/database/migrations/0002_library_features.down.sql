@@ -0,0 +1,23 @@
+-- Drops the tables and columns of the library features, and with them their
+-- data.
+
+DROP TABLE IF EXISTS "import_jobs";
+DROP TABLE IF EXISTS "highlights";
+DROP TABLE IF EXISTS "review_revisions";
+DROP TABLE IF EXISTS "reviews";
+DROP TABLE IF EXISTS "reading_plans";
+DROP TABLE IF EXISTS "reading_goals";
+DROP TABLE IF EXISTS "streak_freezes";
+DROP TABLE IF EXISTS "user_book_shelves";
+DROP TABLE IF EXISTS "shelves";
+DROP TABLE IF EXISTS "user_book_tags";
+DROP TABLE IF EXISTS "tags";
+
+ALTER TABLE "reading_activities" DROP COLUMN IF EXISTS "duration";
+ALTER TABLE "user_books" DROP COLUMN IF EXISTS "isbn";
+ALTER TABLE "user_books" DROP COLUMN IF EXISTS "genre";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "rest_days";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "daily_minimum_minutes";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "daily_minimum_pages";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "timezone";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "calendar_token";
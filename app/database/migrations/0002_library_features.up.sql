@@ -0,0 +1,218 @@
+-- Statistics, streaks, goals, plans, the calendar feed, shelves and tags,
+-- reviews, highlights and imports. Statements are guarded as in the baseline:
+-- databases that AutoMigrate set up with some of these tables or columns
+-- already are brought up to date.
+
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "calendar_token" varchar(64);
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "timezone" varchar(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "daily_minimum_pages" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "daily_minimum_minutes" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "rest_days" varchar(100);
+CREATE INDEX IF NOT EXISTS "idx_users_calendar_token" ON "users" ("calendar_token");
+
+ALTER TABLE "user_books" ADD COLUMN IF NOT EXISTS "genre" varchar(100);
+ALTER TABLE "user_books" ADD COLUMN IF NOT EXISTS "isbn" varchar(13);
+CREATE INDEX IF NOT EXISTS "idx_user_books_isbn" ON "user_books" ("isbn");
+
+ALTER TABLE "reading_activities" ADD COLUMN IF NOT EXISTS "duration" bigint DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS "tags" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"name" varchar(50) NOT NULL,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_tags_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_tags_user_name" ON "tags" ("user_id", "name");
+
+CREATE TABLE IF NOT EXISTS "user_book_tags" (
+	"tag_id" bigint,
+	"user_book_id" bigint,
+	PRIMARY KEY ("tag_id", "user_book_id"),
+	CONSTRAINT "fk_user_book_tags_user_book" FOREIGN KEY ("user_book_id") REFERENCES "user_books"("id"),
+	CONSTRAINT "fk_user_book_tags_tag" FOREIGN KEY ("tag_id") REFERENCES "tags"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "shelves" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"name" varchar(100) NOT NULL,
+	"description" text,
+	"visibility" varchar(20) NOT NULL DEFAULT 'public',
+	"position" bigint NOT NULL DEFAULT 0,
+	"created_at" timestamptz,
+	"created_by" bigint,
+	"updated_at" timestamptz,
+	"updated_by" bigint,
+	"deleted_at" timestamptz,
+	"deleted_by" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_shelves_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+	CONSTRAINT "chk_shelves_visibility" CHECK (visibility IN ('public', 'private'))
+);
+CREATE INDEX IF NOT EXISTS "idx_shelves_user_id" ON "shelves" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_shelves_deleted_at" ON "shelves" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "user_book_shelves" (
+	"shelf_id" bigint,
+	"user_book_id" bigint,
+	"position" bigint NOT NULL DEFAULT 0,
+	"created_at" timestamptz,
+	PRIMARY KEY ("shelf_id", "user_book_id"),
+	CONSTRAINT "fk_user_book_shelves_shelf" FOREIGN KEY ("shelf_id") REFERENCES "shelves"("id"),
+	CONSTRAINT "fk_user_book_shelves_user_book" FOREIGN KEY ("user_book_id") REFERENCES "user_books"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_user_book_shelves_user_book_id" ON "user_book_shelves" ("user_book_id");
+
+CREATE TABLE IF NOT EXISTS "streak_freezes" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"freeze_date" date NOT NULL,
+	"reason" varchar(255),
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_streak_freezes_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_streak_freezes_user_date" ON "streak_freezes" ("user_id", "freeze_date");
+
+CREATE TABLE IF NOT EXISTS "reading_goals" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"title" varchar(255) NOT NULL,
+	"type" varchar(20) NOT NULL,
+	"target" bigint NOT NULL,
+	"start_date" date NOT NULL,
+	"end_date" date NOT NULL,
+	"created_at" timestamptz,
+	"created_by" bigint,
+	"updated_at" timestamptz,
+	"updated_by" bigint,
+	"deleted_at" timestamptz,
+	"deleted_by" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_reading_goals_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+	CONSTRAINT "chk_reading_goals_type" CHECK (type IN ('books', 'pages', 'pages_per_day'))
+);
+CREATE INDEX IF NOT EXISTS "idx_reading_goals_user_id" ON "reading_goals" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_reading_goals_deleted_at" ON "reading_goals" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "reading_plans" (
+	"id" bigserial,
+	"user_book_id" bigint NOT NULL,
+	"title" varchar(255),
+	"start_date" date NOT NULL,
+	"deadline" date NOT NULL,
+	"start_page" bigint NOT NULL DEFAULT 0,
+	"created_at" timestamptz,
+	"created_by" bigint,
+	"updated_at" timestamptz,
+	"updated_by" bigint,
+	"deleted_at" timestamptz,
+	"deleted_by" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_reading_plans_user_book" FOREIGN KEY ("user_book_id") REFERENCES "user_books"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_reading_plans_user_book_id" ON "reading_plans" ("user_book_id");
+CREATE INDEX IF NOT EXISTS "idx_reading_plans_deleted_at" ON "reading_plans" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "reviews" (
+	"id" bigserial,
+	"user_book_id" bigint NOT NULL,
+	"user_id" bigint NOT NULL,
+	"rating" numeric(2,1) NOT NULL,
+	"body" text,
+	"spoiler" boolean NOT NULL DEFAULT false,
+	"visibility" varchar(20) NOT NULL DEFAULT 'public',
+	"edit_count" bigint NOT NULL DEFAULT 0,
+	"created_at" timestamptz,
+	"created_by" bigint,
+	"updated_at" timestamptz,
+	"updated_by" bigint,
+	"deleted_at" timestamptz,
+	"deleted_by" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_reviews_user_book" FOREIGN KEY ("user_book_id") REFERENCES "user_books"("id"),
+	CONSTRAINT "fk_reviews_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+	CONSTRAINT "chk_reviews_visibility" CHECK (visibility IN ('public', 'private')),
+	CONSTRAINT "chk_reviews_rating" CHECK (rating >= 0.5 AND rating <= 5)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_reviews_user_book" ON "reviews" ("user_book_id") WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS "idx_reviews_user_id" ON "reviews" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_reviews_deleted_at" ON "reviews" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "review_revisions" (
+	"id" bigserial,
+	"review_id" bigint NOT NULL,
+	"rating" numeric(2,1) NOT NULL,
+	"body" text,
+	"spoiler" boolean,
+	"visibility" varchar(20) NOT NULL,
+	"written_at" timestamptz,
+	"created_at" timestamptz,
+	"created_by" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_reviews_revisions" FOREIGN KEY ("review_id") REFERENCES "reviews"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_review_revisions_review_id" ON "review_revisions" ("review_id");
+
+CREATE TABLE IF NOT EXISTS "highlights" (
+	"id" bigserial,
+	"user_book_id" bigint NOT NULL,
+	"user_id" bigint NOT NULL,
+	"kind" varchar(20) NOT NULL DEFAULT 'highlight',
+	"source" varchar(20) NOT NULL DEFAULT 'manual',
+	"page" bigint,
+	"location" varchar(100),
+	"quote" text NOT NULL,
+	"comment" text,
+	"color" varchar(20) NOT NULL DEFAULT 'yellow',
+	"tag" varchar(50),
+	"highlighted_at" timestamptz NOT NULL,
+	"created_at" timestamptz,
+	"created_by" bigint,
+	"updated_at" timestamptz,
+	"updated_by" bigint,
+	"deleted_at" timestamptz,
+	"deleted_by" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_highlights_user_book" FOREIGN KEY ("user_book_id") REFERENCES "user_books"("id"),
+	CONSTRAINT "chk_highlights_kind" CHECK (kind IN ('highlight', 'note', 'bookmark'))
+);
+CREATE INDEX IF NOT EXISTS "idx_highlights_user_book_id" ON "highlights" ("user_book_id");
+CREATE INDEX IF NOT EXISTS "idx_highlights_user_id" ON "highlights" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_highlights_tag" ON "highlights" ("tag");
+CREATE INDEX IF NOT EXISTS "idx_highlights_highlighted_at" ON "highlights" ("highlighted_at");
+CREATE INDEX IF NOT EXISTS "idx_highlights_deleted_at" ON "highlights" ("deleted_at");
+
+-- Full-text search over highlights. 'simple' keeps Indonesian and English words intact.
+ALTER TABLE "highlights" ADD COLUMN IF NOT EXISTS "search_vector" tsvector
+	GENERATED ALWAYS AS (to_tsvector('simple', coalesce(quote, '') || ' ' || coalesce(comment, '') || ' ' || coalesce(tag, ''))) STORED;
+CREATE INDEX IF NOT EXISTS "idx_highlights_search_vector" ON "highlights" USING GIN ("search_vector");
+
+CREATE TABLE IF NOT EXISTS "import_jobs" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"source" varchar(20) NOT NULL,
+	"file_name" varchar(255),
+	"status" varchar(20) NOT NULL DEFAULT 'queued',
+	"total_rows" bigint NOT NULL DEFAULT 0,
+	"processed_rows" bigint NOT NULL DEFAULT 0,
+	"books_created" bigint NOT NULL DEFAULT 0,
+	"created" bigint NOT NULL DEFAULT 0,
+	"updated" bigint NOT NULL DEFAULT 0,
+	"skipped" bigint NOT NULL DEFAULT 0,
+	"failed" bigint NOT NULL DEFAULT 0,
+	"error" text,
+	"error_report" text,
+	"started_at" timestamptz,
+	"finished_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_import_jobs_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+	CONSTRAINT "chk_import_jobs_status" CHECK (status IN ('queued', 'running', 'completed', 'failed'))
+);
+CREATE INDEX IF NOT EXISTS "idx_import_jobs_user_id" ON "import_jobs" ("user_id");
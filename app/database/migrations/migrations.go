@@ -0,0 +1,12 @@
+// Package migrations holds the versioned SQL migrations of the database.
+//
+// Every change to the schema is a pair of files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, where version is a
+// number higher than all existing ones. Migrations run in a transaction, so
+// statements such as CREATE INDEX CONCURRENTLY cannot be used.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,95 @@
+package seeders
+
+import (
+	"ayo-baca-buku/app/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SeedDemo gives the sample user a small library to try the API with. It does
+// nothing when the sample user already has books.
+func SeedDemo(db *gorm.DB) error {
+	var user models.User
+	if err := db.Where("username = ?", SampleUsername).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user " + SampleUsername + " does not exist, seed the admin set first")
+		}
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&models.UserBook{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	daysAgo := func(days int) time.Time { return today.AddDate(0, 0, -days) }
+	actor := int64(user.ID)
+
+	books := []models.UserBook{
+		{Title: "Laskar Pelangi", Author: "Andrea Hirata", Publisher: "Bentang Pustaka", Genre: "Novel", TotalPages: 529,
+			Status: "finished", StartDate: daysAgo(40), EndDate: daysAgo(20), CurrentPage: 529},
+		{Title: "Bumi Manusia", Author: "Pramoedya Ananta Toer", Publisher: "Lentera Dipantara", Genre: "Fiksi Sejarah", TotalPages: 535,
+			Status: "reading", StartDate: daysAgo(6)},
+		{Title: "Filosofi Teras", Author: "Henry Manampiring", Publisher: "Penerbit Buku Kompas", Genre: "Filsafat", TotalPages: 346,
+			Status: "reading", StartDate: today, MotivationRead: "Belajar stoisisme"},
+	}
+	for i := range books {
+		books[i].UserID = user.ID
+		books[i].CreatedBy, books[i].UpdatedBy = actor, actor
+		if err := db.Create(&books[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	// Sessions on consecutive days give Bumi Manusia a streak and a pace.
+	var activities []models.ReadingActivity
+	page := 0
+	for day := 6; day >= 1; day-- {
+		pages := 25 + day*3
+		activities = append(activities, models.ReadingActivity{
+			UserBookID:  books[1].ID,
+			PagesRead:   pages,
+			StartPage:   page + 1,
+			EndPage:     page + pages,
+			Duration:    pages * 2,
+			ReadingDate: daysAgo(day),
+		})
+		page += pages
+	}
+	activities = append(activities, models.ReadingActivity{
+		UserBookID: books[0].ID, PagesRead: 529, StartPage: 1, EndPage: 529, Duration: 900,
+		Notes: "Selesai dalam beberapa akhir pekan", ReadingDate: daysAgo(20),
+	})
+	if err := db.Create(&activities).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&books[1]).Update("current_page", page).Error; err != nil {
+		return err
+	}
+
+	review := models.Review{
+		UserBookID: books[0].ID, UserID: user.ID, Rating: 4.5, Visibility: "public",
+		Body:      "Kisah persahabatan yang hangat dari Belitung.",
+		CreatedBy: actor, UpdatedBy: actor,
+	}
+	if err := db.Create(&review).Error; err != nil {
+		return err
+	}
+
+	shelf := models.Shelf{UserID: user.ID, Name: "Sastra Indonesia", Visibility: "public", CreatedBy: actor, UpdatedBy: actor}
+	if err := db.Create(&shelf).Error; err != nil {
+		return err
+	}
+	for position, book := range books[:2] {
+		if err := db.Create(&models.UserBookShelf{ShelfID: shelf.ID, UserBookID: book.ID, Position: position}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
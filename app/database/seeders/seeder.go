@@ -0,0 +1,74 @@
+package seeders
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Set is a named group of seed data, loaded with "go run ./cmd seed <name>".
+// Every set can be run again without creating duplicates.
+type Set struct {
+	Name        string
+	Description string
+	Production  bool // The set may be seeded when APP_ENV is production
+	Run         func(db *gorm.DB) error
+}
+
+// DefaultSet is seeded when no set is named.
+const DefaultSet = "admin"
+
+// Sets lists the available seed sets in the order they are seeded.
+var Sets = []Set{
+	{
+		Name:        "admin",
+		Description: "admin user " + SampleUsername + " with the password " + SamplePassword,
+		Run:         SeedUser,
+	},
+	{
+		Name:        "demo",
+		Description: "books, reading sessions, a shelf and a review for " + SampleUsername + " (needs admin)",
+		Run:         SeedDemo,
+	},
+}
+
+// Run seeds the named sets, each in its own transaction. Sets that are not
+// allowed in production are refused before anything is seeded.
+func Run(db *gorm.DB, names []string, production bool) error {
+	selected := make([]Set, 0, len(names))
+	for _, name := range names {
+		set, ok := lookup(name)
+		if !ok {
+			return fmt.Errorf("unknown seed set %q, available: %s", name, strings.Join(setNames(), ", "))
+		}
+		if production && !set.Production {
+			return fmt.Errorf("seed set %q is not allowed when APP_ENV is production", name)
+		}
+		selected = append(selected, set)
+	}
+
+	for _, set := range selected {
+		if err := db.Transaction(set.Run); err != nil {
+			return fmt.Errorf("seed %s: %w", set.Name, err)
+		}
+	}
+	return nil
+}
+
+func lookup(name string) (Set, bool) {
+	for _, set := range Sets {
+		if set.Name == name {
+			return set, true
+		}
+	}
+	return Set{}, false
+}
+
+func setNames() []string {
+	names := make([]string, len(Sets))
+	for i, set := range Sets {
+		names[i] = set.Name
+	}
+	return names
+}
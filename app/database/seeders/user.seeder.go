@@ -3,29 +3,35 @@ package seeders
 import (
 	"ayo-baca-buku/app/models"
 	"ayo-baca-buku/app/util/jwt"
-	"log"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-func SeedUser(db *gorm.DB) {
-	password, err := jwt.HashPassword("rahasia")
+// Credentials of the admin created by the "admin" seed set.
+const (
+	SampleUsername = "sampleuser"
+	SamplePassword = "rahasia"
+)
+
+func SeedUser(db *gorm.DB) error {
+	password, err := jwt.HashPassword(SamplePassword)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	//check unique username & email
-	if db.Where("username = ?", "sampleuser").Or("email = ?", "sampleuser@example.com").Find(&models.User{}).RowsAffected == 0 {
-		db.Create(&models.User{
+	if db.Where("username = ?", SampleUsername).Or("email = ?", "sampleuser@example.com").Find(&models.User{}).RowsAffected == 0 {
+		return db.Create(&models.User{
 			Name:      "Sample User",
-			Username:  "sampleuser",
+			Username:  SampleUsername,
 			Email:     "sampleuser@example.com",
 			Token:     "",
 			Role:      "admin",
 			Password:  password,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
-		})
+		}).Error
 	}
+	return nil
 }
@@ -0,0 +1,197 @@
+package exporter
+
+import (
+	"ayo-baca-buku/app/util/isbn"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout          = "2006-01-02"
+	goodreadsDateLayout = "2006/01/02" // Sama dengan format yang dibaca importer
+)
+
+var csvHeader = []string{
+	"book_id", "title", "author", "publisher", "genre", "isbn", "total_pages", "current_page", "progress",
+	"status", "start_date", "end_date", "rating", "shelves", "tags",
+	"activity_id", "reading_date", "start_page", "end_page", "pages_read", "duration_minutes", "notes",
+}
+
+// goodreadsHeader follows the columns of Goodreads' "Export Library" file so
+// the result can be imported by Goodreads and by other services that read it.
+var goodreadsHeader = []string{
+	"Book Id", "Title", "Author", "Author l-f", "Additional Authors", "ISBN", "ISBN13", "My Rating",
+	"Average Rating", "Publisher", "Binding", "Number of Pages", "Year Published", "Original Publication Year",
+	"Date Read", "Date Added", "Bookshelves", "Bookshelves with positions", "Exclusive Shelf", "My Review",
+	"Spoiler", "Private Notes", "Read Count", "Owned Copies",
+}
+
+// WriteCSV writes one row per reading activity, repeating the book columns.
+// Books without activities get a single row with empty activity columns.
+func WriteCSV(w io.Writer, library *Library) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for {
+		book, err := library.Next()
+		if err != nil {
+			return err
+		}
+		if book == nil {
+			break
+		}
+
+		bookColumns := []string{
+			strconv.FormatUint(uint64(book.ID), 10),
+			book.Title,
+			book.Author,
+			book.Publisher,
+			book.Genre,
+			book.ISBN,
+			strconv.Itoa(book.TotalPages),
+			strconv.Itoa(book.CurrentPage),
+			strconv.FormatFloat(book.Progress, 'f', -1, 64),
+			book.Status,
+			book.StartDate.Format(dateLayout),
+			formatDate(book.EndDate, dateLayout),
+			formatRating(book.Rating),
+			strings.Join(book.Shelves, ", "),
+			strings.Join(book.Tags, ", "),
+		}
+
+		if len(book.ReadingActivities) == 0 {
+			if err := writer.Write(append(bookColumns, "", "", "", "", "", "", "")); err != nil {
+				return err
+			}
+		}
+		for _, activity := range book.ReadingActivities {
+			record := append(bookColumns[:len(bookColumns):len(bookColumns)],
+				strconv.FormatUint(uint64(activity.ID), 10),
+				activity.ReadingDate.Format(time.RFC3339),
+				strconv.Itoa(activity.StartPage),
+				strconv.Itoa(activity.EndPage),
+				strconv.Itoa(activity.PagesRead),
+				strconv.Itoa(activity.Duration),
+				activity.Notes,
+			)
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		// Flush per book so large libraries are streamed instead of buffered.
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteGoodreads writes the library in Goodreads' CSV layout. Goodreads has no
+// reading sessions, so the activity history is left out; half-star ratings
+// are rounded to whole stars.
+func WriteGoodreads(w io.Writer, library *Library) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(goodreadsHeader); err != nil {
+		return err
+	}
+
+	for {
+		book, err := library.Next()
+		if err != nil {
+			return err
+		}
+		if book == nil {
+			break
+		}
+
+		exclusive, readCount := "currently-reading", "0"
+		if book.Status == "finished" {
+			exclusive, readCount = "read", "1"
+		}
+		isbn10, _ := isbn.ToISBN10(book.ISBN)
+		shelves := append([]string{exclusive}, book.Shelves...)
+
+		myRating := "0"
+		if book.Rating != nil {
+			myRating = strconv.Itoa(int(math.Round(*book.Rating)))
+		}
+		spoiler := ""
+		if book.ReviewSpoiler {
+			spoiler = "true"
+		}
+
+		var dateRead string
+		if book.Status == "finished" {
+			dateRead = formatDate(book.EndDate, goodreadsDateLayout)
+		}
+
+		if err := writer.Write([]string{
+			strconv.FormatUint(uint64(book.ID), 10),
+			book.Title,
+			book.Author,
+			authorLastFirst(book.Author),
+			"",
+			fmt.Sprintf(`="%s"`, isbn10),
+			fmt.Sprintf(`="%s"`, book.ISBN),
+			myRating,
+			"",
+			book.Publisher,
+			"",
+			strconv.Itoa(book.TotalPages),
+			"",
+			"",
+			dateRead,
+			book.CreatedAt.Format(goodreadsDateLayout),
+			strings.Join(shelves, ", "),
+			"",
+			exclusive,
+			strings.ReplaceAll(book.Review, "\n", "<br/>"),
+			spoiler,
+			book.MotivationRead,
+			readCount,
+			"0",
+		}); err != nil {
+			return err
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatDate(value *time.Time, layout string) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format(layout)
+}
+
+func formatRating(rating *float64) string {
+	if rating == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*rating, 'f', 1, 64)
+}
+
+// authorLastFirst turns "Andrea Hirata" into "Hirata, Andrea".
+func authorLastFirst(author string) string {
+	names := strings.Fields(author)
+	if len(names) < 2 {
+		return author
+	}
+	return names[len(names)-1] + ", " + strings.Join(names[:len(names)-1], " ")
+}
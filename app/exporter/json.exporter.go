@@ -0,0 +1,62 @@
+package exporter
+
+import (
+	"ayo-baca-buku/app/models"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type jsonExportUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Timezone string `json:"timezone"`
+}
+
+// WriteJSON writes {"exported_at", "user", "user_books": [...]}. The array is
+// written one book at a time instead of being marshalled as a whole.
+func WriteJSON(w io.Writer, user *models.User, library *Library) error {
+	header, err := json.Marshal(struct {
+		ExportedAt time.Time      `json:"exported_at"`
+		User       jsonExportUser `json:"user"`
+	}{
+		ExportedAt: time.Now(),
+		User:       jsonExportUser{ID: user.ID, Username: user.Username, Name: user.Name, Timezone: user.Timezone},
+	})
+	if err != nil {
+		return err
+	}
+	// Open the object again to append the user_books array.
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"user_books":[`); err != nil {
+		return err
+	}
+
+	for first := true; ; first = false {
+		book, err := library.Next()
+		if err != nil {
+			return err
+		}
+		if book == nil {
+			break
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(book)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
@@ -0,0 +1,220 @@
+package exporter
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Book is one exported UserBook with its review, shelves, tags and reading
+// history.
+type Book struct {
+	ID                uint        `json:"id"`
+	Title             string      `json:"title"`
+	Author            string      `json:"author"`
+	Publisher         string      `json:"publisher"`
+	Genre             string      `json:"genre"`
+	ISBN              string      `json:"isbn"`
+	Cover             string      `json:"cover"`
+	TotalPages        int         `json:"total_pages"`
+	CurrentPage       int         `json:"current_page"`
+	Progress          float64     `json:"progress" gorm:"-"` // Persen, 0-100
+	Status            string      `json:"status"`
+	MotivationRead    string      `json:"motivation_read"`
+	StartDate         time.Time   `json:"start_date"`
+	EndDate           *time.Time  `json:"end_date"`
+	Rating            *float64    `json:"rating"`
+	Review            string      `json:"review"`
+	ReviewSpoiler     bool        `json:"review_spoiler"`
+	ShelfNames        string      `json:"-"` // JSON array dari query
+	TagNames          string      `json:"-"` // JSON array dari query
+	Shelves           []string    `json:"shelves" gorm:"-"`
+	Tags              []string    `json:"tags" gorm:"-"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+	ReadingActivities []Activity  `json:"reading_activities" gorm:"-"`
+	Highlights        []Highlight `json:"highlights,omitempty" gorm:"-"` // Hanya jika IncludeHighlights dipanggil
+}
+
+// Activity is one exported ReadingActivity.
+type Activity struct {
+	ID          uint      `json:"id"`
+	UserBookID  uint      `json:"-"`
+	ReadingDate time.Time `json:"reading_date"`
+	StartPage   int       `json:"start_page"`
+	EndPage     int       `json:"end_page"`
+	PagesRead   int       `json:"pages_read"`
+	Duration    int       `json:"duration"`
+	Notes       string    `json:"notes"`
+}
+
+// Highlight is one exported Highlight.
+type Highlight struct {
+	ID            uint      `json:"id"`
+	UserBookID    uint      `json:"-"`
+	Kind          string    `json:"kind"`
+	Page          *int      `json:"page"`
+	Location      string    `json:"location"`
+	Quote         string    `json:"quote"`
+	Comment       string    `json:"comment"`
+	Color         string    `json:"color"`
+	Tag           string    `json:"tag"`
+	HighlightedAt time.Time `json:"highlighted_at"`
+}
+
+// childCursor reads rows that belong to a book from a query ordered by book
+// ID first. Because the books are read in the same order, the rows of a book
+// always come before those of the next book.
+type childCursor[T any] struct {
+	db      *gorm.DB
+	rows    *sql.Rows
+	bookID  func(*T) uint
+	pending *T
+}
+
+func (c *childCursor[T]) take(bookID uint) ([]T, error) {
+	children := []T{}
+	for {
+		if c.pending == nil {
+			if !c.rows.Next() {
+				return children, c.rows.Err()
+			}
+			var child T
+			if err := c.db.ScanRows(c.rows, &child); err != nil {
+				return nil, err
+			}
+			c.pending = &child
+		}
+		if c.bookID(c.pending) > bookID {
+			return children, nil
+		}
+		if c.bookID(c.pending) == bookID {
+			children = append(children, *c.pending)
+		}
+		c.pending = nil
+	}
+}
+
+// Library walks a user's books in ID order. Books, reading activities and
+// highlights are read from separate cursors that are merged on the book ID, so
+// only one book and its children are held in memory at a time.
+type Library struct {
+	db         *gorm.DB
+	userID     uint
+	books      *sql.Rows
+	activities *childCursor[Activity]
+	highlights *childCursor[Highlight]
+}
+
+// OpenLibrary starts the queries for the user's books. Close must be called
+// when done.
+func OpenLibrary(db *gorm.DB, userID uint) (*Library, error) {
+	books, err := db.Table("user_books AS ub").
+		Select(`ub.id, ub.title, ub.author, ub.publisher, ub.genre, ub.isbn, ub.cover, ub.total_pages, ub.current_page,
+			ub.status, ub.motivation_read, ub.start_date, ub.end_date, ub.created_at, ub.updated_at,
+			r.rating, coalesce(r.body, '') AS review, coalesce(r.spoiler, false) AS review_spoiler,
+			coalesce((SELECT json_agg(s.name ORDER BY s.position)::text FROM user_book_shelves ubs
+				JOIN shelves s ON s.id = ubs.shelf_id AND s.deleted_at IS NULL
+				WHERE ubs.user_book_id = ub.id), '[]') AS shelf_names,
+			coalesce((SELECT json_agg(t.name ORDER BY t.name)::text FROM user_book_tags ubt
+				JOIN tags t ON t.id = ubt.tag_id
+				WHERE ubt.user_book_id = ub.id), '[]') AS tag_names`).
+		Joins("LEFT JOIN reviews r ON r.user_book_id = ub.id AND r.deleted_at IS NULL").
+		Where("ub.user_id = ? AND ub.deleted_at IS NULL", userID).
+		Order("ub.id").
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("query books: %w", err)
+	}
+
+	activities, err := db.Table("reading_activities AS ra").
+		Select("ra.id, ra.user_book_id, ra.reading_date, ra.start_page, ra.end_page, ra.pages_read, ra.duration, ra.notes").
+		Joins("JOIN user_books ub ON ub.id = ra.user_book_id AND ub.deleted_at IS NULL").
+		Where("ub.user_id = ? AND ra.deleted_at IS NULL", userID).
+		Order("ra.user_book_id, ra.reading_date, ra.id").
+		Rows()
+	if err != nil {
+		books.Close()
+		return nil, fmt.Errorf("query reading activities: %w", err)
+	}
+
+	return &Library{
+		db:         db,
+		userID:     userID,
+		books:      books,
+		activities: &childCursor[Activity]{db: db, rows: activities, bookID: func(a *Activity) uint { return a.UserBookID }},
+	}, nil
+}
+
+// IncludeHighlights adds the highlights of each book, ordered by page, to the
+// books returned by Next. It must be called before the first call to Next.
+func (l *Library) IncludeHighlights() error {
+	highlights, err := l.db.Table("highlights AS h").
+		Select("h.id, h.user_book_id, h.kind, h.page, h.location, h.quote, h.comment, h.color, h.tag, h.highlighted_at").
+		Joins("JOIN user_books ub ON ub.id = h.user_book_id AND ub.deleted_at IS NULL").
+		Where("ub.user_id = ? AND h.deleted_at IS NULL", l.userID).
+		Order("h.user_book_id, h.page NULLS LAST, h.highlighted_at, h.id").
+		Rows()
+	if err != nil {
+		return fmt.Errorf("query highlights: %w", err)
+	}
+	l.highlights = &childCursor[Highlight]{db: l.db, rows: highlights, bookID: func(h *Highlight) uint { return h.UserBookID }}
+	return nil
+}
+
+// Next returns the next book, or nil when there are no more books.
+func (l *Library) Next() (*Book, error) {
+	if !l.books.Next() {
+		return nil, l.books.Err()
+	}
+
+	var book Book
+	if err := l.db.ScanRows(l.books, &book); err != nil {
+		return nil, fmt.Errorf("scan book: %w", err)
+	}
+	if book.EndDate != nil && book.EndDate.IsZero() {
+		book.EndDate = nil
+	}
+	if book.TotalPages > 0 {
+		book.Progress = math.Min(100, math.Round(float64(book.CurrentPage)/float64(book.TotalPages)*1000)/10)
+	}
+	if err := json.Unmarshal([]byte(book.ShelfNames), &book.Shelves); err != nil {
+		return nil, fmt.Errorf("decode shelves of book %d: %w", book.ID, err)
+	}
+	if err := json.Unmarshal([]byte(book.TagNames), &book.Tags); err != nil {
+		return nil, fmt.Errorf("decode tags of book %d: %w", book.ID, err)
+	}
+
+	activities, err := l.activities.take(book.ID)
+	if err != nil {
+		return nil, fmt.Errorf("scan reading activity: %w", err)
+	}
+	book.ReadingActivities = activities
+
+	if l.highlights != nil {
+		highlights, err := l.highlights.take(book.ID)
+		if err != nil {
+			return nil, fmt.Errorf("scan highlight: %w", err)
+		}
+		book.Highlights = highlights
+	}
+	return &book, nil
+}
+
+// Close releases the cursors.
+func (l *Library) Close() error {
+	err := l.books.Close()
+	if closeErr := l.activities.rows.Close(); err == nil {
+		err = closeErr
+	}
+	if l.highlights != nil {
+		if closeErr := l.highlights.rows.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,272 @@
+package exporter
+
+import (
+	"archive/zip"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+const (
+	// maxMarkdownNoteSize limits the file rendered for one book.
+	maxMarkdownNoteSize = 1 << 20
+	// markdownRenderTimeout limits the time rendering one book may take.
+	markdownRenderTimeout = 5 * time.Second
+)
+
+// markdownLists are the fields of Book a template may range over.
+var markdownLists = map[string]bool{
+	"ReadingActivities": true,
+	"Highlights":        true,
+	"Shelves":           true,
+	"Tags":              true,
+}
+
+// DefaultMarkdownTemplate renders a book as an Obsidian-friendly note with
+// YAML front matter. It is used when no custom template is given.
+//
+//go:embed templates/book.md.tmpl
+var DefaultMarkdownTemplate string
+
+var markdownFuncs = template.FuncMap{
+	// date formats a time.Time or *time.Time as YYYY-MM-DD.
+	"date": func(value interface{}) string {
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format(dateLayout)
+		case *time.Time:
+			return formatDate(v, dateLayout)
+		}
+		return ""
+	},
+	// yaml quotes a string for use as a YAML scalar. A JSON string is valid YAML.
+	"yaml": func(value string) string {
+		data, _ := json.Marshal(value)
+		return string(data)
+	},
+	"rating": formatRating,
+	"deref": func(value *int) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.Itoa(*value)
+	},
+	// quote renders text as a Markdown blockquote.
+	"quote": func(text string) string {
+		return "> " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n> ")
+	},
+	"withNotes": func(activities []Activity) []Activity {
+		var withNotes []Activity
+		for _, activity := range activities {
+			if strings.TrimSpace(activity.Notes) != "" {
+				withNotes = append(withNotes, activity)
+			}
+		}
+		return withNotes
+	},
+	"join": strings.Join,
+}
+
+// sampleBook has every optional field set, so executing a template on it
+// catches mistakes before the export starts streaming.
+func sampleBook() *Book {
+	now := time.Now()
+	page, rating := 12, 4.5
+	return &Book{
+		ID: 1, Title: "Laskar Pelangi", Author: "Andrea Hirata", ISBN: "9789793062792",
+		TotalPages: 529, CurrentPage: 529, Progress: 100, Status: "finished",
+		StartDate: now, EndDate: &now, Rating: &rating, Review: "Review", Shelves: []string{"shelf"}, Tags: []string{"tag"},
+		CreatedAt: now, UpdatedAt: now,
+		ReadingActivities: []Activity{{ID: 1, UserBookID: 1, ReadingDate: now, StartPage: 1, EndPage: 12, PagesRead: 12, Duration: 30, Notes: "Notes"}},
+		Highlights:        []Highlight{{ID: 1, UserBookID: 1, Kind: "highlight", Page: &page, Location: "100-110", Quote: "Quote", Comment: "Comment", Color: "yellow", Tag: "tag", HighlightedAt: now}},
+	}
+}
+
+// ParseMarkdownTemplate parses a text/template for one book. An empty text
+// selects DefaultMarkdownTemplate. The template receives a *Book and may use
+// the functions date, yaml, rating, deref, quote, withNotes and join.
+//
+// Templates come from users and a running template cannot be stopped, so
+// loops are restricted to make the work depend on the size of the library:
+// range only loops over the lists of a book (ReadingActivities, Highlights,
+// Shelves and Tags, also through withNotes), ranges cannot be nested and
+// templates cannot call other templates.
+func ParseMarkdownTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultMarkdownTemplate
+	}
+	tmpl, err := template.New("book").Funcs(markdownFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, errors.New("template must not define other templates")
+	}
+	if err := checkMarkdownNode(tmpl.Tree.Root, false, false); err != nil {
+		return nil, err
+	}
+	if err := renderMarkdown(io.Discard, tmpl, sampleBook()); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// checkMarkdownNode rejects the loops ParseMarkdownTemplate does not allow.
+// dotIsList tells whether dot is one of the lists of the book, e.g. inside
+// {{with .Highlights}}.
+func checkMarkdownNode(node parse.Node, inRange, dotIsList bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkMarkdownNode(child, inRange, dotIsList); err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		return errors.New("template must not call other templates")
+	case *parse.IfNode:
+		return checkMarkdownBranch(&n.BranchNode, inRange, dotIsList, dotIsList)
+	case *parse.WithNode:
+		return checkMarkdownBranch(&n.BranchNode, inRange, dotIsList, isMarkdownList(n.Pipe, dotIsList))
+	case *parse.RangeNode:
+		if inRange {
+			return errors.New("range must not be nested in another range")
+		}
+		if !isMarkdownList(n.Pipe, dotIsList) {
+			return errors.New("range can only loop over .ReadingActivities, .Highlights, .Shelves or .Tags")
+		}
+		if err := checkMarkdownNode(n.List, true, false); err != nil {
+			return err
+		}
+		return checkMarkdownNode(n.ElseList, inRange, dotIsList)
+	}
+	return nil
+}
+
+func checkMarkdownBranch(branch *parse.BranchNode, inRange, dotIsList, bodyDotIsList bool) error {
+	if err := checkMarkdownNode(branch.List, inRange, bodyDotIsList); err != nil {
+		return err
+	}
+	return checkMarkdownNode(branch.ElseList, inRange, dotIsList)
+}
+
+// isMarkdownList reports whether pipe is one of the lists of the book, e.g.
+// .Highlights, $.Tags or withNotes .ReadingActivities.
+func isMarkdownList(pipe *parse.PipeNode, dotIsList bool) bool {
+	if pipe == nil || len(pipe.Cmds) != 1 {
+		return false
+	}
+	return isMarkdownListArgs(pipe.Cmds[0].Args, dotIsList)
+}
+
+func isMarkdownListArgs(args []parse.Node, dotIsList bool) bool {
+	switch len(args) {
+	case 1:
+		switch arg := args[0].(type) {
+		case *parse.FieldNode:
+			return len(arg.Ident) == 1 && markdownLists[arg.Ident[0]]
+		case *parse.VariableNode:
+			return len(arg.Ident) == 2 && arg.Ident[0] == "$" && markdownLists[arg.Ident[1]]
+		case *parse.DotNode:
+			return dotIsList
+		}
+	case 2:
+		fn, ok := args[0].(*parse.IdentifierNode)
+		return ok && fn.Ident == "withNotes" && isMarkdownListArgs(args[1:], dotIsList)
+	}
+	return false
+}
+
+// markdownWriter fails once the note of a book gets too large or rendering it
+// takes too long, so a template cannot hold the export forever.
+type markdownWriter struct {
+	w        io.Writer
+	written  int
+	deadline time.Time
+}
+
+func (m *markdownWriter) Write(p []byte) (int, error) {
+	if m.written+len(p) > maxMarkdownNoteSize {
+		return 0, fmt.Errorf("note is larger than %d KB", maxMarkdownNoteSize>>10)
+	}
+	if time.Now().After(m.deadline) {
+		return 0, fmt.Errorf("note took longer than %s to render", markdownRenderTimeout)
+	}
+	m.written += len(p)
+	return m.w.Write(p)
+}
+
+// renderMarkdown executes tmpl for book within the size and time limits.
+func renderMarkdown(w io.Writer, tmpl *template.Template, book *Book) error {
+	return tmpl.Execute(&markdownWriter{w: w, deadline: time.Now().Add(markdownRenderTimeout)}, book)
+}
+
+// WriteMarkdownZip writes a ZIP archive with one Markdown file per book. The
+// library should include highlights.
+func WriteMarkdownZip(w io.Writer, library *Library, tmpl *template.Template) error {
+	archive := zip.NewWriter(w)
+	usedNames := map[string]int{}
+
+	for {
+		book, err := library.Next()
+		if err != nil {
+			return err
+		}
+		if book == nil {
+			break
+		}
+
+		name := markdownFileName(book)
+		usedNames[strings.ToLower(name)]++
+		if count := usedNames[strings.ToLower(name)]; count > 1 {
+			name = fmt.Sprintf("%s (%d)", name, count)
+		}
+
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name + ".md",
+			Method:   zip.Deflate,
+			Modified: book.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := renderMarkdown(file, tmpl, book); err != nil {
+			return fmt.Errorf("render book %d: %w", book.ID, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+// markdownFileName builds "Title - Author" without characters that are not
+// allowed in file names on common systems or in Obsidian links.
+func markdownFileName(book *Book) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', '#', '^', '[', ']':
+			return '-'
+		}
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, book.Title+" - "+book.Author)
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > 120 {
+		name = strings.TrimSpace(string(runes[:120]))
+	}
+	if name = strings.Trim(name, ". "); name == "" {
+		name = fmt.Sprintf("book-%d", book.ID)
+	}
+	return name
+}
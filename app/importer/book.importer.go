@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"ayo-baca-buku/app/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UnknownAuthor is stored when an imported book has no author, since
+// UserBook.Author is required.
+const UnknownAuthor = "Unknown"
+
+// bookKey identifies a book across imports: title and author compared
+// case-insensitively, ignoring surrounding whitespace.
+func bookKey(title, author string) string {
+	return strings.ToLower(strings.TrimSpace(title)) + "\x00" + strings.ToLower(strings.TrimSpace(author))
+}
+
+// findUserBook looks up the user's copy of a book by title and author.
+// It returns nil without error when the user does not have the book yet.
+func findUserBook(tx *gorm.DB, userID uint, title, author string) (*models.UserBook, error) {
+	var userBook models.UserBook
+	err := tx.Where("user_id = ? AND LOWER(TRIM(title)) = LOWER(TRIM(?)) AND LOWER(TRIM(author)) = LOWER(TRIM(?))", userID, title, author).
+		Order("id").
+		First(&userBook).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &userBook, nil
+}
+
+// findOrCreateUserBook returns the user's copy of a book, creating it from
+// template when it does not exist yet. created reports whether it was created.
+func findOrCreateUserBook(tx *gorm.DB, template models.UserBook) (userBook *models.UserBook, created bool, err error) {
+	template.Title = strings.TrimSpace(template.Title)
+	template.Author = strings.TrimSpace(template.Author)
+	if template.Author == "" {
+		template.Author = UnknownAuthor
+	}
+
+	userBook, err = findUserBook(tx, template.UserID, template.Title, template.Author)
+	if err != nil || userBook != nil {
+		return userBook, false, err
+	}
+
+	if template.Status == "" {
+		template.Status = "reading"
+	}
+	if template.StartDate.IsZero() {
+		template.StartDate = time.Now()
+	}
+	template.CreatedBy = int64(template.UserID) // Placeholder for actor ID
+	template.UpdatedBy = int64(template.UserID) // Placeholder for actor ID
+	if err := tx.Create(&template).Error; err != nil {
+		return nil, false, err
+	}
+	return &template, true, nil
+}
@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/calibre"
+	"ayo-baca-buku/app/util/isbn"
+	"ayo-baca-buku/app/util/storage"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SourceCalibre marks books imported from a Calibre library.
+const SourceCalibre = "calibre"
+
+// maxCoverSize skips covers that are unreasonably large for a thumbnail.
+const maxCoverSize = 10 << 20
+
+var errCoverTooLarge = fmt.Errorf("cover is larger than %d MB", maxCoverSize>>20)
+
+// Calibre stores the books of a Calibre library for userID. Books are matched
+// by ISBN, then by title and author. Calibre tags become tags and series
+// become shelves ordered by series index. Covers are copied to files when
+// covers is not nil and the book has no cover yet. A book that cannot be
+// saved is reported as failed; the other books are still imported.
+func Calibre(db *gorm.DB, files *storage.Local, userID uint, books []calibre.Book, covers calibre.Covers) *models.ImportReport {
+	report := &models.ImportReport{Source: SourceCalibre, Items: []models.ImportReportItem{}}
+
+	// Import series in reading order so their shelves are ordered too.
+	sorted := append([]calibre.Book(nil), books...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Series != b.Series {
+			return a.Series < b.Series
+		}
+		return a.Series != "" && a.SeriesIndex < b.SeriesIndex
+	})
+
+	for _, book := range sorted {
+		var (
+			item        models.ImportReportItem
+			bookCreated bool
+		)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			item, bookCreated, err = importCalibreBook(tx, files, userID, book, covers)
+			return err
+		})
+		if err != nil {
+			item = models.ImportReportItem{
+				Row:    book.ID,
+				Title:  book.Title,
+				Author: strings.Join(book.Authors, " & "),
+				Status: models.ImportItemFailed,
+				Reason: fmt.Sprintf("could not be saved: %v", err),
+			}
+		}
+		if bookCreated {
+			report.BooksCreated++
+		}
+		report.Add(item)
+	}
+	return report
+}
+
+// importCalibreBook stores one Calibre book and returns what happened to it.
+func importCalibreBook(tx *gorm.DB, files *storage.Local, userID uint, book calibre.Book, covers calibre.Covers) (item models.ImportReportItem, bookCreated bool, err error) {
+	author := truncate(strings.Join(book.Authors, " & "), 255)
+	item = models.ImportReportItem{Row: book.ID, Title: book.Title, Author: author}
+	if book.Title == "" {
+		item.Status, item.Reason = models.ImportItemFailed, "title is missing"
+		return item, false, nil
+	}
+	bookISBN, _ := isbn.Normalize(book.ISBN)
+
+	var userBook *models.UserBook
+	if bookISBN != "" {
+		var found models.UserBook
+		err := tx.Where("user_id = ? AND isbn = ?", userID, bookISBN).Order("id").First(&found).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return item, false, err
+		}
+		if err == nil {
+			userBook = &found
+		}
+	}
+
+	changed := false
+	if userBook == nil {
+		userBook, bookCreated, err = findOrCreateUserBook(tx, models.UserBook{
+			UserID:     userID,
+			Title:      truncate(book.Title, 255),
+			Author:     author,
+			Publisher:  truncate(book.Publisher, 255),
+			ISBN:       bookISBN,
+			TotalPages: book.Pages,
+			StartDate:  book.AddedAt,
+		})
+		if err != nil {
+			return item, false, err
+		}
+	}
+
+	if !bookCreated {
+		updates := map[string]interface{}{}
+		if userBook.ISBN == "" && bookISBN != "" {
+			updates["isbn"] = bookISBN
+		}
+		if userBook.TotalPages == 0 && book.Pages > 0 {
+			updates["total_pages"] = book.Pages
+		}
+		if userBook.Publisher == "" && book.Publisher != "" {
+			updates["publisher"] = truncate(book.Publisher, 255)
+		}
+		if len(updates) > 0 {
+			updates["updated_by"] = int64(userID)
+			if err := tx.Model(userBook).Updates(updates).Error; err != nil {
+				return item, false, err
+			}
+			changed = true
+		}
+	}
+
+	var tags []string
+	for _, tag := range book.Tags {
+		if tag = normalizeName(tag); tag != "" && len([]rune(tag)) <= 50 {
+			tags = append(tags, tag)
+		}
+	}
+	added, err := addTags(tx, userID, userBook, tags)
+	if err != nil {
+		return item, bookCreated, err
+	}
+	changed = changed || added
+
+	if book.Series != "" {
+		if added, err = addToShelves(tx, userID, userBook.ID, []string{truncate(book.Series, 100)}); err != nil {
+			return item, bookCreated, err
+		}
+		changed = changed || added
+	}
+
+	if book.HasCover && covers != nil && userBook.Cover == "" {
+		url, err := saveCalibreCover(files, covers, userID, userBook.ID, book.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist), errors.Is(err, errCoverTooLarge):
+			// The book is imported without the cover.
+		case err != nil:
+			return item, bookCreated, err
+		default:
+			if err := tx.Model(userBook).Update("cover", url).Error; err != nil {
+				return item, bookCreated, err
+			}
+			changed = true
+		}
+	}
+
+	switch {
+	case bookCreated:
+		item.Status = models.ImportItemCreated
+	case changed:
+		item.Status = models.ImportItemUpdated
+	default:
+		item.Status, item.Reason = models.ImportItemSkipped, reasonAlreadyInLibrary
+	}
+	return item, bookCreated, nil
+}
+
+// saveCalibreCover copies the cover of a Calibre book to files and returns its URL.
+func saveCalibreCover(files *storage.Local, covers calibre.Covers, userID, userBookID uint, bookPath string) (string, error) {
+	cover, err := covers.Open(bookPath)
+	if err != nil {
+		return "", err
+	}
+	defer cover.Close()
+
+	data, err := io.ReadAll(io.LimitReader(cover, maxCoverSize+1))
+	if err != nil {
+		return "", fmt.Errorf("read cover: %w", err)
+	}
+	if len(data) > maxCoverSize {
+		return "", errCoverTooLarge
+	}
+	return files.Save(fmt.Sprintf("covers/%d/%d.jpg", userID, userBookID), bytes.NewReader(data))
+}
+
+// truncate shortens value to at most limit characters.
+func truncate(value string, limit int) string {
+	if runes := []rune(value); len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return value
+}
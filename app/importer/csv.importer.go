@@ -0,0 +1,407 @@
+package importer
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/isbn"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Library export formats accepted by CSV import jobs.
+const (
+	SourceGoodreads  = "goodreads"
+	SourceStoryGraph = "storygraph"
+)
+
+const exportDateLayout = "2006/01/02"
+
+// reasonAlreadyInLibrary marks duplicate rows, which are not errors.
+const reasonAlreadyInLibrary = "already in library"
+
+// libraryRow is one book of a Goodreads or StoryGraph export mapped onto the
+// app's fields. skipReason is set for rows the app cannot represent.
+type libraryRow struct {
+	line        int
+	title       string
+	author      string
+	publisher   string
+	isbn        string
+	totalPages  int
+	status      string
+	skipReason  string
+	shelves     []string
+	tags        []string
+	rating      float64
+	review      string
+	spoiler     bool
+	dateAdded   time.Time
+	dateStarted time.Time
+	dateRead    time.Time
+}
+
+// csvColumns gives access to the columns of a CSV row by header name.
+type csvColumns map[string]int
+
+func (c csvColumns) get(record []string, name string) string {
+	i, ok := c[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+var requiredColumns = map[string][]string{
+	SourceGoodreads:  {"Title", "Author", "Exclusive Shelf"},
+	SourceStoryGraph: {"Title", "Authors", "Read Status"},
+}
+
+// parseLibraryCSV reads a Goodreads or StoryGraph export. It fails only when
+// the file is not such an export; problems with single rows are left to the
+// import so they end up in the error report.
+func parseLibraryCSV(source string, r io.Reader) ([]libraryRow, error) {
+	required, ok := requiredColumns[source]
+	if !ok {
+		return nil, fmt.Errorf("unknown import source %q", source)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	columns := make(csvColumns, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %q is missing; is this a %s export?", name, source)
+		}
+	}
+
+	var rows []libraryRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		var row libraryRow
+		if source == SourceGoodreads {
+			row = goodreadsRow(columns, record)
+		} else {
+			row = storyGraphRow(columns, record)
+		}
+		row.line = line
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func goodreadsRow(columns csvColumns, record []string) libraryRow {
+	row := libraryRow{
+		title:     columns.get(record, "Title"),
+		author:    columns.get(record, "Author"),
+		publisher: columns.get(record, "Publisher"),
+		review:    goodreadsReview(columns.get(record, "My Review")),
+		spoiler:   strings.EqualFold(columns.get(record, "Spoiler"), "true"),
+	}
+	for _, column := range []string{"ISBN13", "ISBN"} {
+		if value, ok := isbn.Normalize(columns.get(record, column)); ok {
+			row.isbn = value
+			break
+		}
+	}
+	row.totalPages, _ = strconv.Atoi(columns.get(record, "Number of Pages"))
+	row.rating, _ = strconv.ParseFloat(columns.get(record, "My Rating"), 64)
+	row.dateAdded, _ = time.Parse(exportDateLayout, columns.get(record, "Date Added"))
+	row.dateRead, _ = time.Parse(exportDateLayout, columns.get(record, "Date Read"))
+
+	exclusive := columns.get(record, "Exclusive Shelf")
+	row.status, row.skipReason = mapReadStatus(exclusive)
+
+	// Bookshelves repeats the exclusive shelf; the others become shelves.
+	for _, shelf := range strings.Split(columns.get(record, "Bookshelves"), ",") {
+		shelf = strings.TrimSpace(shelf)
+		if shelf != "" && shelf != exclusive && shelf != "read" && shelf != "currently-reading" && shelf != "to-read" {
+			row.shelves = append(row.shelves, shelf)
+		}
+	}
+	return row
+}
+
+func storyGraphRow(columns csvColumns, record []string) libraryRow {
+	row := libraryRow{
+		title:  columns.get(record, "Title"),
+		author: columns.get(record, "Authors"),
+		review: columns.get(record, "Review"),
+	}
+	row.isbn, _ = isbn.Normalize(columns.get(record, "ISBN/UID"))
+	row.rating, _ = strconv.ParseFloat(columns.get(record, "Star Rating"), 64)
+	row.dateAdded, _ = time.Parse(exportDateLayout, columns.get(record, "Date Added"))
+	row.dateRead, _ = time.Parse(exportDateLayout, columns.get(record, "Last Date Read"))
+
+	// Dates Read lists every read as "start-end", separated by commas; the
+	// last one is the read being imported.
+	if reads := strings.Split(columns.get(record, "Dates Read"), ","); len(reads) > 0 {
+		bounds := strings.SplitN(strings.TrimSpace(reads[len(reads)-1]), "-", 2)
+		row.dateStarted, _ = time.Parse(exportDateLayout, strings.TrimSpace(bounds[0]))
+		if len(bounds) == 2 && row.dateRead.IsZero() {
+			row.dateRead, _ = time.Parse(exportDateLayout, strings.TrimSpace(bounds[1]))
+		}
+	}
+
+	row.status, row.skipReason = mapReadStatus(columns.get(record, "Read Status"))
+
+	for _, tag := range strings.Split(columns.get(record, "Tags"), ",") {
+		if tag = normalizeName(tag); tag != "" {
+			row.tags = append(row.tags, tag)
+		}
+	}
+	return row
+}
+
+// mapReadStatus maps an export's reading status onto UserBook.Status.
+func mapReadStatus(value string) (status, skipReason string) {
+	switch strings.ToLower(value) {
+	case "read":
+		return "finished", ""
+	case "currently-reading":
+		return "reading", ""
+	case "to-read":
+		return "", "want-to-read books are not tracked yet"
+	case "did-not-finish":
+		return "", "did-not-finish books are not imported"
+	case "":
+		return "", "reading status is missing"
+	}
+	return "", fmt.Sprintf("exclusive shelf %q has no matching status", value)
+}
+
+// goodreadsReview turns the HTML line breaks of Goodreads reviews into Markdown.
+func goodreadsReview(value string) string {
+	replacer := strings.NewReplacer("<br/>", "\n", "<br />", "\n", "<br>", "\n")
+	return strings.TrimSpace(replacer.Replace(value))
+}
+
+// normalizeName trims, collapses whitespace and lower-cases tag names the same
+// way the tag endpoints do.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// halfStars rounds export ratings (StoryGraph uses quarter stars) to the
+// half-star steps of reviews.
+func halfStars(rating float64) float64 {
+	return math.Min(math.Max(math.Round(rating*2)/2, 0.5), 5)
+}
+
+// importLibraryRow stores one row and returns what happened to it.
+func importLibraryRow(tx *gorm.DB, userID uint, row libraryRow) (item models.ImportReportItem, bookCreated bool, err error) {
+	item = models.ImportReportItem{Row: row.line, Title: row.title, Author: row.author}
+
+	switch {
+	case row.title == "":
+		item.Status, item.Reason = models.ImportItemFailed, "title is missing"
+		return item, false, nil
+	case row.skipReason != "":
+		item.Status, item.Reason = models.ImportItemSkipped, row.skipReason
+		return item, false, nil
+	}
+
+	// Goodreads has no start date; books are often added after they were read.
+	startDate := row.dateStarted
+	if startDate.IsZero() {
+		for _, candidate := range []time.Time{row.dateAdded, row.dateRead} {
+			if startDate.IsZero() || (!candidate.IsZero() && candidate.Before(startDate)) {
+				startDate = candidate
+			}
+		}
+	}
+
+	var userBook *models.UserBook
+	if row.isbn != "" {
+		var found models.UserBook
+		err := tx.Where("user_id = ? AND isbn = ?", userID, row.isbn).Order("id").First(&found).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return item, false, err
+		}
+		if err == nil {
+			userBook = &found
+		}
+	}
+
+	changed := false
+	if userBook == nil {
+		template := models.UserBook{
+			UserID:     userID,
+			Title:      row.title,
+			Author:     row.author,
+			Publisher:  row.publisher,
+			ISBN:       row.isbn,
+			TotalPages: row.totalPages,
+			Status:     row.status,
+			StartDate:  startDate,
+		}
+		if row.status == "finished" {
+			template.CurrentPage = row.totalPages
+			template.EndDate = row.dateRead
+		}
+		userBook, bookCreated, err = findOrCreateUserBook(tx, template)
+		if err != nil {
+			return item, false, err
+		}
+	}
+
+	if !bookCreated {
+		updates := map[string]interface{}{}
+		if userBook.ISBN == "" && row.isbn != "" {
+			updates["isbn"] = row.isbn
+		}
+		if userBook.TotalPages == 0 && row.totalPages > 0 {
+			updates["total_pages"] = row.totalPages
+			userBook.TotalPages = row.totalPages
+		}
+		if userBook.Status != "finished" && row.status == "finished" {
+			updates["status"] = "finished"
+			updates["current_page"] = userBook.TotalPages
+			if !row.dateRead.IsZero() {
+				updates["end_date"] = row.dateRead
+			}
+			userBook.Status = "finished"
+		}
+		if len(updates) > 0 {
+			updates["updated_by"] = int64(userID)
+			if err := tx.Model(userBook).Updates(updates).Error; err != nil {
+				return item, false, err
+			}
+			changed = true
+		}
+	}
+
+	added, err := addToShelves(tx, userID, userBook.ID, row.shelves)
+	if err != nil {
+		return item, bookCreated, err
+	}
+	changed = changed || added
+
+	if added, err = addTags(tx, userID, userBook, row.tags); err != nil {
+		return item, bookCreated, err
+	}
+	changed = changed || added
+
+	if row.rating > 0 && userBook.Status == "finished" {
+		var count int64
+		if err := tx.Model(&models.Review{}).Where("user_book_id = ?", userBook.ID).Count(&count).Error; err != nil {
+			return item, bookCreated, err
+		}
+		if count == 0 {
+			review := models.Review{
+				UserBookID: userBook.ID,
+				UserID:     userID,
+				Rating:     halfStars(row.rating),
+				Body:       row.review,
+				Spoiler:    row.spoiler,
+				Visibility: "public",
+				CreatedBy:  int64(userID), // Placeholder for actor ID
+				UpdatedBy:  int64(userID), // Placeholder for actor ID
+			}
+			if err := tx.Create(&review).Error; err != nil {
+				return item, bookCreated, err
+			}
+			changed = true
+		}
+	}
+
+	switch {
+	case bookCreated:
+		item.Status = models.ImportItemCreated
+	case changed:
+		item.Status = models.ImportItemUpdated
+	default:
+		item.Status, item.Reason = models.ImportItemSkipped, reasonAlreadyInLibrary
+	}
+	return item, bookCreated, nil
+}
+
+// addToShelves puts the book at the end of the named shelves, creating the
+// shelves that do not exist yet. It reports whether anything was added.
+func addToShelves(tx *gorm.DB, userID, userBookID uint, names []string) (bool, error) {
+	added := false
+	for _, name := range names {
+		var shelf models.Shelf
+		err := tx.Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name).First(&shelf).Error
+		if err == gorm.ErrRecordNotFound {
+			var count int64
+			if err := tx.Model(&models.Shelf{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+				return added, err
+			}
+			shelf = models.Shelf{
+				UserID:     userID,
+				Name:       name,
+				Visibility: "public",
+				Position:   int(count),
+				CreatedBy:  int64(userID), // Placeholder for actor ID
+				UpdatedBy:  int64(userID), // Placeholder for actor ID
+			}
+			err = tx.Create(&shelf).Error
+		}
+		if err != nil {
+			return added, err
+		}
+
+		var count int64
+		if err := tx.Model(&models.UserBookShelf{}).Where("shelf_id = ?", shelf.ID).Count(&count).Error; err != nil {
+			return added, err
+		}
+		result := tx.Where(models.UserBookShelf{ShelfID: shelf.ID, UserBookID: userBookID}).
+			Attrs(models.UserBookShelf{Position: int(count)}).
+			FirstOrCreate(&models.UserBookShelf{})
+		if result.Error != nil {
+			return added, result.Error
+		}
+		added = added || result.RowsAffected > 0
+	}
+	return added, nil
+}
+
+// addTags attaches the named tags to the book, creating missing tags. It
+// reports whether the book got a tag it did not have.
+func addTags(tx *gorm.DB, userID uint, userBook *models.UserBook, names []string) (bool, error) {
+	if len(names) == 0 {
+		return false, nil
+	}
+	before := tx.Model(userBook).Association("Tags").Count()
+
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tag := models.Tag{UserID: userID, Name: name}
+		if err := tx.Where(models.Tag{UserID: userID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return false, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := tx.Model(userBook).Association("Tags").Append(tags); err != nil {
+		return false, err
+	}
+	return tx.Model(userBook).Association("Tags").Count() > before, nil
+}
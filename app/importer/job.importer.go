@@ -0,0 +1,280 @@
+package importer
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/logger"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrQueueFull is returned by Enqueue when too many jobs are waiting.
+var ErrQueueFull = errors.New("import queue is full")
+
+// ErrRunnerStopped is returned by Enqueue after Stop has been called.
+var ErrRunnerStopped = errors.New("import runner is stopped")
+
+// progressEvery is how many rows are imported between progress updates.
+const progressEvery = 10
+
+type queuedJob struct {
+	jobID uint
+	data  []byte
+}
+
+// Runner executes CSV import jobs in the background with a fixed number of
+// workers. Jobs live in memory only: jobs that were queued or running when the
+// process stopped are marked as failed by Start.
+type Runner struct {
+	db      *gorm.DB
+	workers int
+	queue   chan queuedJob
+	quit    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	stopped bool
+}
+
+// NewRunner creates a runner with the given number of workers.
+func NewRunner(db *gorm.DB, workers int) *Runner {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Runner{
+		db:      db,
+		workers: workers,
+		queue:   make(chan queuedJob, 100),
+		quit:    make(chan struct{}),
+	}
+}
+
+// Start fails jobs left over from a previous run and starts the workers.
+func (r *Runner) Start() {
+	log := logger.GetLogger()
+	now := time.Now()
+	result := r.db.Model(&models.ImportJob{}).
+		Where("status IN ?", []string{models.ImportJobQueued, models.ImportJobRunning}).
+		Updates(map[string]interface{}{
+			"status":      models.ImportJobFailed,
+			"error":       "import was interrupted by a server restart; please upload the file again",
+			"finished_at": now,
+		})
+	if result.Error != nil {
+		log.Error("Failed to mark interrupted import jobs", zap.Error(result.Error))
+	} else if result.RowsAffected > 0 {
+		log.Warn("Marked interrupted import jobs as failed", zap.Int64("count", result.RowsAffected))
+	}
+
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+}
+
+// Enqueue schedules an already created job with the uploaded file contents.
+func (r *Runner) Enqueue(job *models.ImportJob, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return ErrRunnerStopped
+	}
+	select {
+	case r.queue <- queuedJob{jobID: job.ID, data: data}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop lets running jobs finish and fails the jobs still in the queue. It
+// returns ctx.Err() if the running jobs do not finish in time.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.stopped {
+		r.stopped = true
+		close(r.quit)
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+	for {
+		// Prefer quitting over picking up another job.
+		select {
+		case <-r.quit:
+			r.failQueued()
+			return
+		default:
+		}
+
+		select {
+		case <-r.quit:
+			r.failQueued()
+			return
+		case queued := <-r.queue:
+			r.run(queued)
+		}
+	}
+}
+
+// failQueued marks the jobs that will not run because the runner is stopping.
+func (r *Runner) failQueued() {
+	for {
+		select {
+		case queued := <-r.queue:
+			r.fail(queued.jobID, errors.New("server shut down before the import started; please upload the file again"))
+		default:
+			return
+		}
+	}
+}
+
+func (r *Runner) fail(jobID uint, cause error) {
+	now := time.Now()
+	if err := r.db.Model(&models.ImportJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"status":      models.ImportJobFailed,
+		"error":       cause.Error(),
+		"finished_at": now,
+	}).Error; err != nil {
+		logger.GetLogger().Error("Failed to mark import job as failed", zap.Error(err), zap.Uint("jobID", jobID))
+	}
+}
+
+func (r *Runner) run(queued queuedJob) {
+	log := logger.GetLogger()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Error("Import job panicked", zap.Any("panic", recovered), zap.Uint("jobID", queued.jobID))
+			r.fail(queued.jobID, errors.New("import failed unexpectedly"))
+		}
+	}()
+
+	var job models.ImportJob
+	if err := r.db.First(&job, queued.jobID).Error; err != nil {
+		log.Error("Failed to load import job", zap.Error(err), zap.Uint("jobID", queued.jobID))
+		return
+	}
+
+	rows, err := parseLibraryCSV(job.Source, bytes.NewReader(queued.data))
+	if err != nil {
+		log.Warn("Import file rejected", zap.Error(err), zap.Uint("jobID", job.ID))
+		r.fail(job.ID, err)
+		return
+	}
+
+	now := time.Now()
+	job.Status = models.ImportJobRunning
+	job.TotalRows = len(rows)
+	job.StartedAt = &now
+	if err := r.db.Select("status", "total_rows", "started_at").Save(&job).Error; err != nil {
+		log.Error("Failed to start import job", zap.Error(err), zap.Uint("jobID", job.ID))
+		return
+	}
+	log.Info("Import job started", zap.Uint("jobID", job.ID), zap.String("source", job.Source), zap.Int("rows", len(rows)))
+
+	report := &models.ImportReport{Source: job.Source}
+	for i, row := range rows {
+		var (
+			item        models.ImportReportItem
+			bookCreated bool
+		)
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			item, bookCreated, err = importLibraryRow(tx, job.UserID, row)
+			return err
+		})
+		if err != nil {
+			log.Warn("Failed to import row", zap.Error(err), zap.Uint("jobID", job.ID), zap.Int("line", row.line))
+			item = models.ImportReportItem{
+				Row:    row.line,
+				Title:  row.title,
+				Author: row.author,
+				Status: models.ImportItemFailed,
+				Reason: fmt.Sprintf("could not be saved: %v", err),
+			}
+		}
+		if bookCreated {
+			report.BooksCreated++
+		}
+		report.Add(item)
+
+		if (i+1)%progressEvery == 0 {
+			r.saveProgress(&job, report, i+1)
+		}
+	}
+
+	errorReport, err := buildErrorReport(report.Items)
+	if err != nil {
+		log.Error("Failed to build import error report", zap.Error(err), zap.Uint("jobID", job.ID))
+	}
+
+	finished := time.Now()
+	job.Status = models.ImportJobCompleted
+	job.ErrorReport = errorReport
+	job.FinishedAt = &finished
+	r.saveProgress(&job, report, len(rows))
+
+	log.Info("Import job completed",
+		zap.Uint("jobID", job.ID),
+		zap.Int("created", report.Created),
+		zap.Int("updated", report.Updated),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("failed", report.Failed))
+}
+
+func (r *Runner) saveProgress(job *models.ImportJob, report *models.ImportReport, processed int) {
+	job.ProcessedRows = processed
+	job.BooksCreated = report.BooksCreated
+	job.Created = report.Created
+	job.Updated = report.Updated
+	job.Skipped = report.Skipped
+	job.Failed = report.Failed
+	if err := r.db.Select("status", "processed_rows", "books_created", "created", "updated", "skipped", "failed", "error_report", "finished_at").
+		Save(job).Error; err != nil {
+		logger.GetLogger().Error("Failed to save import progress", zap.Error(err), zap.Uint("jobID", job.ID))
+	}
+}
+
+// buildErrorReport lists the rows that were not imported as CSV. Duplicates
+// are left out. It is empty when every row was imported.
+func buildErrorReport(items []models.ImportReportItem) (string, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	rows := 0
+	for _, item := range items {
+		if (item.Status != models.ImportItemSkipped && item.Status != models.ImportItemFailed) || item.Reason == reasonAlreadyInLibrary {
+			continue
+		}
+		if rows == 0 {
+			writer.Write([]string{"line", "title", "author", "status", "reason"})
+		}
+		rows++
+		writer.Write([]string{strconv.Itoa(item.Row), item.Title, item.Author, item.Status, item.Reason})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("write error report: %w", err)
+	}
+	return buf.String(), nil
+}
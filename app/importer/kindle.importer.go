@@ -0,0 +1,221 @@
+package importer
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/clippings"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SourceKindle marks highlights imported from a Kindle.
+const SourceKindle = "kindle"
+
+// kindleBook is the clippings of one book, in file order.
+type kindleBook struct {
+	title     string
+	author    string
+	clippings []clippings.Clipping
+}
+
+// clippingKey identifies a clipping within a book across imports. Kindle adds
+// a new entry when a highlight is extended, keeping its start location, so the
+// start location (or the page and text when there is none) is the identity.
+func clippingKey(kind string, locationStart int, page *int, text string) string {
+	if locationStart > 0 {
+		return kind + ":loc:" + strconv.Itoa(locationStart)
+	}
+	if page != nil {
+		return kind + ":page:" + strconv.Itoa(*page) + ":" + text
+	}
+	return kind + ":text:" + text
+}
+
+// highlightKey is clippingKey for a stored highlight.
+func highlightKey(highlight models.Highlight) string {
+	start, _ := strconv.Atoi(strings.SplitN(highlight.Location, "-", 2)[0])
+	text := highlight.Quote
+	if highlight.Kind == clippings.KindNote {
+		text = highlight.Comment
+	}
+	return clippingKey(highlight.Kind, start, highlight.Page, text)
+}
+
+// collapseClippings keeps the last version of every clipping and attaches
+// notes to the highlight they were written on. Kindle stores such a note as a
+// separate entry at the highlight's end location.
+func collapseClippings(entries []clippings.Clipping) (highlights []clippings.Clipping, notes map[int]string) {
+	latest := make(map[string]int)
+	for _, entry := range entries {
+		key := clippingKey(entry.Kind, entry.LocationStart, entry.Page, entry.Text)
+		if i, ok := latest[key]; ok {
+			highlights[i] = entry
+			continue
+		}
+		latest[key] = len(highlights)
+		highlights = append(highlights, entry)
+	}
+
+	notes = make(map[int]string)
+	result := make([]clippings.Clipping, 0, len(highlights))
+	var pending []clippings.Clipping
+	for _, entry := range highlights {
+		if entry.Kind == clippings.KindNote {
+			pending = append(pending, entry)
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	for _, note := range pending {
+		attached := false
+		if note.LocationStart > 0 {
+			for i, entry := range result {
+				if entry.Kind == clippings.KindHighlight && entry.LocationEnd == note.LocationStart {
+					notes[i] = note.Text
+					attached = true
+					break
+				}
+			}
+		}
+		if !attached {
+			result = append(result, note)
+		}
+	}
+	return result, notes
+}
+
+// Kindle stores parsed My Clippings.txt entries for user. Books are matched by
+// title and author and created when missing. Re-importing a file is safe:
+// entries that are already stored are skipped and extended highlights or
+// changed notes update the stored highlight.
+func Kindle(db *gorm.DB, user *models.User, entries []clippings.Clipping, invalid []clippings.Invalid) (*models.ImportReport, error) {
+	report := &models.ImportReport{Source: SourceKindle, Items: []models.ImportReportItem{}}
+
+	var books []*kindleBook
+	byKey := make(map[string]*kindleBook)
+	for _, entry := range entries {
+		key := bookKey(entry.Title, entry.Author)
+		book, ok := byKey[key]
+		if !ok {
+			book = &kindleBook{title: entry.Title, author: entry.Author}
+			byKey[key] = book
+			books = append(books, book)
+		}
+		book.clippings = append(book.clippings, entry)
+	}
+
+	for _, book := range books {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return importKindleBook(tx, user, book, report)
+		}); err != nil {
+			return nil, fmt.Errorf("import %q: %w", book.title, err)
+		}
+	}
+
+	for _, entry := range invalid {
+		report.Add(models.ImportReportItem{
+			Title:  entry.Title,
+			Status: models.ImportItemSkipped,
+			Reason: fmt.Sprintf("entry %d: %s", entry.Entry, entry.Reason),
+		})
+	}
+	return report, nil
+}
+
+func importKindleBook(tx *gorm.DB, user *models.User, book *kindleBook, report *models.ImportReport) error {
+	entries, notes := collapseClippings(book.clippings)
+
+	template := models.UserBook{UserID: user.ID, Title: book.title, Author: book.author}
+	for _, entry := range entries {
+		if entry.Page != nil && *entry.Page > template.TotalPages {
+			template.TotalPages = *entry.Page
+		}
+		if !entry.AddedAt.IsZero() && (template.StartDate.IsZero() || entry.AddedAt.Before(template.StartDate)) {
+			template.StartDate = entry.AddedAt
+		}
+	}
+
+	userBook, created, err := findOrCreateUserBook(tx, template)
+	if err != nil {
+		return err
+	}
+	if created {
+		report.BooksCreated++
+	} else {
+		report.BooksMatched++
+	}
+
+	var existing []models.Highlight
+	if err := tx.Where("user_book_id = ? AND source = ?", userBook.ID, SourceKindle).Find(&existing).Error; err != nil {
+		return err
+	}
+	stored := make(map[string]*models.Highlight, len(existing))
+	for i := range existing {
+		stored[highlightKey(existing[i])] = &existing[i]
+	}
+
+	for i, entry := range entries {
+		highlight := models.Highlight{
+			UserBookID:    userBook.ID,
+			UserID:        user.ID,
+			Kind:          entry.Kind,
+			Source:        SourceKindle,
+			Page:          entry.Page,
+			Location:      entry.Location(),
+			Quote:         entry.Text,
+			Comment:       notes[i],
+			Color:         "yellow",
+			HighlightedAt: entry.AddedAt,
+			CreatedBy:     int64(user.ID), // Placeholder for actor ID
+			UpdatedBy:     int64(user.ID), // Placeholder for actor ID
+		}
+		if entry.Kind == clippings.KindNote {
+			highlight.Quote = ""
+			highlight.Comment = entry.Text
+		}
+		if highlight.HighlightedAt.IsZero() {
+			highlight.HighlightedAt = time.Now()
+		}
+
+		item := models.ImportReportItem{
+			Title:    userBook.Title,
+			Author:   userBook.Author,
+			Kind:     entry.Kind,
+			Location: highlight.Location,
+		}
+
+		current, ok := stored[highlightKey(highlight)]
+		switch {
+		case !ok:
+			if err := tx.Create(&highlight).Error; err != nil {
+				return err
+			}
+			item.Status = models.ImportItemCreated
+		case current.Quote == highlight.Quote && current.Location == highlight.Location &&
+			(highlight.Comment == "" || current.Comment == highlight.Comment):
+			item.Status = models.ImportItemSkipped
+			item.Reason = "already imported"
+		default:
+			updates := map[string]interface{}{
+				"quote":      highlight.Quote,
+				"location":   highlight.Location,
+				"page":       highlight.Page,
+				"updated_by": int64(user.ID),
+			}
+			// Keep comments edited in the app unless Kindle has a note for the highlight.
+			if highlight.Comment != "" {
+				updates["comment"] = highlight.Comment
+			}
+			if err := tx.Model(current).Updates(updates).Error; err != nil {
+				return err
+			}
+			item.Status = models.ImportItemUpdated
+		}
+		report.Add(item)
+	}
+	return nil
+}
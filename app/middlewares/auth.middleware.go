@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/jwt"
+	"ayo-baca-buku/app/util/logger"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// userLocalKey is the ctx.Locals key holding the authenticated *models.User.
+const userLocalKey = "user"
+
+// AuthJWTMiddleware requires a valid "Authorization: Bearer <token>" header.
+// The token must be the one issued by the user's latest login, so logging in
+// again invalidates older tokens. The user is available through CurrentUser.
+func AuthJWTMiddleware(DB *gorm.DB, tokens *jwt.Manager) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		return authenticate(ctx, DB, tokens)
+	}
+}
+
+// OptionalAuthJWTMiddleware authenticates the request like AuthJWTMiddleware
+// when it carries an Authorization header and lets it through anonymously
+// otherwise, so public routes can show the owner more than everyone else.
+// CurrentUser is nil for anonymous requests.
+func OptionalAuthJWTMiddleware(DB *gorm.DB, tokens *jwt.Manager) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if ctx.Get(fiber.HeaderAuthorization) == "" {
+			return ctx.Next()
+		}
+		return authenticate(ctx, DB, tokens)
+	}
+}
+
+// authenticate checks the bearer token and stores its user for CurrentUser.
+func authenticate(ctx *fiber.Ctx, DB *gorm.DB, tokens *jwt.Manager) error {
+	log := logger.FromContext(ctx.UserContext())
+
+	claims, err := tokens.GetUserInfo(ctx)
+	if err != nil {
+		log.Warn("Invalid authorization token", zap.Error(err), zap.String("path", ctx.Path()))
+		return apperror.Unauthorized("Unauthorized")
+	}
+
+	var user models.User
+	if err := DB.WithContext(ctx.UserContext()).Where("uid = ?", claims.UID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warn("Token user not found", zap.String("uid", claims.UID))
+			return apperror.Unauthorized("Unauthorized")
+		}
+		log.Error("Failed to fetch token user", zap.Error(err), zap.String("uid", claims.UID))
+		return apperror.Internal("Failed to authenticate")
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(ctx.Get(fiber.HeaderAuthorization), "Bearer "))
+	if user.Token == "" || user.Token != token {
+		log.Warn("Token is no longer active", zap.Uint("userID", user.ID))
+		return apperror.Unauthorized("Unauthorized")
+	}
+
+	ctx.Locals(userLocalKey, &user)
+	setRequestLogger(ctx, zap.Uint("userID", user.ID))
+	return ctx.Next()
+}
+
+// RequireRole lets only users with one of roles through. It must follow
+// AuthJWTMiddleware.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		user := CurrentUser(ctx)
+		if user == nil {
+			return apperror.Unauthorized("Unauthorized")
+		}
+		for _, role := range roles {
+			if user.Role == role {
+				return ctx.Next()
+			}
+		}
+		logger.FromContext(ctx.UserContext()).Warn("Role not allowed", zap.String("role", user.Role), zap.String("path", ctx.Path()))
+		return apperror.Forbidden("Forbidden")
+	}
+}
+
+// CurrentUser returns the user authenticated by AuthJWTMiddleware, or nil
+// when the route is not protected or the request is anonymous.
+func CurrentUser(ctx *fiber.Ctx) *models.User {
+	user, _ := ctx.Locals(userLocalKey).(*models.User)
+	return user
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// MIMEApplicationProblemJSON is the content type of error responses.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// ErrorHandler answers requests whose handler returned an error with RFC 7807
+// problem details. *apperror.Error values are sent as they are, *fiber.Error
+// values, e.g. the 404 of an unknown path, keep their status and any other
+// error is a 500 that does not reveal the cause.
+func ErrorHandler(ctx *fiber.Ctx, err error) error {
+	var (
+		appErr   *apperror.Error
+		fiberErr *fiber.Error
+	)
+	switch {
+	case errors.As(err, &appErr):
+	case errors.As(err, &fiberErr):
+		appErr = apperror.New(fiberErr.Code, apperror.StatusCode(fiberErr.Code), fiberErr.Message)
+	default:
+		appErr = apperror.From(err)
+	}
+
+	// Handlers log what went wrong before returning an error, only causes
+	// they passed on are logged here.
+	if appErr.Status >= fiber.StatusInternalServerError && appErr.Err != nil {
+		logger.FromContext(ctx.UserContext()).Error("Request failed", zap.Error(appErr.Err), zap.String("path", ctx.Path()))
+	}
+
+	return ctx.Status(appErr.Status).JSON(appErr.Problem(ctx.Path(), GetRequestID(ctx)), MIMEApplicationProblemJSON)
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"ayo-baca-buku/app/util/apperror"
+	"ayo-baca-buku/app/util/logger"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// RecoverMiddleware turns a panic in a handler into a 500 response. The panic
+// is logged with its stack; the client only gets the request ID.
+func RecoverMiddleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.FromContext(ctx.UserContext()).Error("Panic while handling request",
+					zap.Any("panic", r), zap.String("path", ctx.Path()), zap.Stack("stack"))
+				err = apperror.Internal("Internal Server Error")
+			}
+		}()
+		return ctx.Next()
+	}
+}
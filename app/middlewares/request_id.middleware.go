@@ -0,0 +1,113 @@
+package middlewares
+
+import (
+	"ayo-baca-buku/app/util/logger"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// requestIDLocalKey is the ctx.Locals key holding the request ID.
+const requestIDLocalKey = "requestID"
+
+// maxRequestIDLength limits request IDs taken from the X-Request-ID header.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware gives every request an ID: the X-Request-ID header of
+// the caller, e.g. a load balancer, or a new random one. The ID is sent back
+// in the X-Request-ID response header and as request_id in JSON error bodies.
+// Handlers get a logger that adds the request ID and trace ID to every line
+// with logger.FromContext(ctx.UserContext()); queries run with
+// DB.WithContext(ctx.UserContext()) are logged with it too.
+func RequestIDMiddleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		requestID := ctx.Get(fiber.HeaderXRequestID)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		} else {
+			// Header values point into a buffer that is reused after the request.
+			requestID = strings.Clone(requestID)
+		}
+		ctx.Locals(requestIDLocalKey, requestID)
+		ctx.Set(fiber.HeaderXRequestID, requestID)
+
+		fields := []zap.Field{zap.String("requestID", requestID)}
+		if span := trace.SpanContextFromContext(ctx.UserContext()); span.IsValid() {
+			fields = append(fields, zap.String("traceID", span.TraceID().String()))
+		}
+		requestLogger := logger.GetLogger().With(fields...)
+		ctx.SetUserContext(logger.WithContext(ctx.UserContext(), requestLogger))
+
+		if err := ctx.Next(); err != nil {
+			return err
+		}
+		addRequestIDToError(ctx, requestID)
+		return nil
+	}
+}
+
+// GetRequestID returns the ID RequestIDMiddleware gave the request.
+func GetRequestID(ctx *fiber.Ctx) string {
+	requestID, _ := ctx.Locals(requestIDLocalKey).(string)
+	return requestID
+}
+
+// setRequestLogger replaces the logger of the request, e.g. to add the user
+// once it is authenticated.
+func setRequestLogger(ctx *fiber.Ctx, fields ...zap.Field) {
+	requestLogger := logger.FromContext(ctx.UserContext()).With(fields...)
+	ctx.SetUserContext(logger.WithContext(ctx.UserContext(), requestLogger))
+}
+
+// validRequestID accepts IDs of letters, digits and -_.: only, so a header
+// cannot inject anything into logs or responses.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// addRequestIDToError adds request_id to the JSON object of an error
+// response, so users can quote it when reporting a problem.
+func addRequestIDToError(ctx *fiber.Ctx, requestID string) {
+	response := ctx.Response()
+	if response.StatusCode() < fiber.StatusBadRequest ||
+		!strings.HasPrefix(string(response.Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return
+	}
+	body := bytes.TrimSpace(response.Body())
+	if len(body) < 2 || body[0] != '{' || body[len(body)-1] != '}' {
+		return
+	}
+
+	id, _ := json.Marshal(requestID)
+	withID := make([]byte, 0, len(body)+len(id)+16)
+	withID = append(withID, `{"request_id":`...)
+	withID = append(withID, id...)
+	if rest := bytes.TrimSpace(body[1:]); len(rest) > 1 {
+		withID = append(withID, ',')
+	}
+	withID = append(withID, body[1:]...)
+	response.SetBodyRaw(withID)
+}
@@ -0,0 +1,7 @@
+package models
+
+// MarkdownExportRequest defines the payload for a Markdown export with a custom
+// template. An empty Template uses the built-in one.
+type MarkdownExportRequest struct {
+	Template string `json:"template" validate:"max=65536"` // Go text/template untuk satu buku
+}
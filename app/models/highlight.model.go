@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Highlight is a passage marked in a UserBook, optionally with the reader's own
+// comment. The highlights table also has a generated search_vector column for
+// full-text search; it is created by the 0002_library_features migration.
+type Highlight struct {
+	ID            uint           `json:"id" gorm:"primarykey"`
+	UserBookID    uint           `json:"user_book_id" gorm:"not null;index"`
+	UserID        uint           `json:"user_id" gorm:"not null;index"`
+	Kind          string         `json:"kind" gorm:"type:varchar(20);not null;check:kind IN ('highlight', 'note', 'bookmark');default:'highlight'"`
+	Source        string         `json:"source" gorm:"type:varchar(20);not null;default:'manual'"` // manual atau kindle
+	Page          *int           `json:"page"`
+	Location      string         `json:"location" gorm:"type:varchar(100)"` // Lokasi e-reader, mis. "1234-1240" pada Kindle
+	Quote         string         `json:"quote" gorm:"type:text;not null"`
+	Comment       string         `json:"comment" gorm:"type:text"`
+	Color         string         `json:"color" gorm:"type:varchar(20);not null;default:'yellow'"`
+	Tag           string         `json:"tag" gorm:"type:varchar(50);index"`
+	HighlightedAt time.Time      `json:"highlighted_at" gorm:"not null;index"`
+	UserBook      *UserBook      `json:"user_book,omitempty" gorm:"foreignKey:UserBookID"`
+	CreatedAt     time.Time      `json:"created_at"`
+	CreatedBy     int64          `json:"created_by"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	UpdatedBy     int64          `json:"updated_by"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	DeletedBy     int64          `json:"deleted_by,omitempty"`
+}
+
+// HighlightCreateRequest defines the payload for saving a highlight.
+// HighlightedAt defaults to now.
+type HighlightCreateRequest struct {
+	UserBookID    uint       `json:"user_book_id" validate:"required"`
+	Kind          string     `json:"kind,omitempty" validate:"omitempty,oneof=highlight note bookmark"`
+	Page          *int       `json:"page,omitempty" validate:"omitempty,gte=0"`
+	Location      string     `json:"location,omitempty" validate:"omitempty,max=100"`
+	Quote         string     `json:"quote" validate:"required_unless=Kind bookmark,max=10000"`
+	Comment       string     `json:"comment,omitempty" validate:"omitempty,max=10000"`
+	Color         string     `json:"color,omitempty" validate:"omitempty,oneof=yellow green blue pink purple orange"`
+	Tag           string     `json:"tag,omitempty" validate:"omitempty,max=50"`
+	HighlightedAt *time.Time `json:"highlighted_at,omitempty"`
+}
+
+// HighlightUpdateRequest defines the payload for editing a highlight.
+type HighlightUpdateRequest struct {
+	Page          *int       `json:"page,omitempty" validate:"omitempty,gte=0"`
+	Location      *string    `json:"location,omitempty" validate:"omitempty,max=100"`
+	Quote         string     `json:"quote,omitempty" validate:"omitempty,max=10000"`
+	Comment       *string    `json:"comment,omitempty" validate:"omitempty,max=10000"`
+	Color         string     `json:"color,omitempty" validate:"omitempty,oneof=yellow green blue pink purple orange"`
+	Tag           *string    `json:"tag,omitempty" validate:"omitempty,max=50"`
+	HighlightedAt *time.Time `json:"highlighted_at,omitempty"`
+}
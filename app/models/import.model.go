@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// Import job states.
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+// ImportJob is a library import running in the background. The uploaded file
+// is only kept in memory; ErrorReport holds the CSV of rows that were not
+// imported once the job has finished.
+type ImportJob struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	Source        string     `json:"source" gorm:"type:varchar(20);not null"` // goodreads atau storygraph
+	FileName      string     `json:"file_name" gorm:"type:varchar(255)"`
+	Status        string     `json:"status" gorm:"type:varchar(20);not null;check:status IN ('queued', 'running', 'completed', 'failed');default:'queued'"`
+	TotalRows     int        `json:"total_rows" gorm:"not null;default:0"`
+	ProcessedRows int        `json:"processed_rows" gorm:"not null;default:0"`
+	BooksCreated  int        `json:"books_created" gorm:"not null;default:0"`
+	Created       int        `json:"created" gorm:"not null;default:0"`
+	Updated       int        `json:"updated" gorm:"not null;default:0"`
+	Skipped       int        `json:"skipped" gorm:"not null;default:0"`
+	Failed        int        `json:"failed" gorm:"not null;default:0"`
+	Error         string     `json:"error,omitempty" gorm:"type:text"`
+	ErrorReport   string     `json:"-" gorm:"type:text"`
+	HasErrors     bool       `json:"has_error_report" gorm:"-"`
+	Progress      float64    `json:"progress" gorm:"-"` // persen baris yang sudah diproses
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	User          User       `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Outcome of a single imported item.
+const (
+	ImportItemCreated = "created"
+	ImportItemUpdated = "updated"
+	ImportItemSkipped = "skipped"
+	ImportItemFailed  = "failed"
+)
+
+// ImportReport summarises what an import did. Items lists every entry of the
+// imported file with its outcome.
+type ImportReport struct {
+	Source       string             `json:"source"`
+	BooksCreated int                `json:"books_created"`
+	BooksMatched int                `json:"books_matched"`
+	Created      int                `json:"created"`
+	Updated      int                `json:"updated"`
+	Skipped      int                `json:"skipped"`
+	Failed       int                `json:"failed"`
+	Items        []ImportReportItem `json:"items"`
+}
+
+// ImportReportItem is one entry of an ImportReport.
+type ImportReportItem struct {
+	Row      int    `json:"row,omitempty"` // CSV line number, for CSV imports
+	Title    string `json:"title"`
+	Author   string `json:"author,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Location string `json:"location,omitempty"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Add records an item and updates the matching counter.
+func (r *ImportReport) Add(item ImportReportItem) {
+	switch item.Status {
+	case ImportItemCreated:
+		r.Created++
+	case ImportItemUpdated:
+		r.Updated++
+	case ImportItemSkipped:
+		r.Skipped++
+	case ImportItemFailed:
+		r.Failed++
+	}
+	r.Items = append(r.Items, item)
+}
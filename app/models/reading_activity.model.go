@@ -12,6 +12,7 @@ type ReadingActivity struct {
 	PagesRead   int            `json:"pages_read" gorm:"not null"`
 	StartPage   int            `json:"start_page" gorm:"not null"`
 	EndPage     int            `json:"end_page" gorm:"not null"`
+	Duration    int            `json:"duration" gorm:"default:0"` // Lama membaca dalam menit, 0 jika tidak dicatat
 	Notes       string         `json:"notes" gorm:"type:text"`
 	ReadingDate time.Time      `json:"reading_date" gorm:"not null"`
 	UserBook    UserBook       `json:"user_book" gorm:"foreignKey:UserBookID"`
@@ -26,6 +27,7 @@ type ReadingActivityCreateRequest struct {
 	PagesRead   int       `json:"pages_read" validate:"required,gt=0"`
 	StartPage   int       `json:"start_page" validate:"required,gte=0"`
 	EndPage     int       `json:"end_page" validate:"required,gtfield=StartPage"`
+	Duration    int       `json:"duration,omitempty" validate:"omitempty,gte=0"` // Minutes spent reading
 	Notes       string    `json:"notes,omitempty"`
 	ReadingDate time.Time `json:"reading_date" validate:"required"`
 }
@@ -35,6 +37,7 @@ type ReadingActivityUpdateRequest struct {
 	PagesRead   *int      `json:"pages_read,omitempty" validate:"omitempty,gt=0"`
 	StartPage   *int      `json:"start_page,omitempty" validate:"omitempty,gte=0"`
 	EndPage     *int      `json:"end_page,omitempty" validate:"omitempty,gtfield=StartPage"`
+	Duration    *int      `json:"duration,omitempty" validate:"omitempty,gte=0"`
 	Notes       string    `json:"notes,omitempty"`
 	ReadingDate time.Time `json:"reading_date,omitempty" validate:"omitempty,required"`
 }
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Goal types. "books" counts finished UserBooks, "pages" sums pages read over the
+// goal period and "pages_per_day" expects Target pages on every day of the period.
+const (
+	GoalTypeBooks       = "books"
+	GoalTypePages       = "pages"
+	GoalTypePagesPerDay = "pages_per_day"
+)
+
+type ReadingGoal struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	UserID    uint           `json:"user_id" gorm:"not null;index"`
+	Title     string         `json:"title" gorm:"type:varchar(255);not null"`
+	Type      string         `json:"type" gorm:"type:varchar(20);not null;check:type IN ('books', 'pages', 'pages_per_day')"`
+	Target    int            `json:"target" gorm:"not null"`
+	StartDate time.Time      `json:"start_date" gorm:"type:date;not null"`
+	EndDate   time.Time      `json:"end_date" gorm:"type:date;not null"`
+	User      User           `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt time.Time      `json:"created_at"`
+	CreatedBy int64          `json:"created_by"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	UpdatedBy int64          `json:"updated_by"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	DeletedBy int64          `json:"deleted_by,omitempty"`
+}
+
+// ReadingGoalCreateRequest defines the payload for creating a reading goal.
+// Either Year or both StartDate and EndDate must be given; Year is a shortcut
+// for a goal covering that whole calendar year.
+type ReadingGoalCreateRequest struct {
+	UserID    uint   `json:"user_id" validate:"required"`
+	Title     string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Type      string `json:"type" validate:"required,oneof=books pages pages_per_day"`
+	Target    int    `json:"target" validate:"required,gt=0"`
+	Year      int    `json:"year,omitempty" validate:"required_without_all=StartDate EndDate,omitempty,gte=1970,lte=9999"`
+	StartDate string `json:"start_date,omitempty" validate:"required_with=EndDate,omitempty,datetime=2006-01-02"`
+	EndDate   string `json:"end_date,omitempty" validate:"required_with=StartDate,omitempty,datetime=2006-01-02"`
+}
+
+// ReadingGoalUpdateRequest defines the payload for updating a reading goal.
+type ReadingGoalUpdateRequest struct {
+	Title     string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Target    *int   `json:"target,omitempty" validate:"omitempty,gt=0"`
+	StartDate string `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	EndDate   string `json:"end_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// Goal statuses reported in ReadingGoalProgress.
+const (
+	GoalStatusUpcoming  = "upcoming"
+	GoalStatusOnTrack   = "on_track"
+	GoalStatusBehind    = "behind"
+	GoalStatusCompleted = "completed"
+	GoalStatusFailed    = "failed"
+)
+
+// ReadingGoalProgress is computed on every request from UserBook finishes and
+// ReadingActivity pages; it is never stored.
+type ReadingGoalProgress struct {
+	Current         int64   `json:"current"`
+	TargetTotal     int64   `json:"target_total"`
+	PercentComplete float64 `json:"percent_complete"`
+	TotalDays       int     `json:"total_days"`
+	ElapsedDays     int     `json:"elapsed_days"`
+	RemainingDays   int     `json:"remaining_days"`
+	Expected        float64 `json:"expected"`                  // where the user should be by today to finish on time
+	Projected       float64 `json:"projected"`                 // where the user ends up at the current pace
+	RequiredPerDay  float64 `json:"required_per_day"`          // needed from today on to still reach the target
+	DaysMetTarget   *int    `json:"days_met_target,omitempty"` // pages_per_day goals only
+	Status          string  `json:"status"`
+}
+
+// ReadingGoalWithProgress is the API representation of a goal.
+type ReadingGoalWithProgress struct {
+	ReadingGoal
+	Progress ReadingGoalProgress `json:"progress"`
+}
+
+// ReadingGoalSummary groups a user's goals by state.
+type ReadingGoalSummary struct {
+	UserID         uint                      `json:"user_id"`
+	ActiveGoals    int                       `json:"active_goals"`
+	OnTrack        int                       `json:"on_track"`
+	Behind         int                       `json:"behind"`
+	CompletedGoals int                       `json:"completed_goals"`
+	FailedGoals    int                       `json:"failed_goals"`
+	Active         []ReadingGoalWithProgress `json:"active"`
+	Upcoming       []ReadingGoalWithProgress `json:"upcoming"`
+	RecentlyEnded  []ReadingGoalWithProgress `json:"recently_ended"`
+}
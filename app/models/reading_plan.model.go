@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReadingPlan is a deadline for finishing a UserBook. The daily schedule is not
+// stored: it is derived from StartPage/StartDate (the original plan) and from the
+// book's CurrentPage (the recalculated plan) every time it is requested.
+type ReadingPlan struct {
+	ID         uint           `json:"id" gorm:"primarykey"`
+	UserBookID uint           `json:"user_book_id" gorm:"not null;index"`
+	Title      string         `json:"title" gorm:"type:varchar(255)"` // mis. "Book club meeting"
+	StartDate  time.Time      `json:"start_date" gorm:"type:date;not null"`
+	Deadline   time.Time      `json:"deadline" gorm:"type:date;not null"`
+	StartPage  int            `json:"start_page" gorm:"not null;default:0"`
+	UserBook   UserBook       `json:"-" gorm:"foreignKey:UserBookID"`
+	CreatedAt  time.Time      `json:"created_at"`
+	CreatedBy  int64          `json:"created_by"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	UpdatedBy  int64          `json:"updated_by"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	DeletedBy  int64          `json:"deleted_by,omitempty"`
+}
+
+// ReadingPlanCreateRequest defines the payload for creating a reading plan.
+// StartDate defaults to today in the user's timezone.
+type ReadingPlanCreateRequest struct {
+	Title     string `json:"title,omitempty" validate:"omitempty,max=255"`
+	StartDate string `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Deadline  string `json:"deadline" validate:"required,datetime=2006-01-02"`
+}
+
+// ReadingPlanUpdateRequest defines the payload for moving the deadline or renaming a plan.
+type ReadingPlanUpdateRequest struct {
+	Title    string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Deadline string `json:"deadline,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// ReadingPlanDay is one day of the schedule returned by the API. Days before
+// today come from the original plan, today and later from the recalculated one.
+type ReadingPlanDay struct {
+	Date        time.Time `json:"date"`
+	Rest        bool      `json:"rest"`
+	Pages       int       `json:"pages"`
+	TargetPage  int       `json:"target_page"`
+	ActualPages *int64    `json:"actual_pages,omitempty"` // only for days up to today
+}
+
+// ReadingPlanSchedule is a plan together with its current schedule.
+type ReadingPlanSchedule struct {
+	ReadingPlan
+	BookTitle          string           `json:"book_title"`
+	TotalPages         int              `json:"total_pages"`
+	CurrentPage        int              `json:"current_page"`
+	RestDays           string           `json:"rest_days"`
+	ExpectedPage       int              `json:"expected_page"` // where the original plan expected the reader to be today
+	Behind             bool             `json:"behind"`
+	PagesBehind        int              `json:"pages_behind"`
+	ReadingDaysLeft    int              `json:"reading_days_left"`
+	PagesPerReadingDay float64          `json:"pages_per_reading_day"`
+	TodayTarget        int              `json:"today_target"` // pages to read today according to the recalculated plan
+	Completed          bool             `json:"completed"`
+	Days               []ReadingPlanDay `json:"days"`
+}
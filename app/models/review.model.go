@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Review is what a reader thought of a finished UserBook. A UserBook has at
+// most one review; earlier versions are kept as ReviewRevisions.
+type Review struct {
+	ID         uint             `json:"id" gorm:"primarykey"`
+	UserBookID uint             `json:"user_book_id" gorm:"not null;uniqueIndex:idx_reviews_user_book,where:deleted_at IS NULL"`
+	UserID     uint             `json:"user_id" gorm:"not null;index"`
+	Rating     float64          `json:"rating" gorm:"type:numeric(2,1);not null;check:rating >= 0.5 AND rating <= 5"` // Bintang, kelipatan 0.5
+	Body       string           `json:"body" gorm:"type:text"`                                                        // Markdown
+	Spoiler    bool             `json:"spoiler" gorm:"not null;default:false"`
+	Visibility string           `json:"visibility" gorm:"type:varchar(20);not null;check:visibility IN ('public', 'private');default:'public'"`
+	EditCount  int              `json:"edit_count" gorm:"not null;default:0"`
+	UserBook   *UserBook        `json:"user_book,omitempty" gorm:"foreignKey:UserBookID"`
+	User       *User            `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Revisions  []ReviewRevision `json:"revisions,omitempty" gorm:"foreignKey:ReviewID"`
+	CreatedAt  time.Time        `json:"created_at"`
+	CreatedBy  int64            `json:"created_by"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	UpdatedBy  int64            `json:"updated_by"`
+	DeletedAt  gorm.DeletedAt   `json:"deleted_at,omitempty" gorm:"index"`
+	DeletedBy  int64            `json:"deleted_by,omitempty"`
+}
+
+// ReviewRevision is a snapshot of a review taken right before it was edited.
+type ReviewRevision struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	ReviewID   uint      `json:"review_id" gorm:"not null;index"`
+	Rating     float64   `json:"rating" gorm:"type:numeric(2,1);not null"`
+	Body       string    `json:"body" gorm:"type:text"`
+	Spoiler    bool      `json:"spoiler"`
+	Visibility string    `json:"visibility" gorm:"type:varchar(20);not null"`
+	WrittenAt  time.Time `json:"written_at"` // UpdatedAt of the review when this version was current
+	CreatedAt  time.Time `json:"created_at"`
+	CreatedBy  int64     `json:"created_by"`
+}
+
+// ReviewCreateRequest defines the payload for reviewing a finished user book.
+type ReviewCreateRequest struct {
+	Rating     float64 `json:"rating" validate:"required,half_star"`
+	Body       string  `json:"body,omitempty" validate:"omitempty,max=20000"`
+	Spoiler    bool    `json:"spoiler,omitempty"`
+	Visibility string  `json:"visibility,omitempty" validate:"omitempty,oneof=public private"`
+}
+
+// ReviewUpdateRequest defines the payload for editing a review.
+type ReviewUpdateRequest struct {
+	Rating     *float64 `json:"rating,omitempty" validate:"omitempty,half_star"`
+	Body       *string  `json:"body,omitempty" validate:"omitempty,max=20000"`
+	Spoiler    *bool    `json:"spoiler,omitempty"`
+	Visibility string   `json:"visibility,omitempty" validate:"omitempty,oneof=public private"`
+}
+
+// BookRating is the aggregate of public ratings for a catalog book. There is
+// no shared book catalog yet, so a book is identified by its title and author
+// compared case-insensitively.
+type BookRating struct {
+	Title         string           `json:"title"`
+	Author        string           `json:"author"`
+	AverageRating float64          `json:"average_rating"`
+	RatingCount   int64            `json:"rating_count"`
+	ReviewCount   int64            `json:"review_count"` // ratings that come with review text
+	Distribution  map[string]int64 `json:"distribution"` // "0.5" .. "5" -> number of ratings
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Shelf is a user-defined, ordered group of UserBooks such as "Work" or "Bedtime".
+type Shelf struct {
+	ID          uint           `json:"id" gorm:"primarykey"`
+	UserID      uint           `json:"user_id" gorm:"not null;index"`
+	Name        string         `json:"name" gorm:"type:varchar(100);not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	Visibility  string         `json:"visibility" gorm:"type:varchar(20);not null;check:visibility IN ('public', 'private');default:'public'"`
+	Position    int            `json:"position" gorm:"not null;default:0"` // Urutan rak milik user (drag-and-drop)
+	BookCount   int64          `json:"book_count" gorm:"-"`
+	UserBooks   []UserBook     `json:"user_books,omitempty" gorm:"many2many:user_book_shelves"`
+	User        User           `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CreatedBy   int64          `json:"created_by"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	UpdatedBy   int64          `json:"updated_by"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	DeletedBy   int64          `json:"deleted_by,omitempty"`
+}
+
+// UserBookShelf is the join table between shelves and user books. Position orders
+// the books within a shelf.
+type UserBookShelf struct {
+	ShelfID    uint      `json:"shelf_id" gorm:"primaryKey"`
+	UserBookID uint      `json:"user_book_id" gorm:"primaryKey;index"`
+	Position   int       `json:"position" gorm:"not null;default:0"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ShelfCreateRequest defines the payload for creating a shelf.
+// When Position is omitted the shelf is appended after the user's last shelf.
+type ShelfCreateRequest struct {
+	UserID      uint   `json:"user_id" validate:"required"`
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Description string `json:"description,omitempty"`
+	Visibility  string `json:"visibility,omitempty" validate:"omitempty,oneof=public private"`
+	Position    *int   `json:"position,omitempty" validate:"omitempty,gte=0"`
+}
+
+// ShelfUpdateRequest defines the payload for updating a shelf.
+type ShelfUpdateRequest struct {
+	Name        string  `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty"`
+	Visibility  string  `json:"visibility,omitempty" validate:"omitempty,oneof=public private"`
+	Position    *int    `json:"position,omitempty" validate:"omitempty,gte=0"`
+}
+
+// ShelfReorderRequest lists all of a user's shelves in their new order.
+type ShelfReorderRequest struct {
+	UserID   uint   `json:"user_id" validate:"required"`
+	ShelfIDs []uint `json:"shelf_ids" validate:"required,min=1,unique"`
+}
+
+// ShelfBookAddRequest adds a user book to a shelf. When Position is omitted the
+// book is appended at the end of the shelf.
+type ShelfBookAddRequest struct {
+	UserBookID uint `json:"user_book_id" validate:"required"`
+	Position   *int `json:"position,omitempty" validate:"omitempty,gte=0"`
+}
+
+// ShelfBookReorderRequest lists all books of a shelf in their new order.
+type ShelfBookReorderRequest struct {
+	UserBookIDs []uint `json:"user_book_ids" validate:"required,min=1,unique"`
+}
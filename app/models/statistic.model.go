@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// ReadingSummary aggregates every reading activity of a single user.
+type ReadingSummary struct {
+	UserID                 uint     `json:"user_id"`
+	TotalSessions          int64    `json:"total_sessions"`
+	TotalPagesRead         int64    `json:"total_pages_read"`
+	TotalMinutes           int64    `json:"total_minutes"`
+	AveragePagesPerSession float64  `json:"average_pages_per_session"`
+	PagesPerHour           *float64 `json:"pages_per_hour"` // nil until at least one activity has a duration
+	BooksReading           int64    `json:"books_reading"`
+	BooksFinished          int64    `json:"books_finished"`
+}
+
+// PagesReadPerPeriod is one bucket of the pages-read time series.
+type PagesReadPerPeriod struct {
+	Period    time.Time `json:"period"`
+	PagesRead int64     `json:"pages_read"`
+	Sessions  int64     `json:"sessions"`
+	Minutes   int64     `json:"minutes"`
+}
+
+// BooksFinishedPerPeriod is one bucket of the finished-books time series.
+type BooksFinishedPerPeriod struct {
+	Period        time.Time `json:"period"`
+	BooksFinished int64     `json:"books_finished"`
+}
+
+// ReadingBreakdown groups a user's books by author or genre.
+type ReadingBreakdown struct {
+	Name          string `json:"name"`
+	Books         int64  `json:"books"`
+	BooksFinished int64  `json:"books_finished"`
+	PagesRead     int64  `json:"pages_read"`
+}
+
+// UserBookProgress describes how far along a single UserBook is.
+type UserBookProgress struct {
+	UserBookID             uint       `json:"user_book_id"`
+	Status                 string     `json:"status"`
+	TotalPages             int        `json:"total_pages"`
+	CurrentPage            int        `json:"current_page"`
+	PagesRemaining         int        `json:"pages_remaining"`
+	PercentComplete        float64    `json:"percent_complete"`
+	Sessions               int64      `json:"sessions"`
+	PagesRead              int64      `json:"pages_read"`
+	AveragePagesPerSession float64    `json:"average_pages_per_session"`
+	AveragePagesPerDay     float64    `json:"average_pages_per_day"`
+	PagesPerHour           *float64   `json:"pages_per_hour"`
+	FirstReadingDate       *time.Time `json:"first_reading_date"`
+	LastReadingDate        *time.Time `json:"last_reading_date"`
+	EstimatedFinishDate    *time.Time `json:"estimated_finish_date"` // nil when finished or no pace yet
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// StreakFreeze marks a day on which a missed reading session does not break the user's streak.
+type StreakFreeze struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_streak_freezes_user_date"`
+	FreezeDate time.Time `json:"freeze_date" gorm:"type:date;not null;uniqueIndex:idx_streak_freezes_user_date"`
+	Reason     string    `json:"reason" gorm:"type:varchar(255)"`
+	User       User      `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// StreakFreezeCreateRequest defines the payload for adding a streak-freeze day.
+type StreakFreezeCreateRequest struct {
+	FreezeDate string `json:"freeze_date" validate:"required,datetime=2006-01-02"`
+	Reason     string `json:"reason,omitempty" validate:"omitempty,max=255"`
+}
+
+// ReadingHabitSetting is the part of the user profile that drives streak computation.
+type ReadingHabitSetting struct {
+	Timezone            string `json:"timezone"`
+	DailyMinimumPages   int    `json:"daily_minimum_pages"`
+	DailyMinimumMinutes int    `json:"daily_minimum_minutes"`
+	RestDays            string `json:"rest_days"` // Skipped by reading plans
+}
+
+// ReadingHabitUpdateRequest defines the payload for updating the habit settings.
+// A day counts as a "read day" when either configured minimum is reached.
+type ReadingHabitUpdateRequest struct {
+	Timezone            string  `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	DailyMinimumPages   *int    `json:"daily_minimum_pages,omitempty" validate:"omitempty,gte=0"`
+	DailyMinimumMinutes *int    `json:"daily_minimum_minutes,omitempty" validate:"omitempty,gte=0"`
+	RestDays            *string `json:"rest_days,omitempty" validate:"omitempty,max=100"` // Comma separated weekdays, "" clears
+}
+
+// ReadingStreak is the streak summary returned by the API.
+type ReadingStreak struct {
+	ReadingHabitSetting
+	CurrentStreak      int        `json:"current_streak"`
+	CurrentStreakStart *time.Time `json:"current_streak_start"`
+	LongestStreak      int        `json:"longest_streak"`
+	LongestStreakStart *time.Time `json:"longest_streak_start"`
+	LongestStreakEnd   *time.Time `json:"longest_streak_end"`
+	ReadToday          bool       `json:"read_today"`
+	LastReadDate       *time.Time `json:"last_read_date"`
+	TotalReadDays      int        `json:"total_read_days"`
+}
+
+// ReadingDay holds the reading totals of a single calendar day in the user's timezone.
+type ReadingDay struct {
+	Date      time.Time `json:"date"`
+	PagesRead int64     `json:"pages_read"`
+	Minutes   int64     `json:"minutes"`
+	Sessions  int64     `json:"sessions"`
+	ReadDay   bool      `json:"read_day"`
+	Frozen    bool      `json:"frozen"`
+}
+
+// ReadingHeatmap is a calendar year of ReadingDay entries, one per day.
+type ReadingHeatmap struct {
+	Year     int          `json:"year"`
+	Timezone string       `json:"timezone"`
+	Days     []ReadingDay `json:"days"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Tag is a free-form label a user attaches to their books. Names are stored
+// trimmed and lower-cased so "Work" and "work " are the same tag.
+type Tag struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name      string     `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_tags_user_name"`
+	BookCount int64      `json:"book_count" gorm:"->;-:migration"` // Diisi oleh query daftar tag
+	UserBooks []UserBook `json:"user_books,omitempty" gorm:"many2many:user_book_tags"`
+	User      User       `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TagCreateRequest defines the payload for creating a tag.
+type TagCreateRequest struct {
+	UserID uint   `json:"user_id" validate:"required"`
+	Name   string `json:"name" validate:"required,min=1,max=50"`
+}
+
+// TagUpdateRequest defines the payload for renaming a tag.
+type TagUpdateRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=50"`
+}
+
+// UserBookTagRequest attaches tags to a user book by name; missing tags are created.
+type UserBookTagRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,dive,min=1,max=50"`
+}
@@ -7,21 +7,26 @@ import (
 )
 
 type User struct {
-	ID        uint           `json:"id" gorm:"primarykey"`
-	UID       string         `json:"uid" gorm:"type:uuid;default:gen_random_uuid()"`
-	Name      string         `json:"name" gorm:"type:varchar(255);not null"`
-	Username  string         `json:"username" gorm:"type:varchar(100);uniqueIndex;not null"`
-	Email     string         `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
-	Token     string         `json:"token" gorm:"type:varchar(255)"`
-	Password  string         `json:"-" gorm:"type:varchar(255);not null"`
-	Role      string         `json:"role" gorm:"type:varchar(255)"`
-	UserBooks []UserBook     `json:"user_books" gorm:"foreignKey:UserID"`
-	CreatedAt time.Time      `json:"created_at"`
-	CreatedBy int64          `json:"created_by"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	UpdatedBy int64          `json:"updated_by"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-	DeletedBy int64          `json:"deleted_by"`
+	ID                  uint           `json:"id" gorm:"primarykey"`
+	UID                 string         `json:"uid" gorm:"type:uuid;default:gen_random_uuid()"`
+	Name                string         `json:"name" gorm:"type:varchar(255);not null"`
+	Username            string         `json:"username" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Email               string         `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Token               string         `json:"-" gorm:"type:varchar(255)"`      // Token login aktif, hanya dikirim oleh /login
+	CalendarToken       string         `json:"-" gorm:"type:varchar(64);index"` // Secret untuk feed kalender (.ics)
+	Password            string         `json:"-" gorm:"type:varchar(255);not null"`
+	Role                string         `json:"role" gorm:"type:varchar(255)"`
+	Timezone            string         `json:"timezone" gorm:"type:varchar(64);not null;default:'UTC'"`
+	DailyMinimumPages   int            `json:"daily_minimum_pages" gorm:"not null;default:1"` // Minimum untuk dihitung sebagai hari membaca (streak)
+	DailyMinimumMinutes int            `json:"daily_minimum_minutes" gorm:"not null;default:0"`
+	RestDays            string         `json:"rest_days" gorm:"type:varchar(100)"` // Hari libur membaca, mis. "saturday,sunday"
+	UserBooks           []UserBook     `json:"user_books" gorm:"foreignKey:UserID"`
+	CreatedAt           time.Time      `json:"created_at"`
+	CreatedBy           int64          `json:"created_by"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	UpdatedBy           int64          `json:"updated_by"`
+	DeletedAt           gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	DeletedBy           int64          `json:"deleted_by"`
 }
 
 type UserCreateRequest struct {
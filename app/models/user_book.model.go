@@ -12,6 +12,8 @@ type UserBook struct {
 	Title             string            `json:"title" gorm:"type:varchar(255);not null"`
 	Author            string            `json:"author" gorm:"type:varchar(255);not null"`
 	Publisher         string            `json:"publisher" gorm:"type:varchar(255)"`
+	Genre             string            `json:"genre" gorm:"type:varchar(100)"`
+	ISBN              string            `json:"isbn" gorm:"type:varchar(13);index"` // Selalu disimpan sebagai ISBN-13
 	Cover             string            `json:"cover" gorm:"type:varchar(255)"` // URL atau path ke gambar cover
 	TotalPages        int               `json:"total_pages" gorm:"not null"`
 	CurrentPage       int               `json:"current_page" gorm:"default:0"`
@@ -20,6 +22,8 @@ type UserBook struct {
 	StartDate         time.Time         `json:"start_date" gorm:"not null"`
 	EndDate           time.Time         `json:"end_date"`
 	ReadingActivities []ReadingActivity `json:"reading_activities" gorm:"foreignKey:UserBookID"`
+	Shelves           []Shelf           `json:"shelves,omitempty" gorm:"many2many:user_book_shelves"`
+	Tags              []Tag             `json:"tags,omitempty" gorm:"many2many:user_book_tags"`
 	User              User              `json:"user" gorm:"foreignKey:UserID"`
 	CreatedAt         time.Time         `json:"created_at"`
 	CreatedBy         int64             `json:"created_by"`
@@ -36,6 +40,8 @@ type UserBookCreateRequest struct {
 	Title          string    `json:"title" validate:"required,min=1,max=255"`
 	Author         string    `json:"author" validate:"required,min=1,max=255"`
 	Publisher      string    `json:"publisher,omitempty" validate:"omitempty,max=255"`
+	Genre          string    `json:"genre,omitempty" validate:"omitempty,max=100"`
+	ISBN           string    `json:"isbn,omitempty" validate:"omitempty,isbn"`
 	Cover          string    `json:"cover,omitempty" validate:"omitempty,url,max=255"`
 	TotalPages     int       `json:"total_pages" validate:"required,gt=0"`
 	MotivationRead string    `json:"motivation_read,omitempty"`
@@ -48,6 +54,8 @@ type UserBookUpdateRequest struct {
 	Title          string    `json:"title,omitempty" validate:"omitempty,min=1,max=255"`
 	Author         string    `json:"author,omitempty" validate:"omitempty,min=1,max=255"`
 	Publisher      string    `json:"publisher,omitempty" validate:"omitempty,max=255"`
+	Genre          string    `json:"genre,omitempty" validate:"omitempty,max=100"`
+	ISBN           string    `json:"isbn,omitempty" validate:"omitempty,isbn"`
 	Cover          string    `json:"cover,omitempty" validate:"omitempty,url,max=255"`
 	TotalPages     *int      `json:"total_pages,omitempty" validate:"omitempty,gt=0"` // Pointer to distinguish between 0 and not provided
 	CurrentPage    *int      `json:"current_page,omitempty" validate:"omitempty,gte=0"`
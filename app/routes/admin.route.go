@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/util/jwt"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupAdminRoutes(app *fiber.App, DB *gorm.DB, tokens *jwt.Manager) {
+	logController := controllers.NewLogController()
+
+	// Group routes for /admin, only for users with the admin role
+	adminRoutes := app.Group("/admin", middlewares.AuthJWTMiddleware(DB, tokens), middlewares.RequireRole("admin"))
+
+	adminRoutes.Get("/log-level", logController.GetLogLevel)
+	adminRoutes.Put("/log-level", logController.UpdateLogLevel)
+}
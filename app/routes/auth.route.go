@@ -2,13 +2,14 @@ package routes
 
 import (
 	"ayo-baca-buku/app/controllers"
+	"ayo-baca-buku/app/util/jwt"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-func SetupAuthRoutes(app *fiber.App, DB *gorm.DB) {
-	authController := controllers.NewAuthController(DB)
+func SetupAuthRoutes(app *fiber.App, DB *gorm.DB, tokens *jwt.Manager) {
+	authController := controllers.NewAuthController(DB, tokens)
 
 	app.Post("/login", authController.Login)
 	app.Post("/register", authController.Register)
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupBarcodeRoutes(app *fiber.App, DB *gorm.DB) {
+	barcodeController := controllers.NewBarcodeController(DB)
+
+	// Barcode scans pre-fill the body of POST /userbooks
+	userBookRoutes := app.Group("/userbooks")
+
+	userBookRoutes.Post("/scan", barcodeController.ScanIsbnBarcode) // multipart, fields "image" and optional "user_id"
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupCalendarRoutes(app *fiber.App, me fiber.Router, DB *gorm.DB) {
+	calendarController := controllers.NewCalendarController(DB)
+
+	// The token is the only credential of the feed, only its owner may see or revoke it
+	me.Post("/calendar-token", calendarController.GenerateCalendarToken)
+	me.Delete("/calendar-token", calendarController.RevokeCalendarToken)
+
+	// Public feed, authenticated by the secret token in the URL
+	app.Get("/calendar/:token.ics", calendarController.GetCalendarFeed)
+}
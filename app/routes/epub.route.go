@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+	"ayo-baca-buku/app/util/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupEpubRoutes(app *fiber.App, DB *gorm.DB, files *storage.Local) {
+	epubController := controllers.NewEpubController(DB, files)
+
+	// EPUB uploads live next to the /userbooks routes
+	userBookRoutes := app.Group("/userbooks")
+
+	userBookRoutes.Post("/epub", epubController.CreateUserBookFromEpub) // multipart, fields "file" and "user_id"
+	userBookRoutes.Post("/:id/epub", epubController.UploadUserBookEpub) // multipart, field "file"
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupExportRoutes(me fiber.Router, DB *gorm.DB) {
+	exportController := controllers.NewExportController(DB)
+
+	// me is the /me group, the user comes from the bearer token
+	me.Get("/export", exportController.ExportLibrary) // ?format=csv|json|goodreads
+	me.Get("/export/markdown", exportController.ExportMarkdown)
+	me.Post("/export/markdown", exportController.ExportMarkdown) // custom template
+	me.Get("/export/markdown/template", exportController.GetMarkdownTemplate)
+}
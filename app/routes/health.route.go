@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/config"
+	"ayo-baca-buku/app/controllers"
+	"ayo-baca-buku/app/util/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupHealthRoutes(app *fiber.App, DB *gorm.DB, files *storage.Local, mail config.MailConfig) {
+	healthController := controllers.NewHealthController(DB, files, mail)
+
+	// Probes for the orchestrator, outside of any group and without auth
+	app.Get("/healthz", healthController.Liveness)
+	app.Get("/readyz", healthController.Readiness)
+	app.Get("/version", healthController.GetVersion)
+}
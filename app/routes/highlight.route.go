@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupHighlightRoutes(app *fiber.App, DB *gorm.DB) {
+	highlightController := controllers.NewHighlightController(DB)
+
+	// Group routes for /highlights
+	highlightRoutes := app.Group("/highlights")
+
+	highlightRoutes.Post("/", highlightController.CreateHighlight)
+	highlightRoutes.Get("/", highlightController.GetAllHighlights) // ?user_id=&user_book_id=&kind=&tag=&color=&from=&to=&q=
+	highlightRoutes.Get("/:id", highlightController.GetHighlightByID)
+	highlightRoutes.Put("/:id", highlightController.UpdateHighlight)
+	highlightRoutes.Delete("/:id", highlightController.DeleteHighlight) // Soft delete
+}
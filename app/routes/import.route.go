@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+	"ayo-baca-buku/app/importer"
+	"ayo-baca-buku/app/util/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupImportRoutes(app *fiber.App, DB *gorm.DB, jobs *importer.Runner, files *storage.Local) {
+	importController := controllers.NewImportController(DB, jobs, files)
+
+	// Group routes for /users/:userId/imports
+	importRoutes := app.Group("/users/:userId/imports")
+
+	importRoutes.Get("/", importController.GetImportJobs)
+	importRoutes.Post("/kindle", importController.ImportKindleClippings)      // multipart, field "file"
+	importRoutes.Post("/goodreads", importController.CreateGoodreadsImport)   // multipart, field "file"
+	importRoutes.Post("/storygraph", importController.CreateStoryGraphImport) // multipart, field "file"
+	importRoutes.Post("/calibre", importController.ImportCalibreLibrary)      // multipart, fields "file" and "covers"
+	importRoutes.Get("/:jobId", importController.GetImportJob)
+	importRoutes.Get("/:jobId/errors", importController.GetImportJobErrors)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupReadingGoalRoutes(app *fiber.App, DB *gorm.DB) {
+	readingGoalController := controllers.NewReadingGoalController(DB)
+
+	// Group routes for /goals
+	goalRoutes := app.Group("/goals")
+
+	goalRoutes.Post("/", readingGoalController.CreateReadingGoal)
+	goalRoutes.Get("/", readingGoalController.GetAllReadingGoals) // ?user_id=&state=
+	goalRoutes.Get("/:id", readingGoalController.GetReadingGoalByID)
+	goalRoutes.Put("/:id", readingGoalController.UpdateReadingGoal)
+	goalRoutes.Delete("/:id", readingGoalController.DeleteReadingGoal) // Soft delete
+
+	app.Get("/users/:userId/goals/summary", readingGoalController.GetReadingGoalSummary)
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupReadingPlanRoutes(app *fiber.App, DB *gorm.DB) {
+	readingPlanController := controllers.NewReadingPlanController(DB)
+
+	// A user book has at most one reading plan
+	planRoutes := app.Group("/userbooks/:userBookId/plan")
+
+	planRoutes.Post("/", readingPlanController.CreateReadingPlan)
+	planRoutes.Get("/", readingPlanController.GetReadingPlan)
+	planRoutes.Put("/", readingPlanController.UpdateReadingPlan)
+	planRoutes.Delete("/", readingPlanController.DeleteReadingPlan) // Soft delete
+	planRoutes.Get("/export", readingPlanController.ExportReadingPlan)
+}
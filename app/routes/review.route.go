@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/util/jwt"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupReviewRoutes(app *fiber.App, DB *gorm.DB, tokens *jwt.Manager) {
+	reviewController := controllers.NewReviewController(DB)
+
+	// Only the author writes a review; private reviews are shown to the author only
+	auth := middlewares.AuthJWTMiddleware(DB, tokens)
+	viewer := middlewares.OptionalAuthJWTMiddleware(DB, tokens)
+
+	// A user book has at most one review
+	reviewRoutes := app.Group("/userbooks/:userBookId/review")
+
+	reviewRoutes.Post("/", auth, reviewController.CreateReview)
+	reviewRoutes.Get("/", viewer, reviewController.GetReview)
+	reviewRoutes.Put("/", auth, reviewController.UpdateReview)
+	reviewRoutes.Delete("/", auth, reviewController.DeleteReview) // Soft delete
+	reviewRoutes.Get("/history", viewer, reviewController.GetReviewHistory)
+
+	app.Get("/users/:userId/reviews", viewer, reviewController.GetUserReviews)
+	app.Get("/reviews", reviewController.GetBookReviews)       // ?title=&author=&sort=
+	app.Get("/reviews/rating", reviewController.GetBookRating) // ?title=&author=
+}
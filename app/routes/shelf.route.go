@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/util/jwt"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupShelfRoutes(app *fiber.App, DB *gorm.DB, tokens *jwt.Manager) {
+	shelfController := controllers.NewShelfController(DB)
+
+	// Private shelves are listed only when the owner's token is sent
+	viewer := middlewares.OptionalAuthJWTMiddleware(DB, tokens)
+
+	// Group routes for /shelves
+	shelfRoutes := app.Group("/shelves")
+
+	shelfRoutes.Post("/", shelfController.CreateShelf)
+	shelfRoutes.Get("/", viewer, shelfController.GetAllShelves) // ?user_id=
+	shelfRoutes.Put("/reorder", viewer, shelfController.ReorderShelves)
+	shelfRoutes.Get("/:id", viewer, shelfController.GetShelfByID)
+	shelfRoutes.Put("/:id", shelfController.UpdateShelf)
+	shelfRoutes.Delete("/:id", shelfController.DeleteShelf) // Soft delete
+
+	shelfRoutes.Post("/:id/books", shelfController.AddBookToShelf)
+	shelfRoutes.Put("/:id/books/reorder", shelfController.ReorderShelfBooks)
+	shelfRoutes.Delete("/:id/books/:userBookId", shelfController.RemoveBookFromShelf)
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupStatisticRoutes(app *fiber.App, DB *gorm.DB) {
+	statisticController := controllers.NewStatisticController(DB)
+
+	// Group routes for /statistics
+	statisticRoutes := app.Group("/statistics")
+
+	statisticRoutes.Get("/users/:userId", statisticController.GetUserSummary)
+	statisticRoutes.Get("/users/:userId/pages", statisticController.GetPagesRead)
+	statisticRoutes.Get("/users/:userId/finished", statisticController.GetBooksFinished)
+	statisticRoutes.Get("/users/:userId/breakdown", statisticController.GetBreakdown)
+	statisticRoutes.Get("/userbooks/:userBookId", statisticController.GetUserBookProgress)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupStreakRoutes(app *fiber.App, DB *gorm.DB) {
+	streakController := controllers.NewStreakController(DB)
+
+	// Habit tracking lives under the owning user
+	habitRoutes := app.Group("/users/:userId")
+
+	habitRoutes.Get("/streak", streakController.GetStreak)
+	habitRoutes.Get("/heatmap", streakController.GetHeatmap)
+	habitRoutes.Get("/habit", streakController.GetHabitSetting)
+	habitRoutes.Put("/habit", streakController.UpdateHabitSetting)
+	habitRoutes.Get("/streak-freezes", streakController.GetStreakFreezes)
+	habitRoutes.Post("/streak-freezes", streakController.CreateStreakFreeze)
+	habitRoutes.Delete("/streak-freezes/:freezeId", streakController.DeleteStreakFreeze)
+}
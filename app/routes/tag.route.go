@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"ayo-baca-buku/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupTagRoutes(app *fiber.App, DB *gorm.DB) {
+	tagController := controllers.NewTagController(DB)
+
+	// Group routes for /tags
+	tagRoutes := app.Group("/tags")
+
+	tagRoutes.Post("/", tagController.CreateTag)
+	tagRoutes.Get("/", tagController.GetAllTags) // ?user_id=
+	tagRoutes.Put("/:id", tagController.UpdateTag)
+	tagRoutes.Delete("/:id", tagController.DeleteTag)
+
+	app.Post("/userbooks/:id/tags", tagController.AddUserBookTags)
+	app.Delete("/userbooks/:id/tags/:tagId", tagController.RemoveUserBookTag)
+}
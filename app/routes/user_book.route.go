@@ -2,23 +2,27 @@ package routes
 
 import (
 	"ayo-baca-buku/app/controllers" // Import the actual controllers package
-	// "ayo-baca-buku/app/middlewares" // Placeholder for auth middleware if needed
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/util/jwt"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-func SetupUserBookRoutes(app *fiber.App, DB *gorm.DB) {
+func SetupUserBookRoutes(app *fiber.App, DB *gorm.DB, tokens *jwt.Manager) {
 	// Instantiate the actual UserBookController
 	userBookController := controllers.NewUserBookController(DB)
 
+	// The owner's token shows their private shelves on their books
+	viewer := middlewares.OptionalAuthJWTMiddleware(DB, tokens)
+
 	// Group routes for /userbooks
 	// Apply middleware here if needed, e.g., middlewares.AuthJWTMiddleware()
 	userBookRoutes := app.Group("/userbooks")
 
 	userBookRoutes.Post("/", userBookController.CreateUserBook)
-	userBookRoutes.Get("/", userBookController.GetAllUserBooks)
-	userBookRoutes.Get("/:id", userBookController.GetUserBookByID)
+	userBookRoutes.Get("/", viewer, userBookController.GetAllUserBooks)
+	userBookRoutes.Get("/:id", viewer, userBookController.GetUserBookByID)
 	userBookRoutes.Put("/:id", userBookController.UpdateUserBook)
 	userBookRoutes.Delete("/:id", userBookController.DeleteUserBook) // Soft delete
 }
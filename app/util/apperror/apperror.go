@@ -0,0 +1,213 @@
+// Package apperror defines the errors handlers return to the client. The
+// error handler of the app renders them as RFC 7807 problem details.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code identifies the kind of error for clients, independent of the message.
+type Code string
+
+const (
+	CodeInvalidRequest     Code = "invalid_request"
+	CodeValidation         Code = "validation_failed"
+	CodeUnauthorized       Code = "unauthorized"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeForbidden          Code = "forbidden"
+	CodeNotFound           Code = "not_found"
+	CodeMethodNotAllowed   Code = "method_not_allowed"
+	CodeConflict           Code = "conflict"
+	CodePayloadTooLarge    Code = "payload_too_large"
+	CodeUnavailable        Code = "service_unavailable"
+	CodeInternal           Code = "internal_error"
+)
+
+// FieldError explains why the value of one request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Key     string `json:"key"` // Kunci terjemahan, mis. "validation.required"
+}
+
+// Error is an error with everything needed to answer the request. Message is
+// shown to the user in English; clients translate it with Key.
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+	Key     string
+	Fields  []FieldError
+	// Err is the cause. It is logged, never sent to the client.
+	Err error
+}
+
+// New creates an error with the default translation key of code,
+// "errors.<code>".
+func New(status int, code Code, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+		Key:     "errors." + string(code),
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithKey sets the translation key, e.g. "errors.user_not_found".
+func (e *Error) WithKey(key string) *Error {
+	e.Key = key
+	return e
+}
+
+// WithField adds the reason a request field was rejected.
+func (e *Error) WithField(field, message string) *Error {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message, Key: "validation.invalid"})
+	return e
+}
+
+// WithCause sets the underlying error.
+func (e *Error) WithCause(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// BadRequest is returned for requests that cannot be processed as sent.
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, message)
+}
+
+// Invalid returns a validation error without fields, to collect the field
+// errors of checks the validator cannot do, e.g. of query parameters. Only
+// return it when a field was added.
+func Invalid() *Error {
+	return New(http.StatusBadRequest, CodeValidation, "Validation failed")
+}
+
+// InvalidField is returned when the value of one field is rejected by a check
+// the validator cannot do, e.g. against stored data.
+func InvalidField(field, message string) *Error {
+	return Invalid().WithField(field, message)
+}
+
+// InvalidBody is returned when the request body cannot be parsed.
+func InvalidBody(err error) *Error {
+	return BadRequest("Invalid Request").WithField("body", "Failed to parse request body").WithCause(err)
+}
+
+// Unauthorized is returned when the request is not authenticated.
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// InvalidCredentials is returned for every failed login, so the response does
+// not tell whether the username exists.
+func InvalidCredentials() *Error {
+	return New(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid username or password")
+}
+
+// Forbidden is returned when the user may not do what was requested.
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound is returned when the requested resource does not exist.
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict is returned when the request clashes with the stored data.
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// PayloadTooLarge is returned for uploads above the allowed size.
+func PayloadTooLarge(message string) *Error {
+	return New(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, message)
+}
+
+// Unavailable is returned when a dependency of the request is down or busy.
+func Unavailable(message string) *Error {
+	return New(http.StatusServiceUnavailable, CodeUnavailable, message)
+}
+
+// Internal is returned when the server failed; message must not contain
+// details of the failure.
+func Internal(message string) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message)
+}
+
+// From returns err as an *Error. Errors of other types are internal errors
+// with err as the cause.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(http.StatusText(http.StatusInternalServerError)).WithCause(err)
+}
+
+// Problem is the RFC 7807 problem details object sent for an error, with the
+// code, translation key and field errors as extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	Key       string       `json:"key"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Problem returns the problem details of e for the request path instance.
+func (e *Error) Problem(instance, requestID string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		Key:       e.Key,
+		RequestID: requestID,
+		Errors:    e.Fields,
+	}
+}
+
+// StatusCode returns the code of errors that only have an HTTP status, e.g.
+// the 404 of a path no route matches.
+func StatusCode(status int) Code {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status < http.StatusInternalServerError {
+		return CodeInvalidRequest
+	}
+	return CodeInternal
+}
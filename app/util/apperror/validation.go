@@ -0,0 +1,103 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Validation turns the error of validator.Struct into a bad request listing
+// every rejected field. Other errors, e.g. a nil struct, are internal errors.
+// Field names are the JSON names when the validator comes from
+// validation.New.
+func Validation(err error) *Error {
+	var vErrs validator.ValidationErrors
+	if !errors.As(err, &vErrs) {
+		return Internal("Failed to validate request").WithCause(err)
+	}
+	appErr := Invalid()
+	for _, vErr := range vErrs {
+		appErr.Fields = append(appErr.Fields, FieldError{
+			Field:   vErr.Field(),
+			Message: fieldMessage(vErr),
+			Key:     "validation." + vErr.Tag(),
+		})
+	}
+	return appErr
+}
+
+// fieldMessage explains a failed validation tag in plain English.
+func fieldMessage(vErr validator.FieldError) string {
+	param := vErr.Param()
+	switch vErr.Tag() {
+	case "required", "required_with", "required_without_all", "required_unless":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "datetime":
+		return "must be a date in the format " + param
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(param, " ", ", ")
+	case "eqfield":
+		return "must match " + jsonName(param)
+	case "gtfield":
+		return "must be greater than " + jsonName(param)
+	case "unique":
+		return "must not contain duplicates"
+	case "unique_username":
+		return "is already taken"
+	case "unique_email":
+		return "is already registered"
+	case "isbn":
+		return "must be a valid ISBN-10 or ISBN-13"
+	case "half_star":
+		return "must be between 0.5 and 5 in steps of 0.5"
+	case "timezone":
+		return "must be an IANA time zone, e.g. Asia/Jakarta"
+	case "min", "gte":
+		return bound(vErr.Kind(), "at least", param)
+	case "max", "lte":
+		return bound(vErr.Kind(), "at most", param)
+	case "gt":
+		return bound(vErr.Kind(), "more than", param)
+	case "lt":
+		return bound(vErr.Kind(), "less than", param)
+	case "len":
+		return bound(vErr.Kind(), "exactly", param)
+	}
+	return "is invalid"
+}
+
+// bound describes a size limit: the length of strings, the number of items
+// of lists and the value of numbers.
+func bound(kind reflect.Kind, limit, param string) string {
+	switch kind {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", limit, param)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must contain %s %s items", limit, param)
+	}
+	return fmt.Sprintf("must be %s %s", limit, param)
+}
+
+// jsonName converts the Go field name of a cross-field tag, e.g. StartPage,
+// to its JSON name, start_page.
+func jsonName(field string) string {
+	var name strings.Builder
+	runes := []rune(field)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && !unicode.IsUpper(runes[i-1]) {
+			name.WriteByte('_')
+		}
+		name.WriteRune(unicode.ToLower(r))
+	}
+	return name.String()
+}
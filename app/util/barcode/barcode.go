@@ -0,0 +1,82 @@
+package barcode
+
+import (
+	"ayo-baca-buku/app/util/isbn"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Register the decoders for uploaded photos
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+)
+
+var (
+	// ErrInvalidImage is returned when the upload is not a JPEG, PNG or GIF.
+	ErrInvalidImage = errors.New("file is not a JPEG, PNG or GIF image")
+	// ErrImageTooLarge is returned for images with more than MaxPixels pixels.
+	ErrImageTooLarge = fmt.Errorf("image has more than %d megapixels", MaxPixels/1_000_000)
+	// ErrNotFound is returned when the image contains no readable EAN-13 barcode.
+	ErrNotFound = errors.New("no EAN-13 barcode found")
+)
+
+// MaxPixels limits the size of decoded images; a 48 MP phone photo fits.
+const MaxPixels = 50_000_000
+
+// NotISBNError is returned when the barcode is a valid EAN-13 that is not an
+// ISBN, e.g. the ISSN of a magazine or the product code of a stationery item.
+type NotISBNError struct {
+	EAN string
+}
+
+func (e *NotISBNError) Error() string {
+	return fmt.Sprintf("barcode %s is not an ISBN", e.EAN)
+}
+
+// DecodeImage reads an uploaded image and returns the ISBN-13 of the EAN-13
+// barcode on it.
+func DecodeImage(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return "", ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrInvalidImage
+	}
+	return DecodeISBN(img)
+}
+
+// DecodeISBN finds an EAN-13 barcode in img and returns it when it is a valid
+// ISBN-13. Rows across the whole image are scanned, also with the image turned
+// 90 degrees, so the barcode does not have to be centered or horizontal.
+func DecodeISBN(img image.Image) (string, error) {
+	bitmap, err := gozxing.NewBinaryBitmap(gozxing.NewHybridBinarizer(gozxing.NewLuminanceSourceFromImage(img)))
+	if err != nil {
+		return "", ErrNotFound
+	}
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	result, err := oned.NewEAN13Reader().Decode(bitmap, hints)
+	if err != nil {
+		return "", ErrNotFound
+	}
+
+	ean := result.GetText()
+	if !isbn.ValidISBN13(ean) {
+		return "", &NotISBNError{EAN: ean}
+	}
+	return ean, nil
+}
@@ -0,0 +1,131 @@
+package barcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+)
+
+func whitePage(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+// barcodeImage renders ean as an EAN-13 barcode on a white page of
+// width x height pixels, drawn at (x, y).
+func barcodeImage(t *testing.T, ean string, width, height, x, y int) *image.Gray {
+	t.Helper()
+	matrix, err := oned.NewEAN13Writer().Encode(ean, gozxing.BarcodeFormat_EAN_13, 300, 120, nil)
+	if err != nil {
+		t.Fatalf("encode %s: %v", ean, err)
+	}
+	img := whitePage(width, height)
+	for row := 0; row < matrix.GetHeight(); row++ {
+		for col := 0; col < matrix.GetWidth(); col++ {
+			if matrix.Get(col, row) {
+				img.SetGray(x+col, y+row, color.Gray{})
+			}
+		}
+	}
+	return img
+}
+
+// rotate90 turns img a quarter turn clockwise.
+func rotate90(img *image.Gray) *image.Gray {
+	bounds := img.Bounds()
+	rotated := image.NewGray(image.Rect(0, 0, bounds.Dy(), bounds.Dx()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			rotated.SetGray(bounds.Dy()-1-y, x, img.GrayAt(x, y))
+		}
+	}
+	return rotated
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeISBN(t *testing.T) {
+	tests := []struct {
+		name    string
+		img     image.Image
+		want    string
+		wantEAN string // set when the barcode is not an ISBN
+		wantErr error
+	}{
+		{name: "centered ISBN", img: barcodeImage(t, "9780306406157", 400, 200, 50, 40), want: "9780306406157"},
+		{name: "979 ISBN", img: barcodeImage(t, "9791032305690", 400, 200, 50, 40), want: "9791032305690"},
+		{name: "off-center ISBN", img: barcodeImage(t, "9789793062792", 900, 700, 560, 520), want: "9789793062792"},
+		{name: "rotated ISBN", img: rotate90(barcodeImage(t, "9780804429573", 400, 200, 50, 40)), want: "9780804429573"},
+		{name: "ISSN of a magazine", img: barcodeImage(t, "9771234567003", 400, 200, 50, 40), wantEAN: "9771234567003"},
+		{name: "product barcode", img: barcodeImage(t, "4006381333931", 400, 200, 50, 40), wantEAN: "4006381333931"},
+		{name: "blank page", img: whitePage(400, 200), wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeISBN(tt.img)
+			var notISBN *NotISBNError
+			switch {
+			case tt.wantEAN != "":
+				if !errors.As(err, &notISBN) || notISBN.EAN != tt.wantEAN {
+					t.Fatalf("DecodeISBN() error = %v, want NotISBNError for %s", err, tt.wantEAN)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("DecodeISBN() error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("DecodeISBN() error = %v", err)
+			case got != tt.want:
+				t.Fatalf("DecodeISBN() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeImage(t *testing.T) {
+	photo := encodePNG(t, barcodeImage(t, "9780306406157", 400, 200, 50, 40))
+
+	// A PNG header claiming more than MaxPixels; the pixels are never decoded.
+	huge := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	binary.BigEndian.PutUint32(huge[16:], 10_000)
+	binary.BigEndian.PutUint32(huge[20:], 10_000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{name: "png photo", data: photo, want: "9780306406157"},
+		{name: "not an image", data: []byte("%PDF-1.7"), wantErr: ErrInvalidImage},
+		{name: "too large", data: huge, wantErr: ErrImageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeImage(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("DecodeImage() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
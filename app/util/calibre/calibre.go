@@ -0,0 +1,296 @@
+package calibre
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// ErrNotCalibreLibrary is returned by Read when the file is not a SQLite
+// database with Calibre's tables.
+var ErrNotCalibreLibrary = errors.New("file is not a Calibre metadata.db")
+
+// coverFileName is the name Calibre gives the cover in every book folder.
+const coverFileName = "cover.jpg"
+
+// pageColumnNames are the lookup names and labels of custom columns that hold
+// a page count, e.g. the column created by the Count Pages plugin.
+var pageColumnNames = []string{"pages", "page count", "page_count", "pagecount", "number of pages", "num_pages", "#pages"}
+
+// Book is one entry of a Calibre library.
+type Book struct {
+	ID          int
+	Title       string
+	Authors     []string
+	Publisher   string
+	ISBN        string // As stored by Calibre; not validated
+	Tags        []string
+	Series      string
+	SeriesIndex float64
+	Pages       int // 0 when the library has no page count column
+	AddedAt     time.Time
+	Path        string // Folder of the book relative to the library, e.g. "Andrea Hirata/Laskar Pelangi (12)"
+	HasCover    bool
+}
+
+// Read loads the books of a Calibre metadata.db. The database is opened read-only.
+func Read(dbPath string) ([]Book, error) {
+	if err := checkHeader(dbPath); err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(sqlite.Open("file:"+filepath.ToSlash(dbPath)+"?mode=ro"), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		return nil, fmt.Errorf("open metadata.db: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDB.Close()
+
+	var rows []struct {
+		ID          int
+		Title       string
+		Path        string
+		HasCover    bool
+		Timestamp   string
+		SeriesIndex float64
+		ISBN        string
+	}
+	err = db.Raw(`SELECT id, title, path, has_cover, CAST(timestamp AS TEXT) AS timestamp,
+		coalesce(series_index, 1) AS series_index, coalesce(isbn, '') AS isbn FROM books ORDER BY id`).
+		Scan(&rows).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil, ErrNotCalibreLibrary
+		}
+		return nil, fmt.Errorf("read books: %w", err)
+	}
+
+	authors, err := links(db, `SELECT l.book, a.name FROM books_authors_link l JOIN authors a ON a.id = l.author ORDER BY l.book, l.id`)
+	if err != nil {
+		return nil, fmt.Errorf("read authors: %w", err)
+	}
+	publishers, err := links(db, `SELECT l.book, p.name FROM books_publishers_link l JOIN publishers p ON p.id = l.publisher ORDER BY l.book, l.id`)
+	if err != nil {
+		return nil, fmt.Errorf("read publishers: %w", err)
+	}
+	tags, err := links(db, `SELECT l.book, t.name FROM books_tags_link l JOIN tags t ON t.id = l.tag ORDER BY l.book, t.name`)
+	if err != nil {
+		return nil, fmt.Errorf("read tags: %w", err)
+	}
+	series, err := links(db, `SELECT l.book, s.name FROM books_series_link l JOIN series s ON s.id = l.series ORDER BY l.book`)
+	if err != nil {
+		return nil, fmt.Errorf("read series: %w", err)
+	}
+	isbns, err := links(db, `SELECT book, val FROM identifiers WHERE LOWER(type) = 'isbn' ORDER BY book, id`)
+	if err != nil {
+		return nil, fmt.Errorf("read identifiers: %w", err)
+	}
+	pages, err := pageCounts(db)
+	if err != nil {
+		return nil, fmt.Errorf("read page counts: %w", err)
+	}
+
+	books := make([]Book, 0, len(rows))
+	for _, row := range rows {
+		book := Book{
+			ID:          row.ID,
+			Title:       strings.TrimSpace(row.Title),
+			Authors:     authors[row.ID],
+			Publisher:   first(publishers[row.ID]),
+			ISBN:        strings.TrimSpace(row.ISBN),
+			Tags:        tags[row.ID],
+			Series:      first(series[row.ID]),
+			SeriesIndex: row.SeriesIndex,
+			Pages:       pages[row.ID],
+			AddedAt:     parseTimestamp(row.Timestamp),
+			Path:        row.Path,
+			HasCover:    row.HasCover,
+		}
+		// The identifiers table replaced books.isbn, which is usually empty.
+		if isbn := first(isbns[row.ID]); isbn != "" {
+			book.ISBN = strings.TrimSpace(isbn)
+		}
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+// checkHeader rejects files that are not SQLite databases before the driver
+// tries to open them.
+func checkHeader(dbPath string) error {
+	file, err := os.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header, []byte("SQLite format 3\x00")) {
+		return ErrNotCalibreLibrary
+	}
+	return nil
+}
+
+// links reads (book, value) pairs into values per book, in query order.
+func links(db *gorm.DB, query string) (map[int][]string, error) {
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[int][]string)
+	for rows.Next() {
+		var (
+			book  int
+			value string
+		)
+		if err := rows.Scan(&book, &value); err != nil {
+			return nil, err
+		}
+		values[book] = append(values[book], value)
+	}
+	return values, rows.Err()
+}
+
+// pageCounts reads the page count custom column, if the library has one.
+func pageCounts(db *gorm.DB) (map[int]int, error) {
+	var columns []struct {
+		ID    int
+		Label string
+		Name  string
+	}
+	err := db.Raw(`SELECT id, label, name FROM custom_columns WHERE datatype IN ('int', 'float') ORDER BY id`).Scan(&columns).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return map[int]int{}, nil
+		}
+		return nil, err
+	}
+
+	for _, column := range columns {
+		if !isPageColumn(column.Label) && !isPageColumn(column.Name) {
+			continue
+		}
+		// The table name comes from custom_columns.id, an integer.
+		rows, err := db.Raw(fmt.Sprintf(`SELECT book, value FROM custom_column_%d`, column.ID)).Rows()
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		pages := make(map[int]int)
+		for rows.Next() {
+			var (
+				book  int
+				value float64
+			)
+			if err := rows.Scan(&book, &value); err != nil {
+				return nil, err
+			}
+			if value > 0 {
+				pages[book] = int(math.Round(value))
+			}
+		}
+		return pages, rows.Err()
+	}
+	return map[int]int{}, nil
+}
+
+func isPageColumn(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, candidate := range pageColumnNames {
+		if name == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimestamp parses Calibre's "2006-01-02 15:04:05.999999+00:00" format.
+func parseTimestamp(value string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", "2006-01-02T15:04:05.999999999-07:00", "2006-01-02 15:04:05"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+// Covers opens the cover of a book by its Calibre path. Open returns an error
+// wrapping fs.ErrNotExist when there is no cover.
+type Covers interface {
+	Open(bookPath string) (io.ReadCloser, error)
+}
+
+type dirCovers string
+
+// DirCovers reads covers from a Calibre library folder on disk.
+func DirCovers(libraryDir string) Covers {
+	return dirCovers(libraryDir)
+}
+
+func (d dirCovers) Open(bookPath string) (io.ReadCloser, error) {
+	clean := path.Clean("/" + bookPath)[1:]
+	if clean == "" || clean != bookPath {
+		return nil, fmt.Errorf("invalid book path %q: %w", bookPath, fs.ErrNotExist)
+	}
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(clean), coverFileName))
+}
+
+type zipCovers map[string]*zip.File
+
+// ZipCovers reads covers from a ZIP of a Calibre library folder. The folder
+// may be zipped with or without its own top-level directory.
+func ZipCovers(archive *zip.Reader) Covers {
+	covers := make(zipCovers)
+	for _, file := range archive.File {
+		name := strings.ReplaceAll(file.Name, "\\", "/")
+		if path.Base(name) != coverFileName {
+			continue
+		}
+		covers[bookFolder(path.Dir(name))] = file
+	}
+	return covers
+}
+
+func (z zipCovers) Open(bookPath string) (io.ReadCloser, error) {
+	file, ok := z[bookFolder(bookPath)]
+	if !ok {
+		return nil, fmt.Errorf("cover of %q: %w", bookPath, fs.ErrNotExist)
+	}
+	return file.Open()
+}
+
+// bookFolder keeps the last two elements of a path; Calibre book folders are
+// always "Author/Title (id)", whatever the library folder is called.
+func bookFolder(dir string) string {
+	parts := strings.Split(strings.Trim(dir, "/"), "/")
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,318 @@
+package clippings
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of clipping entries.
+const (
+	KindHighlight = "highlight"
+	KindNote      = "note"
+	KindBookmark  = "bookmark"
+)
+
+// separator ends every entry in My Clippings.txt.
+const separator = "=========="
+
+// Clipping is one entry of a Kindle "My Clippings.txt" file.
+type Clipping struct {
+	Title         string
+	Author        string
+	Kind          string
+	Page          *int
+	LocationStart int // 0 when the entry has no location
+	LocationEnd   int
+	AddedAt       time.Time // zero when the date could not be parsed
+	Text          string
+}
+
+// Location formats the location the way Kindle shows it, e.g. "1234-1240".
+func (c Clipping) Location() string {
+	if c.LocationStart == 0 {
+		return ""
+	}
+	if c.LocationEnd > c.LocationStart {
+		return strconv.Itoa(c.LocationStart) + "-" + strconv.Itoa(c.LocationEnd)
+	}
+	return strconv.Itoa(c.LocationStart)
+}
+
+// Invalid is an entry that could not be parsed.
+type Invalid struct {
+	Entry  int // 1-based position in the file
+	Title  string
+	Reason string
+}
+
+// Keywords recognised in the metadata line, per Kindle language. The metadata
+// line is lower-cased before matching.
+var (
+	highlightWords = []string{"highlight", "markierung", "surlignement", "subrayado", "evidenziazione", "destaque", "markering", "ハイライト", "标注"}
+	noteWords      = []string{"note", "notiz", "nota", "notitie", "メモ", "笔记"}
+	bookmarkWords  = []string{"bookmark", "lesezeichen", "signet", "marcador", "segnalibro", "bladwijzer", "ブックマーク", "书签"}
+	pageWords      = []string{"page", "seite", "página", "pagina", "ページ", "页"}
+	locationWords  = []string{"location", "loc.", "position", "posición", "emplacement", "posizione", "posição", "locatie", "位置"}
+	addedWords     = []string{"added on", "hinzugefügt am", "ajouté le", "añadido el", "aggiunto in data", "adicionado", "toegevoegd op", "作成日", "添加于"}
+)
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January, "januar": time.January, "janvier": time.January, "enero": time.January, "gennaio": time.January, "janeiro": time.January, "januari": time.January,
+	"february": time.February, "feb": time.February, "februar": time.February, "février": time.February, "febrero": time.February, "febbraio": time.February, "fevereiro": time.February, "februari": time.February,
+	"march": time.March, "mar": time.March, "märz": time.March, "mars": time.March, "marzo": time.March, "março": time.March, "maart": time.March,
+	"april": time.April, "apr": time.April, "avril": time.April, "abril": time.April, "aprile": time.April,
+	"may": time.May, "mai": time.May, "mayo": time.May, "maggio": time.May, "maio": time.May, "mei": time.May,
+	"june": time.June, "jun": time.June, "juni": time.June, "juin": time.June, "junio": time.June, "giugno": time.June, "junho": time.June,
+	"july": time.July, "jul": time.July, "juli": time.July, "juillet": time.July, "julio": time.July, "luglio": time.July, "julho": time.July,
+	"august": time.August, "aug": time.August, "août": time.August, "agosto": time.August, "augustus": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September, "septembre": time.September, "septiembre": time.September, "settembre": time.September, "setembro": time.September,
+	"october": time.October, "oct": time.October, "oktober": time.October, "octobre": time.October, "octubre": time.October, "ottobre": time.October, "outubro": time.October,
+	"november": time.November, "nov": time.November, "novembre": time.November, "noviembre": time.November, "novembro": time.November,
+	"december": time.December, "dec": time.December, "dezember": time.December, "décembre": time.December, "diciembre": time.December, "dicembre": time.December, "dezembro": time.December,
+}
+
+var (
+	numberPattern    = regexp.MustCompile(`\d+`)
+	rangePattern     = regexp.MustCompile(`(\d+)(?:\s*-\s*(\d+))?`)
+	clockPattern     = regexp.MustCompile(`(\d{1,2}):(\d{2})(?::(\d{2}))?`)
+	cjkDatePattern   = regexp.MustCompile(`(\d{4})年(\d{1,2})月(\d{1,2})日`)
+	yearPattern      = regexp.MustCompile(`^\d{4}$`)
+	dayPattern       = regexp.MustCompile(`^\d{1,2}$`)
+	wordSplitPattern = regexp.MustCompile(`[\s,.]+`)
+)
+
+// Parse reads a My Clippings.txt file. Dates carry no timezone in the file and
+// are interpreted in loc. Entries that cannot be parsed are returned as Invalid
+// instead of failing the whole file.
+func Parse(r io.Reader, loc *time.Location) ([]Clipping, []Invalid, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var (
+		result  []Clipping
+		invalid []Invalid
+		lines   []string
+		entry   int
+	)
+	flush := func() {
+		entry++
+		clipping, err := parseEntry(lines, loc)
+		if err != nil {
+			title := ""
+			if len(lines) > 0 {
+				title = strings.TrimSpace(lines[0])
+			}
+			invalid = append(invalid, Invalid{Entry: entry, Title: title, Reason: err.Error()})
+		} else {
+			result = append(result, clipping)
+		}
+		lines = lines[:0]
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		line = strings.TrimPrefix(line, "\ufeff")
+		if strings.TrimSpace(line) == separator {
+			flush()
+			continue
+		}
+		if len(lines) == 0 && strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	// A file may lack the final separator.
+	if len(lines) > 0 {
+		flush()
+	}
+	return result, invalid, nil
+}
+
+func parseEntry(lines []string, loc *time.Location) (Clipping, error) {
+	if len(lines) < 2 {
+		return Clipping{}, errors.New("entry is incomplete")
+	}
+
+	var clipping Clipping
+	clipping.Title, clipping.Author = parseTitleLine(lines[0])
+	if clipping.Title == "" {
+		return Clipping{}, errors.New("entry has no title")
+	}
+
+	meta := strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(lines[1]), "-")))
+	segments := strings.Split(meta, "|")
+
+	// The type is not always in the first segment (Japanese puts the page first).
+	clipping.Kind = detectKind(meta)
+	if clipping.Kind == "" {
+		return Clipping{}, errors.New("unknown clipping type")
+	}
+
+	for _, segment := range segments {
+		switch {
+		case containsAny(segment, addedWords):
+			clipping.AddedAt = parseDate(segment, loc)
+		case containsAny(segment, locationWords):
+			if match := rangePattern.FindStringSubmatch(numbersNear(segment, locationWords)); match != nil {
+				clipping.LocationStart, _ = strconv.Atoi(match[1])
+				clipping.LocationEnd = expandRangeEnd(match[1], match[2])
+			}
+		case containsAny(segment, pageWords):
+			if match := numberPattern.FindString(numbersNear(segment, pageWords)); match != "" {
+				page, _ := strconv.Atoi(match)
+				clipping.Page = &page
+			}
+		}
+	}
+
+	clipping.Text = strings.TrimSpace(strings.Join(lines[2:], "\n"))
+	if clipping.Kind != KindBookmark && clipping.Text == "" {
+		return Clipping{}, errors.New("entry has no text")
+	}
+	return clipping, nil
+}
+
+// parseTitleLine splits "Title (Author)" on the last parenthesised group.
+func parseTitleLine(line string) (string, string) {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+	if !strings.HasSuffix(line, ")") {
+		return line, ""
+	}
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				title := strings.TrimSpace(line[:i])
+				if title == "" {
+					return line, ""
+				}
+				return title, strings.TrimSpace(line[i+1 : len(line)-1])
+			}
+		}
+	}
+	return line, ""
+}
+
+func detectKind(segment string) string {
+	switch {
+	case containsAny(segment, bookmarkWords):
+		return KindBookmark
+	case containsAny(segment, highlightWords):
+		return KindHighlight
+	case containsAny(segment, noteWords):
+		return KindNote
+	}
+	return ""
+}
+
+func containsAny(value string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(value, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// afterKeyword returns the part of segment after the first keyword found, so
+// that numbers in the clipping type (e.g. "page 12 | location") are not mixed up.
+func afterKeyword(segment string, words []string) string {
+	for _, word := range words {
+		if i := strings.Index(segment, word); i >= 0 {
+			return segment[i+len(word):]
+		}
+	}
+	return segment
+}
+
+// numbersNear returns the text after the keyword, or the whole segment when the
+// number precedes the keyword as in Japanese ("12ページ") or Chinese ("第 12 页").
+func numbersNear(segment string, words []string) string {
+	if after := afterKeyword(segment, words); numberPattern.MatchString(after) {
+		return after
+	}
+	return segment
+}
+
+// expandRangeEnd turns Kindle's abbreviated ranges such as "1234-40" into 1240.
+func expandRangeEnd(start, end string) int {
+	if end == "" {
+		value, _ := strconv.Atoi(start)
+		return value
+	}
+	if len(end) < len(start) {
+		end = start[:len(start)-len(end)] + end
+	}
+	value, _ := strconv.Atoi(end)
+	return value
+}
+
+// parseDate understands the date styles of the Kindle languages by looking for
+// the parts (year, month name, day, time) rather than matching fixed layouts.
+func parseDate(segment string, loc *time.Location) time.Time {
+	value := afterKeyword(segment, addedWords)
+
+	var (
+		year, day int
+		month     time.Month
+	)
+	if match := cjkDatePattern.FindStringSubmatch(value); match != nil {
+		year, _ = strconv.Atoi(match[1])
+		monthNumber, _ := strconv.Atoi(match[2])
+		month = time.Month(monthNumber)
+		day, _ = strconv.Atoi(match[3])
+		value = strings.Replace(value, match[0], " ", 1)
+	}
+
+	hour, minute, second := 0, 0, 0
+	clock := clockPattern.FindStringSubmatchIndex(value)
+	if clock != nil {
+		hour, _ = strconv.Atoi(value[clock[2]:clock[3]])
+		minute, _ = strconv.Atoi(value[clock[4]:clock[5]])
+		if clock[6] >= 0 {
+			second, _ = strconv.Atoi(value[clock[6]:clock[7]])
+		}
+		rest := strings.ReplaceAll(value[clock[1]:], ".", "")
+		pm := strings.Contains(rest, "pm") || strings.Contains(value, "下午") || strings.Contains(value, "午後")
+		am := strings.Contains(rest, "am") || strings.Contains(value, "上午") || strings.Contains(value, "午前")
+		if pm && hour < 12 {
+			hour += 12
+		}
+		if am && hour == 12 {
+			hour = 0
+		}
+		value = value[:clock[0]]
+	}
+
+	if year == 0 {
+		for _, word := range wordSplitPattern.Split(value, -1) {
+			switch {
+			case yearPattern.MatchString(word):
+				year, _ = strconv.Atoi(word)
+			case dayPattern.MatchString(word) && day == 0:
+				day, _ = strconv.Atoi(word)
+			default:
+				if m, ok := months[word]; ok && month == 0 {
+					month = m
+				}
+			}
+		}
+	}
+
+	if year == 0 || month == 0 || day < 1 || day > 31 {
+		return time.Time{}
+	}
+	return time.Date(year, month, day, hour, minute, second, 0, loc)
+}
@@ -0,0 +1,287 @@
+package clippings
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestParse(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		name string
+		file string
+		want Clipping
+	}{
+		{
+			name: "english highlight",
+			file: "\ufeffThe Pragmatic Programmer (Hunt, Andrew;Thomas, David)\r\n" +
+				"- Your Highlight on page 42 | Location 635-637 | Added on Sunday, March 3, 2019 9:15:42 PM\r\n" +
+				"\r\n" +
+				"Don't live with broken windows.\r\n" +
+				"==========\r\n",
+			want: Clipping{
+				Title:         "The Pragmatic Programmer",
+				Author:        "Hunt, Andrew;Thomas, David",
+				Kind:          KindHighlight,
+				Page:          intPtr(42),
+				LocationStart: 635,
+				LocationEnd:   637,
+				AddedAt:       time.Date(2019, time.March, 3, 21, 15, 42, 0, jakarta),
+				Text:          "Don't live with broken windows.",
+			},
+		},
+		{
+			name: "english note without page",
+			file: "Laskar Pelangi (Andrea Hirata)\n" +
+				"- Your Note on Location 1520 | Added on Tuesday, 14 January 2020 00:05:09\n" +
+				"\n" +
+				"Bab favorit.\n" +
+				"==========\n",
+			want: Clipping{
+				Title:         "Laskar Pelangi",
+				Author:        "Andrea Hirata",
+				Kind:          KindNote,
+				LocationStart: 1520,
+				LocationEnd:   1520,
+				AddedAt:       time.Date(2020, time.January, 14, 0, 5, 9, 0, jakarta),
+				Text:          "Bab favorit.",
+			},
+		},
+		{
+			name: "english bookmark has no text",
+			file: "Bumi Manusia (Pramoedya Ananta Toer)\n" +
+				"- Your Bookmark on page 7 | Location 98 | Added on Friday, June 5, 2020 12:30:00 AM\n" +
+				"\n" +
+				"\n" +
+				"==========\n",
+			want: Clipping{
+				Title:         "Bumi Manusia",
+				Author:        "Pramoedya Ananta Toer",
+				Kind:          KindBookmark,
+				Page:          intPtr(7),
+				LocationStart: 98,
+				LocationEnd:   98,
+				AddedAt:       time.Date(2020, time.June, 5, 0, 30, 0, 0, jakarta),
+			},
+		},
+		{
+			name: "old kindle abbreviated location range",
+			file: "Walden (Thoreau, Henry David)\n" +
+				"- Highlight Loc. 1234-40  | Added on Friday, April 01, 2011, 09:12 PM\n" +
+				"\n" +
+				"I went to the woods because I wished to live deliberately.\n" +
+				"==========\n",
+			want: Clipping{
+				Title:         "Walden",
+				Author:        "Thoreau, Henry David",
+				Kind:          KindHighlight,
+				LocationStart: 1234,
+				LocationEnd:   1240,
+				AddedAt:       time.Date(2011, time.April, 1, 21, 12, 0, 0, jakarta),
+				Text:          "I went to the woods because I wished to live deliberately.",
+			},
+		},
+		{
+			name: "german highlight",
+			file: "Der Process (Kafka, Franz)\n" +
+				"- Ihre Markierung auf Seite 12 | Position 170-172 | Hinzugefügt am Montag, 4. März 2019 um 08:03:11\n" +
+				"\n" +
+				"Jemand mußte Josef K. verleumdet haben.\n" +
+				"==========\n",
+			want: Clipping{
+				Title:         "Der Process",
+				Author:        "Kafka, Franz",
+				Kind:          KindHighlight,
+				Page:          intPtr(12),
+				LocationStart: 170,
+				LocationEnd:   172,
+				AddedAt:       time.Date(2019, time.March, 4, 8, 3, 11, 0, jakarta),
+				Text:          "Jemand mußte Josef K. verleumdet haben.",
+			},
+		},
+		{
+			name: "french note",
+			file: "L'Étranger (Albert Camus)\n" +
+				"- Votre note sur la page 8 | emplacement 110 | Ajouté le mercredi 6 mars 2019 22:14:09\n" +
+				"\n" +
+				"Aujourd'hui, maman est morte.\n" +
+				"==========\n",
+			want: Clipping{
+				Title:         "L'Étranger",
+				Author:        "Albert Camus",
+				Kind:          KindNote,
+				Page:          intPtr(8),
+				LocationStart: 110,
+				LocationEnd:   110,
+				AddedAt:       time.Date(2019, time.March, 6, 22, 14, 9, 0, jakarta),
+				Text:          "Aujourd'hui, maman est morte.",
+			},
+		},
+		{
+			name: "spanish highlight",
+			file: "Cien años de soledad (García Márquez, Gabriel)\n" +
+				"- Tu subrayado en la página 5 | posición 70-71 | Añadido el lunes, 4 de marzo de 2019 10:20:31\n" +
+				"\n" +
+				"Muchos años después, frente al pelotón de fusilamiento...\n" +
+				"==========\n",
+			want: Clipping{
+				Title:         "Cien años de soledad",
+				Author:        "García Márquez, Gabriel",
+				Kind:          KindHighlight,
+				Page:          intPtr(5),
+				LocationStart: 70,
+				LocationEnd:   71,
+				AddedAt:       time.Date(2019, time.March, 4, 10, 20, 31, 0, jakarta),
+				Text:          "Muchos años después, frente al pelotón de fusilamiento...",
+			},
+		},
+		{
+			name: "japanese highlight",
+			file: "ノルウェイの森 (村上春樹)\n" +
+				"- 12ページ|位置No. 170-172のハイライト |作成日: 2019年3月4日月曜日 8:03:11\n" +
+				"\n" +
+				"僕は三十七歳で、そのときボーイング747のシートに座っていた。\n" +
+				"==========\n",
+			want: Clipping{
+				Title:         "ノルウェイの森",
+				Author:        "村上春樹",
+				Kind:          KindHighlight,
+				Page:          intPtr(12),
+				LocationStart: 170,
+				LocationEnd:   172,
+				AddedAt:       time.Date(2019, time.March, 4, 8, 3, 11, 0, jakarta),
+				Text:          "僕は三十七歳で、そのときボーイング747のシートに座っていた。",
+			},
+		},
+		{
+			name: "title with parentheses and multi-line text",
+			file: "Dune (Dune Chronicles, Book 1) (Herbert, Frank)\n" +
+				"- Your Highlight on Location 2070-2071 | Added on Saturday, 2 May 2020 18:00:00\n" +
+				"\n" +
+				"I must not fear.\n" +
+				"Fear is the mind-killer.\n",
+			want: Clipping{
+				Title:         "Dune (Dune Chronicles, Book 1)",
+				Author:        "Herbert, Frank",
+				Kind:          KindHighlight,
+				LocationStart: 2070,
+				LocationEnd:   2071,
+				AddedAt:       time.Date(2020, time.May, 2, 18, 0, 0, 0, jakarta),
+				Text:          "I must not fear.\nFear is the mind-killer.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, invalid, err := Parse(strings.NewReader(tt.file), jakarta)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(invalid) != 0 {
+				t.Fatalf("Parse() invalid = %+v, want none", invalid)
+			}
+			if len(got) != 1 {
+				t.Fatalf("Parse() returned %d clippings, want 1", len(got))
+			}
+			assertClipping(t, got[0], tt.want)
+		})
+	}
+}
+
+func assertClipping(t *testing.T, got, want Clipping) {
+	t.Helper()
+	if got.Title != want.Title || got.Author != want.Author || got.Kind != want.Kind {
+		t.Errorf("title, author, kind = %q, %q, %q, want %q, %q, %q", got.Title, got.Author, got.Kind, want.Title, want.Author, want.Kind)
+	}
+	switch {
+	case got.Page == nil && want.Page != nil, got.Page != nil && want.Page == nil:
+		t.Errorf("page = %v, want %v", got.Page, want.Page)
+	case got.Page != nil && *got.Page != *want.Page:
+		t.Errorf("page = %d, want %d", *got.Page, *want.Page)
+	}
+	if got.LocationStart != want.LocationStart || got.LocationEnd != want.LocationEnd {
+		t.Errorf("location = %d-%d, want %d-%d", got.LocationStart, got.LocationEnd, want.LocationStart, want.LocationEnd)
+	}
+	if !got.AddedAt.Equal(want.AddedAt) {
+		t.Errorf("added at = %v, want %v", got.AddedAt, want.AddedAt)
+	}
+	if got.Text != want.Text {
+		t.Errorf("text = %q, want %q", got.Text, want.Text)
+	}
+}
+
+func TestParseInvalidEntries(t *testing.T) {
+	file := "Sapiens (Harari, Yuval Noah)\n" +
+		"- Your Highlight on page 3 | Location 40-41 | Added on Monday, 1 July 2019 07:00:00\n" +
+		"\n" +
+		"Kita adalah makhluk pencerita.\n" +
+		"==========\n" +
+		"Sapiens (Harari, Yuval Noah)\n" +
+		"==========\n" +
+		"Sapiens (Harari, Yuval Noah)\n" +
+		"- Your Clip on page 4 | Added on Monday, 1 July 2019 07:01:00\n" +
+		"\n" +
+		"Unknown type.\n" +
+		"==========\n" +
+		"Sapiens (Harari, Yuval Noah)\n" +
+		"- Your Highlight on page 5 | Added on Monday, 1 July 2019 07:02:00\n" +
+		"\n" +
+		"\n" +
+		"==========\n"
+
+	got, invalid, err := Parse(strings.NewReader(file), time.UTC)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Text != "Kita adalah makhluk pencerita." {
+		t.Fatalf("Parse() = %+v, want only the first entry", got)
+	}
+
+	want := []Invalid{
+		{Entry: 2, Title: "Sapiens (Harari, Yuval Noah)", Reason: "entry is incomplete"},
+		{Entry: 3, Title: "Sapiens (Harari, Yuval Noah)", Reason: "unknown clipping type"},
+		{Entry: 4, Title: "Sapiens (Harari, Yuval Noah)", Reason: "entry has no text"},
+	}
+	if len(invalid) != len(want) {
+		t.Fatalf("Parse() invalid = %+v, want %+v", invalid, want)
+	}
+	for i := range want {
+		if invalid[i] != want[i] {
+			t.Errorf("invalid[%d] = %+v, want %+v", i, invalid[i], want[i])
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		segment string
+		want    time.Time
+	}{
+		{" added on sunday, march 3, 2019 9:15:42 pm", time.Date(2019, time.March, 3, 21, 15, 42, 0, time.UTC)},
+		{" added on friday, april 01, 2011, 12:05 am", time.Date(2011, time.April, 1, 0, 5, 0, 0, time.UTC)},
+		{" added on friday, april 01, 2011, 12:05 pm", time.Date(2011, time.April, 1, 12, 5, 0, 0, time.UTC)},
+		{" added on tuesday, 14 january 2020 00:05:09", time.Date(2020, time.January, 14, 0, 5, 9, 0, time.UTC)},
+		{" hinzugefügt am montag, 4. märz 2019 um 08:03:11", time.Date(2019, time.March, 4, 8, 3, 11, 0, time.UTC)},
+		{" ajouté le mercredi 6 mars 2019 22:14:09", time.Date(2019, time.March, 6, 22, 14, 9, 0, time.UTC)},
+		{" añadido el lunes, 4 de marzo de 2019 10:20:31", time.Date(2019, time.March, 4, 10, 20, 31, 0, time.UTC)},
+		{" aggiunto in data lunedì 4 marzo 2019 10:20:31", time.Date(2019, time.March, 4, 10, 20, 31, 0, time.UTC)},
+		{" toegevoegd op maandag 4 maart 2019 10:20:31", time.Date(2019, time.March, 4, 10, 20, 31, 0, time.UTC)},
+		{"作成日: 2019年3月4日月曜日 午後8:03:11", time.Date(2019, time.March, 4, 20, 3, 11, 0, time.UTC)},
+		{"添加于 2019年3月4日星期一 上午8:03:11", time.Date(2019, time.March, 4, 8, 3, 11, 0, time.UTC)},
+		{" added on someday", time.Time{}},
+		{" added on march 2019", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.segment, func(t *testing.T) {
+			if got := parseDate(tt.segment, time.UTC); !got.Equal(tt.want) {
+				t.Errorf("parseDate(%q) = %v, want %v", tt.segment, got, tt.want)
+			}
+		})
+	}
+}
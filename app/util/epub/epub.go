@@ -0,0 +1,362 @@
+package epub
+
+import (
+	"archive/zip"
+	"ayo-baca-buku/app/util/isbn"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidEPUB is returned by Parse when the file is not an EPUB.
+var ErrInvalidEPUB = errors.New("file is not a valid EPUB")
+
+// CharsPerPage is the number of non-space characters counted as one printed
+// page when a book has no page count. It matches roughly 275 words of prose.
+const CharsPerPage = 1500
+
+// Limits that protect against ZIP bombs: files inside an EPUB are small.
+const (
+	maxDocumentSize = 10 << 20
+	maxCoverSize    = 10 << 20
+	maxContentSize  = 200 << 20
+)
+
+// Book is the metadata read from an EPUB's OPF package.
+type Book struct {
+	Title          string   `json:"title"`
+	Authors        []string `json:"authors"`
+	Publisher      string   `json:"publisher"`
+	ISBN           string   `json:"isbn"` // ISBN-13, empty when the book has no valid ISBN
+	Identifiers    []string `json:"identifiers"`
+	Language       string   `json:"language"`
+	Subjects       []string `json:"subjects"`
+	SpineLength    int      `json:"spine_length"`  // Number of documents in reading order
+	ContentChars   int      `json:"content_chars"` // Non-space characters in the spine documents
+	Pages          int      `json:"pages"`
+	PagesEstimated bool     `json:"pages_estimated"` // Pages was calculated from ContentChars
+	Cover          []byte   `json:"-"`
+	CoverMediaType string   `json:"cover_media_type,omitempty"`
+}
+
+// CoverExtension returns the file extension for the cover image, e.g. ".jpg".
+func (b *Book) CoverExtension() string {
+	switch b.CoverMediaType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+type container struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type opfMeta struct {
+	Name     string `xml:"name,attr"`
+	Content  string `xml:"content,attr"`
+	Property string `xml:"property,attr"`
+	Refines  string `xml:"refines,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type opfCreator struct {
+	ID    string `xml:"id,attr"`
+	Role  string `xml:"role,attr"` // opf:role in EPUB 2
+	Value string `xml:",chardata"`
+}
+
+type opfItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+type opfPackage struct {
+	Metadata struct {
+		Titles      []string     `xml:"title"`
+		Creators    []opfCreator `xml:"creator"`
+		Publishers  []string     `xml:"publisher"`
+		Identifiers []string     `xml:"identifier"`
+		Languages   []string     `xml:"language"`
+		Subjects    []string     `xml:"subject"`
+		Metas       []opfMeta    `xml:"meta"`
+	} `xml:"metadata"`
+	Items []opfItem `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"` // NCX item of EPUB 2
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+var (
+	scriptPattern   = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)>`)
+	tagPattern      = regexp.MustCompile(`(?s)<[^>]*>`)
+	pageListPattern = regexp.MustCompile(`(?is)<nav\b[^>]*epub:type\s*=\s*"[^"]*\bpage-list\b[^"]*"[^>]*>(.*?)</nav>`)
+	anchorPattern   = regexp.MustCompile(`(?i)<a\b`)
+	ncxPagePattern  = regexp.MustCompile(`(?i)<pageTarget\b`)
+)
+
+// Parse reads the package metadata, cover and content length of an EPUB.
+func Parse(r io.ReaderAt, size int64) (*Book, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidEPUB
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var meta container
+	if err := readXML(files, "META-INF/container.xml", &meta); err != nil || len(meta.Rootfiles) == 0 {
+		return nil, ErrInvalidEPUB
+	}
+	opfPath := meta.Rootfiles[0].FullPath
+	for _, rootfile := range meta.Rootfiles {
+		if rootfile.MediaType == "application/oebps-package+xml" {
+			opfPath = rootfile.FullPath
+			break
+		}
+	}
+
+	var pkg opfPackage
+	if err := readXML(files, opfPath, &pkg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEPUB, err)
+	}
+
+	book := &Book{
+		Title:       first(pkg.Metadata.Titles),
+		Authors:     authors(pkg.Metadata.Creators, pkg.Metadata.Metas),
+		Publisher:   first(pkg.Metadata.Publishers),
+		Language:    first(pkg.Metadata.Languages),
+		SpineLength: len(pkg.Spine.ItemRefs),
+	}
+	for _, identifier := range pkg.Metadata.Identifiers {
+		identifier = strings.TrimSpace(identifier)
+		book.Identifiers = append(book.Identifiers, identifier)
+		if book.ISBN == "" {
+			book.ISBN, _ = isbn.Normalize(strings.TrimPrefix(strings.ToLower(identifier), "urn:isbn:"))
+		}
+	}
+	for _, subject := range pkg.Metadata.Subjects {
+		if subject = strings.TrimSpace(subject); subject != "" {
+			book.Subjects = append(book.Subjects, subject)
+		}
+	}
+
+	base := path.Dir(opfPath)
+	items := make(map[string]opfItem, len(pkg.Items))
+	for _, item := range pkg.Items {
+		items[item.ID] = item
+	}
+
+	if cover, ok := coverItem(pkg, items); ok {
+		// SVG covers are skipped: they can carry scripts and are served as-is.
+		data, err := readFile(files, resolve(base, cover.Href), maxCoverSize)
+		if err == nil && cover.MediaType != "image/svg+xml" {
+			book.Cover, book.CoverMediaType = data, cover.MediaType
+		}
+	}
+
+	total := 0
+	for _, ref := range pkg.Spine.ItemRefs {
+		item, ok := items[ref.IDRef]
+		if !ok {
+			continue
+		}
+		data, err := readFile(files, resolve(base, item.Href), maxDocumentSize)
+		if err != nil {
+			continue
+		}
+		if total += len(data); total > maxContentSize {
+			break
+		}
+		book.ContentChars += countChars(data)
+	}
+
+	book.Pages = pageCount(pkg, items, files, base)
+	if book.Pages == 0 && book.ContentChars > 0 {
+		book.Pages = (book.ContentChars + CharsPerPage - 1) / CharsPerPage
+		book.PagesEstimated = true
+	}
+	return book, nil
+}
+
+// authors returns the creators with the author role. Creators without a role
+// are authors too, as most EPUBs do not set one.
+func authors(creators []opfCreator, metas []opfMeta) []string {
+	roles := make(map[string]string)
+	for _, meta := range metas {
+		if meta.Property == "role" && strings.HasPrefix(meta.Refines, "#") {
+			roles[meta.Refines[1:]] = strings.TrimSpace(meta.Value)
+		}
+	}
+
+	var names []string
+	for _, creator := range creators {
+		role := creator.Role
+		if role == "" {
+			role = roles[creator.ID]
+		}
+		name := strings.Join(strings.Fields(creator.Value), " ")
+		if name != "" && (role == "" || role == "aut") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// coverItem finds the cover image: the EPUB 3 cover-image property, the EPUB 2
+// cover meta, or an image whose ID or file name says cover.
+func coverItem(pkg opfPackage, items map[string]opfItem) (opfItem, bool) {
+	for _, item := range pkg.Items {
+		if hasProperty(item.Properties, "cover-image") {
+			return item, true
+		}
+	}
+	for _, meta := range pkg.Metadata.Metas {
+		if meta.Name == "cover" {
+			if item, ok := items[meta.Content]; ok && strings.HasPrefix(item.MediaType, "image/") {
+				return item, true
+			}
+		}
+	}
+	for _, item := range pkg.Items {
+		if strings.HasPrefix(item.MediaType, "image/") &&
+			(strings.Contains(strings.ToLower(item.ID), "cover") || strings.Contains(strings.ToLower(path.Base(item.Href)), "cover")) {
+			return item, true
+		}
+	}
+	return opfItem{}, false
+}
+
+// pageCount returns the number of print pages the publisher recorded in the
+// page list of the navigation document or NCX, or in schema:numberOfPages.
+// It is 0 when the EPUB has none.
+func pageCount(pkg opfPackage, items map[string]opfItem, files map[string]*zip.File, base string) int {
+	for _, item := range pkg.Items {
+		if !hasProperty(item.Properties, "nav") {
+			continue
+		}
+		if data, err := readFile(files, resolve(base, item.Href), maxDocumentSize); err == nil {
+			if match := pageListPattern.FindSubmatch(data); match != nil {
+				if count := len(anchorPattern.FindAll(match[1], -1)); count > 0 {
+					return count
+				}
+			}
+		}
+	}
+	if ncx, ok := items[pkg.Spine.Toc]; ok {
+		if data, err := readFile(files, resolve(base, ncx.Href), maxDocumentSize); err == nil {
+			if count := len(ncxPagePattern.FindAll(data, -1)); count > 0 {
+				return count
+			}
+		}
+	}
+	for _, meta := range pkg.Metadata.Metas {
+		if meta.Property == "schema:numberOfPages" {
+			if pages, err := strconv.Atoi(strings.TrimSpace(meta.Value)); err == nil && pages > 0 {
+				return pages
+			}
+		}
+	}
+	return 0
+}
+
+// countChars counts the non-space characters of the text in an XHTML document.
+func countChars(document []byte) int {
+	text := scriptPattern.ReplaceAll(document, nil)
+	text = tagPattern.ReplaceAll(text, []byte(" "))
+	count := 0
+	for _, r := range html.UnescapeString(string(text)) {
+		if !unicode.IsSpace(r) {
+			count++
+		}
+	}
+	return count
+}
+
+func hasProperty(properties, name string) bool {
+	for _, property := range strings.Fields(properties) {
+		if property == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve turns an href from the OPF into a path inside the archive.
+func resolve(base, href string) string {
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	if i := strings.IndexByte(href, '#'); i >= 0 {
+		href = href[:i]
+	}
+	return path.Clean(path.Join(base, href))
+}
+
+func readXML(files map[string]*zip.File, name string, v interface{}) error {
+	data, err := readFile(files, name, maxDocumentSize)
+	if err != nil {
+		return err
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// EPUBs are XML, but HTML entities such as &nbsp; are common.
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder.Decode(v)
+}
+
+func readFile(files map[string]*zip.File, name string, limit int64) ([]byte, error) {
+	file, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing", name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	return data, nil
+}
+
+func first(values []string) string {
+	for _, value := range values {
+		if value = strings.Join(strings.Fields(value), " "); value != "" {
+			return value
+		}
+	}
+	return ""
+}
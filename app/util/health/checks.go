@@ -0,0 +1,64 @@
+package health
+
+import (
+	"ayo-baca-buku/app/config"
+	"ayo-baca-buku/app/database"
+	"ayo-baca-buku/app/util/storage"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Database pings the database.
+func Database(DB *gorm.DB) Check {
+	return Check{Name: "database", Run: func(ctx context.Context) error {
+		sqlDB, err := DB.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}}
+}
+
+// Migrations fails while the database schema differs from the migrations of
+// this build.
+func Migrations(DB *gorm.DB) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) error {
+		return database.CheckSchema(DB.WithContext(ctx))
+	}}
+}
+
+// Storage fails when files cannot be written to the upload directory.
+func Storage(files *storage.Local) Check {
+	return Check{Name: "storage", Run: func(ctx context.Context) error {
+		return files.Check()
+	}}
+}
+
+// Mail connects to the SMTP server and waits for its greeting. It is disabled
+// when no SMTP server is configured.
+func Mail(mail config.MailConfig) Check {
+	return Check{Name: "mail", Run: func(ctx context.Context) error {
+		if !mail.Enabled() {
+			return ErrDisabled
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(mail.Host, strconv.Itoa(mail.Port)))
+		if err != nil {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		client, err := smtp.NewClient(conn, mail.Host)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("smtp greeting: %w", err)
+		}
+		return client.Quit()
+	}}
+}
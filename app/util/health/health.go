@@ -0,0 +1,96 @@
+// Package health runs the readiness checks of the server: the database, its
+// schema and the storage and mail backends.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Status of a check or of a whole report.
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDisabled = "disabled"
+)
+
+// ErrDisabled is returned by a check whose backend is not configured. It does
+// not fail the report.
+var ErrDisabled = errors.New("not configured")
+
+// Check is one dependency the server needs to serve requests.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all checks. Status is StatusFail when any check
+// failed.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Run runs the checks in parallel. A check that has not finished after
+// timeout fails with context.DeadlineExceeded.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := run(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status == StatusFail {
+				report.Status = StatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+// run runs one check, giving up when ctx is done even if the check does not
+// watch ctx itself.
+func run(ctx context.Context, check Check) Result {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Status: StatusOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	switch {
+	case errors.Is(err, ErrDisabled):
+		result.Status = StatusDisabled
+	case err != nil:
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
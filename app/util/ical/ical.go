@@ -0,0 +1,126 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Calendar is a minimal RFC 5545 VCALENDAR containing VEVENT components.
+type Calendar struct {
+	ProdID      string
+	Name        string
+	Description string
+	Events      []Event
+}
+
+// Event is a VEVENT. All-day events use DATE values and an exclusive DTEND on
+// the following day; other events are written in UTC.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Categories  []string
+	Start       time.Time
+	End         time.Time // optional; defaults to one day after Start for all-day events
+	AllDay      bool
+	Stamp       time.Time // DTSTAMP, defaults to the time of writing
+}
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Write serialises the calendar with CRLF line endings and folded content lines.
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	now := time.Now().UTC()
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:"+escapeText(c.ProdID))
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeLine(bw, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+	if c.Description != "" {
+		writeLine(bw, "X-WR-CALDESC:"+escapeText(c.Description))
+	}
+
+	for _, event := range c.Events {
+		stamp := event.Stamp
+		if stamp.IsZero() {
+			stamp = now
+		}
+
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+escapeText(event.UID))
+		writeLine(bw, "DTSTAMP:"+stamp.UTC().Format(dateTimeLayout))
+		if event.AllDay {
+			end := event.End
+			if end.IsZero() || !end.After(event.Start) {
+				end = event.Start.AddDate(0, 0, 1)
+			}
+			writeLine(bw, "DTSTART;VALUE=DATE:"+event.Start.Format(dateLayout))
+			writeLine(bw, "DTEND;VALUE=DATE:"+end.Format(dateLayout))
+			writeLine(bw, "TRANSP:TRANSPARENT")
+		} else {
+			writeLine(bw, "DTSTART:"+event.Start.UTC().Format(dateTimeLayout))
+			if !event.End.IsZero() {
+				writeLine(bw, "DTEND:"+event.End.UTC().Format(dateTimeLayout))
+			}
+		}
+		writeLine(bw, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(bw, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if len(event.Categories) > 0 {
+			categories := make([]string, 0, len(event.Categories))
+			for _, category := range event.Categories {
+				categories = append(categories, escapeText(category))
+			}
+			writeLine(bw, "CATEGORIES:"+strings.Join(categories, ","))
+		}
+		writeLine(bw, "END:VEVENT")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText escapes a TEXT value (RFC 5545 section 3.3.11).
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+// writeLine writes a content line, folding it after 75 octets without
+// splitting multi-byte UTF-8 characters (RFC 5545 section 3.1).
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit.
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,98 @@
+package isbn
+
+import "strings"
+
+// Normalize cleans an ISBN-10 or ISBN-13 as found in exports and user input
+// (hyphens, spaces, Goodreads' ="..." wrapping) and returns it as ISBN-13.
+// ok is false when the value is not a valid ISBN.
+func Normalize(raw string) (string, bool) {
+	var digits strings.Builder
+	for _, r := range strings.ToUpper(raw) {
+		switch {
+		case r >= '0' && r <= '9', r == 'X':
+			digits.WriteRune(r)
+		case r == '-', r == ' ', r == '=', r == '"', r == '\'':
+			// separators and spreadsheet quoting
+		default:
+			return "", false
+		}
+	}
+
+	value := digits.String()
+	switch {
+	case len(value) == 10 && ValidISBN10(value):
+		return ToISBN13(value), true
+	case len(value) == 13 && ValidISBN13(value):
+		return value, true
+	}
+	return "", false
+}
+
+// ValidISBN10 checks the mod-11 check digit of a 10 character ISBN.
+func ValidISBN10(value string) bool {
+	if len(value) != 10 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var digit int
+		switch {
+		case value[i] >= '0' && value[i] <= '9':
+			digit = int(value[i] - '0')
+		case value[i] == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// ValidISBN13 checks the EAN-13 check digit of a 13 digit ISBN. Only the
+// Bookland prefixes 978 and 979 are ISBNs.
+func ValidISBN13(value string) bool {
+	if len(value) != 13 || !(strings.HasPrefix(value, "978") || strings.HasPrefix(value, "979")) {
+		return false
+	}
+	return value[12] == checkDigit13(value[:12])
+}
+
+// ToISBN13 converts a valid ISBN-10 to its ISBN-13 form.
+func ToISBN13(isbn10 string) string {
+	prefix := "978" + isbn10[:9]
+	return prefix + string(checkDigit13(prefix))
+}
+
+// ToISBN10 converts a valid 978-prefixed ISBN-13 to its ISBN-10 form. ok is
+// false for 979 ISBNs, which have no ISBN-10 equivalent.
+func ToISBN10(isbn13 string) (string, bool) {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") {
+		return "", false
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X", true
+	}
+	return body + string(rune('0'+check)), true
+}
+
+func checkDigit13(first12 string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		if first12[i] < '0' || first12[i] > '9' {
+			return 0
+		}
+		digit := int(first12[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,114 @@
+package isbn
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{"9780306406157", "9780306406157", true},
+		{"978-0-306-40615-7", "9780306406157", true},
+		{"0-306-40615-2", "9780306406157", true},
+		{"080442957x", "9780804429573", true},
+		{`="0804429579"`, "", false},
+		{`="080442957X"`, "9780804429573", true},
+		{"979 10 323 0569 0", "9791032305690", true},
+		{"979-3062-79-7", "9789793062792", true},
+		{" 978 1861 97271 2 ", "9781861972712", true},
+		{"9780306406158", "", false}, // wrong check digit
+		{"0306406153", "", false},    // wrong check digit
+		{"9771234567003", "", false}, // ISSN, valid EAN-13
+		{"5901234123457", "", false}, // product EAN-13
+		{"X306406152", "", false},    // X only as ISBN-10 check digit
+		{"ISBN 9780306406157", "", false},
+		{"030640615", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := Normalize(tt.raw)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidISBN10(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"0306406152", true},
+		{"080442957X", true},
+		{"9992158107", true},
+		{"1861972717", true},
+		{"9793062797", true},
+		{"0306406153", false},
+		{"0804429579", false},
+		{"08044X9573", false},
+		{"030640615", false},
+		{"03064061522", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidISBN10(tt.value); got != tt.want {
+			t.Errorf("ValidISBN10(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidISBN13(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"9780306406157", true},
+		{"9780804429573", true},
+		{"9781861972712", true},
+		{"9798602405453", true},
+		{"9791032305690", true},
+		{"9780306406150", false},
+		{"9780306406151", false},
+		{"9771234567003", false}, // 977 is ISSN, not Bookland
+		{"4006381333931", false},
+		{"978030640615", false},
+		{"978030640615X", false},
+		{"978O306406157", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidISBN13(tt.value); got != tt.want {
+			t.Errorf("ValidISBN13(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestConversion(t *testing.T) {
+	tests := []struct {
+		isbn10 string
+		isbn13 string
+	}{
+		{"0306406152", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"1861972717", "9781861972712"},
+		{"9793062797", "9789793062792"},
+		{"9992158107", "9789992158104"},
+	}
+
+	for _, tt := range tests {
+		if got := ToISBN13(tt.isbn10); got != tt.isbn13 {
+			t.Errorf("ToISBN13(%q) = %q, want %q", tt.isbn10, got, tt.isbn13)
+		}
+		if got, ok := ToISBN10(tt.isbn13); got != tt.isbn10 || !ok {
+			t.Errorf("ToISBN10(%q) = %q, %v, want %q, true", tt.isbn13, got, ok, tt.isbn10)
+		}
+	}
+
+	if got, ok := ToISBN10("9791032305690"); ok {
+		t.Errorf("ToISBN10 of a 979 ISBN = %q, true, want no ISBN-10", got)
+	}
+}
@@ -1,35 +1,42 @@
 package jwt
 
 import (
-	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/config"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt"
-	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type TokenClaims struct {
-	UID      int64  `json:"uid"`
+	UID      string `json:"uid"` // UUID pengguna, bukan ID numerik
 	Username string `json:"username"`
 }
 
-func GenerateToken(UID string, userName string) (string, error) {
-	logger := logger.GetLogger()
+// Manager signs and verifies the tokens issued at login.
+type Manager struct {
+	secret []byte
+	ttl    time.Duration
+}
 
-	jwtKey := []byte(viper.GetString("JWT_SECRET"))
-	if len(jwtKey) == 0 {
-		logger.Fatal("JWT_SECRET is not set")
+// NewManager creates a Manager for the auth configuration.
+func NewManager(cfg config.AuthConfig) (*Manager, error) {
+	if cfg.JWTSecret == "" {
+		return nil, errors.New("JWT secret is not set")
 	}
+	return &Manager{secret: []byte(cfg.JWTSecret), ttl: cfg.TokenTTL}, nil
+}
 
+func (m *Manager) GenerateToken(UID string, userName string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"uid":      UID,
 		"username": userName,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
+		"exp":      time.Now().Add(m.ttl).Unix(),
 	})
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := token.SignedString(m.secret)
 	if err != nil {
 		return "", err
 	}
@@ -37,35 +44,6 @@ func GenerateToken(UID string, userName string) (string, error) {
 	return tokenString, nil
 }
 
-func VerifyToken(tokenString string) (int64, error) {
-	logger := logger.GetLogger()
-
-	jwtKey := []byte(viper.GetString("JWT_SECRET"))
-	if len(jwtKey) == 0 {
-		logger.Fatal("JWT_SECRET is not set")
-	}
-
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-
-	if err != nil {
-		return 0, err
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
-			return 0, errors.New("token expired")
-		}
-		if userID, ok := claims["userid"].(float64); ok {
-			return int64(userID), nil
-		}
-		return 0, errors.New("userid claim is missing or not a float64")
-	}
-
-	return 0, errors.New("invalid token")
-}
-
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
@@ -76,16 +54,13 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func DecodeToken(tokenString string) (*TokenClaims, error) {
-	logger := logger.GetLogger()
-
-	jwtKey := []byte(viper.GetString("JWT_SECRET"))
-	if len(jwtKey) == 0 {
-		logger.Fatal("JWT_SECRET is not set")
-	}
-
+func (m *Manager) DecodeToken(tokenString string) (*TokenClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
+		// Only accept the algorithm the tokens are signed with
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method " + token.Method.Alg())
+		}
+		return m.secret, nil
 	})
 
 	if err != nil {
@@ -93,8 +68,11 @@ func DecodeToken(tokenString string) (*TokenClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		uid := int64(claims["uid"].(float64))
-		username := claims["username"].(string)
+		uid, ok := claims["uid"].(string)
+		if !ok || uid == "" {
+			return nil, errors.New("uid claim is missing or not a string")
+		}
+		username, _ := claims["username"].(string)
 		return &TokenClaims{
 			UID:      uid,
 			Username: username,
@@ -104,13 +82,16 @@ func DecodeToken(tokenString string) (*TokenClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
-func GetUserInfo(c *fiber.Ctx) (*TokenClaims, error) {
+func (m *Manager) GetUserInfo(c *fiber.Ctx) (*TokenClaims, error) {
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		return nil, errors.New("authorization header is missing")
 	}
-	tokenString := authHeader[len("Bearer "):]
-	tokenClaims, err := DecodeToken(tokenString)
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, errors.New("authorization header is not a bearer token")
+	}
+	tokenString := strings.TrimSpace(authHeader[len("Bearer "):])
+	tokenClaims, err := m.DecodeToken(tokenString)
 	if err != nil {
 		return nil, err
 	}
@@ -1,30 +1,35 @@
 package logger
 
 import (
+	"ayo-baca-buku/app/config"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 	"gorm.io/gorm/logger"
 )
 
 var (
 	globalLogger *zap.Logger
 	once         sync.Once
+	// level is shared by all outputs, so SetLevel changes it at runtime.
+	level = zap.NewAtomicLevelAt(zap.InfoLevel)
 )
 
-func NewLogger() *zap.Logger {
+// defaultConfig is used when GetLogger is called before NewLogger.
+var defaultConfig = config.LogConfig{Level: "info", Format: "console", Outputs: []string{"stdout"}}
+
+// NewLogger creates the global logger. Only the first call configures it.
+// Sensitive fields are redacted from every output, see redactCore.
+func NewLogger(logConfig config.LogConfig) *zap.Logger {
 	once.Do(func() {
-		logFile := &lumberjack.Logger{
-			Filename:   "logs/app-" + time.Now().Format("2006-01-02") + ".log",
-			MaxSize:    10,
-			MaxBackups: 30,
-			MaxAge:     7,
-			Compress:   true,
+		if err := SetLevel(logConfig.Level); err != nil {
+			level.SetLevel(zap.InfoLevel)
 		}
 
 		// Encoder configuration
@@ -32,84 +37,164 @@ func NewLogger() *zap.Logger {
 		encoderConfig.TimeKey = "timestamp"
 		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
-		// Create core for file logging
-		fileCore := zapcore.NewCore(
-			zapcore.NewJSONEncoder(encoderConfig),
-			zapcore.AddSync(logFile),
-			zap.InfoLevel,
-		)
-
-		// Create core for console logging
-		consoleCore := zapcore.NewCore(
-			zapcore.NewConsoleEncoder(encoderConfig),
-			zapcore.AddSync(os.Stdout),
-			zap.InfoLevel,
-		)
-
-		// Combine both cores
-		core := zapcore.NewTee(fileCore, consoleCore)
+		consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
+		if logConfig.Format == "json" {
+			consoleEncoder = zapcore.NewJSONEncoder(encoderConfig)
+		}
+		var cores []zapcore.Core
+		if logConfig.HasOutput("stdout") {
+			cores = append(cores, zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stdout), level))
+		}
+		if logConfig.HasOutput("stderr") {
+			cores = append(cores, zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stderr), level))
+		}
+		if logConfig.HasOutput("file") {
+			logFile := &dailyFile{Dir: logConfig.Dir, Prefix: "app-", MaxAge: logConfig.MaxAge}
+			cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(logFile), level))
+		}
 
-		globalLogger = zap.New(core)
+		globalLogger = zap.New(&redactCore{Core: zapcore.NewTee(cores...)})
 	})
 	return globalLogger
 }
 
 func GetLogger() *zap.Logger {
 	if globalLogger == nil {
-		return NewLogger()
+		return NewLogger(defaultConfig)
 	}
 	return globalLogger
 }
 
+// Level returns the current level of the logger.
+func Level() zapcore.Level {
+	return level.Level()
+}
+
+// SetLevel changes the level of the logger while it runs: debug, info, warn
+// or error.
+func SetLevel(text string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(text)); err != nil {
+		return err
+	}
+	switch l {
+	case zap.DebugLevel, zap.InfoLevel, zap.WarnLevel, zap.ErrorLevel:
+	default:
+		return fmt.Errorf("unsupported log level %q", text)
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying l, for FromContext.
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of the request ctx belongs to, which adds
+// the request ID and user ID to every line, or the global logger.
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := fromContext(ctx); ok {
+		return l
+	}
+	return GetLogger()
+}
+
+func fromContext(ctx context.Context) (*zap.Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	l, ok := ctx.Value(contextKey{}).(*zap.Logger)
+	return l, ok
+}
+
+// GormLogger writes GORM messages to the logger of the query's context, see
+// FromContext, and to ZapLogger for queries without one. At the Info level
+// every statement is logged, at Warn only failed statements and statements
+// slower than SlowThreshold. While the application log is at the debug level,
+// see SetLevel, every statement is logged unless GORM is Silent.
 type GormLogger struct {
-	ZapLogger *zap.Logger
-	LogLevel  logger.LogLevel
+	ZapLogger     *zap.Logger
+	LogLevel      logger.LogLevel
+	SlowThreshold time.Duration // 0 mematikan peringatan query lambat
 }
 
 func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
 	return &GormLogger{
-		ZapLogger: l.ZapLogger,
-		LogLevel:  level,
+		ZapLogger:     l.ZapLogger,
+		LogLevel:      level,
+		SlowThreshold: l.SlowThreshold,
+	}
+}
+
+// level returns LogLevel, raised to Info while the application log is at the
+// debug level.
+func (l *GormLogger) level() logger.LogLevel {
+	if l.LogLevel > logger.Silent && Level() <= zapcore.DebugLevel {
+		return logger.Info
+	}
+	return l.LogLevel
+}
+
+func (l *GormLogger) logger(ctx context.Context) *zap.Logger {
+	if ctxLogger, ok := fromContext(ctx); ok {
+		return ctxLogger
 	}
+	return l.ZapLogger
 }
 
 func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= logger.Info {
-		l.ZapLogger.Sugar().Infof(msg, data...)
+	if l.level() >= logger.Info {
+		l.logger(ctx).Sugar().Infof(msg, data...)
 	}
 }
 
 func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= logger.Warn {
-		l.ZapLogger.Sugar().Warnf(msg, data...)
+	if l.level() >= logger.Warn {
+		l.logger(ctx).Sugar().Warnf(msg, data...)
 	}
 }
 
 func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= logger.Error {
-		l.ZapLogger.Sugar().Errorf(msg, data...)
+	if l.level() >= logger.Error {
+		l.logger(ctx).Sugar().Errorf(msg, data...)
 	}
 }
 
 func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
-	if l.LogLevel > 0 {
-		elapsed := time.Since(begin)
-		sql, rows := fc()
+	level := l.level()
+	if level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
 
-		switch {
-		case err != nil && l.LogLevel >= logger.Error:
-			l.ZapLogger.Error("SQL error",
-				zap.Error(err),
-				zap.String("sql", sql),
-				zap.Int64("rows", rows),
-				zap.Duration("elapsed", elapsed),
-			)
-		case l.LogLevel >= logger.Info:
-			l.ZapLogger.Info("SQL executed",
-				zap.String("sql", sql),
-				zap.Int64("rows", rows),
-				zap.Duration("elapsed", elapsed),
-			)
-		}
+	switch {
+	// A missing record is a normal outcome of First, not a failure.
+	case err != nil && !errors.Is(err, logger.ErrRecordNotFound) && level >= logger.Error:
+		sql, rows := fc()
+		l.logger(ctx).Error("SQL error",
+			zap.Error(err),
+			zap.String("sql", sql),
+			zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed),
+		)
+	case slow && level >= logger.Warn:
+		sql, rows := fc()
+		l.logger(ctx).Warn("Slow SQL",
+			zap.String("sql", sql),
+			zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed),
+			zap.Duration("threshold", l.SlowThreshold),
+		)
+	case level >= logger.Info:
+		sql, rows := fc()
+		l.logger(ctx).Info("SQL executed",
+			zap.String("sql", sql),
+			zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed),
+		)
 	}
 }
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dateLayout is the date in log file names.
+const dateLayout = "2006-01-02"
+
+// dailyFile writes to <Dir>/<Prefix><date>.log and moves on to the file of
+// the next day at midnight, local time. When it opens a new file, files older
+// than MaxAge days are removed.
+type dailyFile struct {
+	Dir    string
+	Prefix string
+	MaxAge int
+
+	mu   sync.Mutex
+	file *os.File
+	date string
+}
+
+func (f *dailyFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if date := time.Now().Format(dateLayout); f.file == nil || date != f.date {
+		if err := f.open(date); err != nil {
+			return 0, err
+		}
+	}
+	return f.file.Write(p)
+}
+
+func (f *dailyFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+func (f *dailyFile) open(date string) error {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(f.Dir, f.Prefix+date+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	f.file, f.date = file, date
+	f.removeOld()
+	return nil
+}
+
+// removeOld deletes the log files of days more than MaxAge days ago.
+func (f *dailyFile) removeOld() {
+	if f.MaxAge <= 0 {
+		return
+	}
+	names, err := filepath.Glob(filepath.Join(f.Dir, f.Prefix+"*.log"))
+	if err != nil {
+		return
+	}
+	oldest := time.Now().AddDate(0, 0, -f.MaxAge).Format(dateLayout)
+	for _, name := range names {
+		date := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), f.Prefix), ".log")
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			continue
+		}
+		if date < oldest {
+			os.Remove(name)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// startKey stores the start time of a query on the statement.
+const startKey = "metrics:start"
+
+var (
+	queryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of database queries by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+	queryErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Failed database queries by operation and table. Record not found is not an error.",
+	}, []string{"operation", "table"})
+)
+
+// GormPlugin records the duration and errors of the queries of a database,
+// and exports its connection pool statistics.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := Registry.Register(collectors.NewDBStatsCollector(sqlDB, "postgres")); err != nil {
+		return err
+	}
+
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("metrics:before_create", before),
+		callback.Create().After("*").Register("metrics:after_create", after("create")),
+		callback.Query().Before("*").Register("metrics:before_query", before),
+		callback.Query().After("*").Register("metrics:after_query", after("query")),
+		callback.Update().Before("*").Register("metrics:before_update", before),
+		callback.Update().After("*").Register("metrics:after_update", after("update")),
+		callback.Delete().Before("*").Register("metrics:before_delete", before),
+		callback.Delete().After("*").Register("metrics:after_delete", after("delete")),
+		callback.Row().Before("*").Register("metrics:before_row", before),
+		callback.Row().After("*").Register("metrics:after_row", after("row")),
+		callback.Raw().Before("*").Register("metrics:before_raw", before),
+		callback.Raw().After("*").Register("metrics:after_raw", after("raw")),
+	)
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		queryDuration.WithLabelValues(operation, table).Observe(time.Since(value.(time.Time)).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
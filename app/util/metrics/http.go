@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests that matched no route, so random paths do
+// not each create a time series.
+const unmatchedRoute = "unmatched"
+
+var (
+	requestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route and status.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"method", "route", "status"})
+	requestsInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests being served.",
+	})
+)
+
+// Middleware records the duration of every request by its route pattern,
+// e.g. /userbooks/:id, rather than the requested path. It must be the first
+// middleware of the app.
+func Middleware() fiber.Handler {
+	var (
+		once      sync.Once
+		endpoints map[endpoint]bool
+	)
+	return func(ctx *fiber.Ctx) error {
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+		start := time.Now()
+
+		err := ctx.Next()
+
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			// Without an error handling middleware, e.g. fiberzap, the error
+			// handler writes the response after the middleware returns.
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		route := ctx.Route().Path
+		// Fiber answers requests that match no route with 404 or 405. The last
+		// route that ran is then a middleware, not an endpoint.
+		if status == fiber.StatusNotFound || status == fiber.StatusMethodNotAllowed {
+			once.Do(func() { endpoints = appEndpoints(ctx.App()) })
+			if !endpoints[endpointOf(ctx.Route())] {
+				route = unmatchedRoute
+			}
+		}
+		// Fiber reuses the buffer behind Method, the label must be a copy.
+		requestDuration.WithLabelValues(strings.Clone(ctx.Method()), route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// endpoint identifies a route by its method and first handler. Fiber copies
+// the routes of middlewares for every method, the copies share the handlers.
+type endpoint struct {
+	method  string
+	handler *fiber.Handler
+}
+
+func endpointOf(route *fiber.Route) endpoint {
+	if len(route.Handlers) == 0 {
+		return endpoint{method: route.Method}
+	}
+	return endpoint{method: route.Method, handler: &route.Handlers[0]}
+}
+
+// appEndpoints returns the routes of app that are not middlewares. Routes
+// must not be added after the first request.
+func appEndpoints(app *fiber.App) map[endpoint]bool {
+	endpoints := map[endpoint]bool{}
+	for _, route := range app.GetRoutes(true) {
+		endpoints[endpointOf(&route)] = true
+	}
+	return endpoints
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
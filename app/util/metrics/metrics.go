@@ -0,0 +1,53 @@
+// Package metrics defines the Prometheus metrics of the server: HTTP
+// requests, database queries and connection pool, and reading activity.
+// They are served on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace prefixes every metric of the application.
+const namespace = "ayo_baca_buku"
+
+// Registry holds the metrics of the application and the Go runtime and
+// process collectors.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Results of a login attempt.
+const (
+	LoginSucceeded = "succeeded"
+	LoginFailed    = "failed"
+)
+
+var (
+	// ActivitiesLogged counts the reading sessions users log.
+	ActivitiesLogged = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reading_activities_logged_total",
+		Help:      "Reading activities logged by users.",
+	})
+	// BooksFinished counts the books marked as finished.
+	BooksFinished = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "books_finished_total",
+		Help:      "User books marked as finished.",
+	})
+	// Logins counts login attempts by result, LoginSucceeded or LoginFailed.
+	Logins = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result.",
+	}, []string{"result"})
+)
@@ -0,0 +1,117 @@
+package planner
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Day is one calendar day of a reading plan. TargetPage is the page the reader
+// should have reached at the end of the day.
+type Day struct {
+	Date       time.Time `json:"date"`
+	Rest       bool      `json:"rest"`
+	Pages      int       `json:"pages"`
+	TargetPage int       `json:"target_page"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// ParseRestDays parses a comma separated list of weekday names such as
+// "saturday,sunday". Names are case-insensitive and may be abbreviated to three letters.
+func ParseRestDays(value string) (map[time.Weekday]bool, error) {
+	restDays := map[time.Weekday]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for full, weekday := range weekdays {
+			if name == full || (len(name) == 3 && strings.HasPrefix(full, name)) {
+				restDays[weekday] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("unknown weekday: " + name)
+		}
+	}
+	return restDays, nil
+}
+
+// FormatRestDays renders rest days in a stable, canonical order.
+func FormatRestDays(restDays map[time.Weekday]bool) string {
+	names := []string{}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if restDays[weekday] {
+			names = append(names, strings.ToLower(weekday.String()))
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Schedule spreads the pages between fromPage and totalPages evenly over the
+// non-rest days from start to deadline (both inclusive, as calendar days).
+// When every day is a rest day, rest days are ignored so the book still gets finished.
+func Schedule(start, deadline time.Time, fromPage, totalPages int, restDays map[time.Weekday]bool) []Day {
+	if deadline.Before(start) {
+		return []Day{}
+	}
+
+	days := []Day{}
+	readingDays := 0
+	for date := start; !date.After(deadline); date = date.AddDate(0, 0, 1) {
+		rest := restDays[date.Weekday()]
+		if !rest {
+			readingDays++
+		}
+		days = append(days, Day{Date: date, Rest: rest})
+	}
+	if readingDays == 0 {
+		for i := range days {
+			days[i].Rest = false
+		}
+		readingDays = len(days)
+	}
+
+	remaining := max(totalPages-fromPage, 0)
+	page := fromPage
+	done := 0
+	for i := range days {
+		if !days[i].Rest {
+			done++
+			// Rounding the cumulative target keeps the daily amounts within one page of each other.
+			target := fromPage + (remaining*done+readingDays/2)/readingDays
+			days[i].Pages = target - page
+			page = target
+		}
+		days[i].TargetPage = page
+	}
+	return days
+}
+
+// TargetPageOn returns the page that should have been reached by the end of date
+// according to days. Dates before the plan return the first day's starting page.
+func TargetPageOn(days []Day, date time.Time) int {
+	target := 0
+	if len(days) > 0 {
+		target = days[0].TargetPage - days[0].Pages
+	}
+	for _, day := range days {
+		if day.Date.After(date) {
+			break
+		}
+		target = day.TargetPage
+	}
+	return target
+}
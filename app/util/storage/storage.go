@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Defaults used when the configuration leaves the storage settings empty.
+const (
+	DefaultDir     = "storage"
+	DefaultBaseURL = "/storage"
+)
+
+// Local stores uploaded files such as covers in a directory on disk. The
+// directory is served as static files under BaseURL.
+type Local struct {
+	Dir     string
+	BaseURL string
+}
+
+// NewLocal creates a local storage, falling back to DefaultDir and
+// DefaultBaseURL for empty values.
+func NewLocal(dir, baseURL string) *Local {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Local{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Save writes r to name, a slash-separated path relative to the storage
+// directory, replacing any existing file. It returns the URL of the file.
+func (s *Local) Save(name string, r io.Reader) (string, error) {
+	target, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so readers never see a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return s.URL(name), nil
+}
+
+// Delete removes name. Deleting a file that does not exist is not an error.
+func (s *Local) Delete(name string) error {
+	target, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Check verifies that files can be written to the storage directory,
+// creating it when it does not exist yet.
+func (s *Local) Check() error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".check-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+// URL returns the public URL of name.
+func (s *Local) URL(name string) string {
+	return s.BaseURL + "/" + strings.TrimLeft(path.Clean("/"+name), "/")
+}
+
+// path resolves name inside the storage directory, rejecting names that
+// would escape it.
+func (s *Local) path(name string) (string, error) {
+	clean := path.Clean("/" + name)[1:]
+	if clean == "" || clean != strings.TrimPrefix(name, "/") {
+		return "", fmt.Errorf("invalid storage name %q", name)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,103 @@
+package streak
+
+import (
+	"sort"
+	"time"
+)
+
+// Result is the outcome of a streak calculation. Dates are calendar days
+// expressed as midnight UTC.
+type Result struct {
+	Current      int
+	CurrentStart *time.Time
+	Longest      int
+	LongestStart *time.Time
+	LongestEnd   *time.Time
+	ReadToday    bool
+	LastReadDate *time.Time
+	TotalDays    int
+}
+
+// IsReadDay reports whether the totals of a day reach the configured minimum.
+// A day qualifies when either minimum is met; with both minimums at zero any
+// logged session counts.
+func IsReadDay(pages, minutes int64, minimumPages, minimumMinutes int) bool {
+	if minimumPages <= 0 && minimumMinutes <= 0 {
+		return pages > 0 || minutes > 0
+	}
+	if minimumPages > 0 && pages >= int64(minimumPages) {
+		return true
+	}
+	return minimumMinutes > 0 && minutes >= int64(minimumMinutes)
+}
+
+// Day truncates t to its calendar day in loc and returns it as midnight UTC,
+// which makes days from different timezones comparable by value.
+func Day(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Calculate computes the current and longest streaks.
+//
+// Freeze days keep a streak alive without extending it. Today only breaks the
+// current streak once it is over, so a streak that ended yesterday is still current.
+func Calculate(readDays []time.Time, freezeDays []time.Time, today time.Time) Result {
+	result := Result{TotalDays: len(readDays)}
+	if len(readDays) == 0 {
+		return result
+	}
+
+	read := make(map[time.Time]bool, len(readDays))
+	for _, d := range readDays {
+		read[d] = true
+	}
+	frozen := make(map[time.Time]bool, len(freezeDays))
+	for _, d := range freezeDays {
+		frozen[d] = true
+	}
+
+	sorted := append([]time.Time(nil), readDays...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	last := sorted[len(sorted)-1]
+	result.LastReadDate = &last
+	result.ReadToday = read[today]
+
+	// Longest streak: walk every day from the first read day onwards.
+	run := 0
+	var runStart time.Time
+	for day := sorted[0]; !day.After(last); day = day.AddDate(0, 0, 1) {
+		switch {
+		case read[day]:
+			if run == 0 {
+				runStart = day
+			}
+			run++
+			if run > result.Longest {
+				start, end := runStart, day
+				result.Longest = run
+				result.LongestStart = &start
+				result.LongestEnd = &end
+			}
+		case frozen[day]:
+			// keeps the run going
+		default:
+			run = 0
+		}
+	}
+
+	// Current streak: walk backwards from today (or yesterday if today is still open).
+	day := today
+	if !read[day] && !frozen[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for ; read[day] || frozen[day]; day = day.AddDate(0, 0, -1) {
+		if read[day] {
+			result.Current++
+			start := day
+			result.CurrentStart = &start
+		}
+	}
+
+	return result
+}
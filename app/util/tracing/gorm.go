@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey stores the span of a statement on the statement.
+const spanKey = "tracing:span"
+
+// GormPlugin traces every statement of a database as a child of the span in
+// the statement's context. The SQL is recorded with its placeholders, never
+// with the values, so passwords and tokens do not end up in traces.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("tracing:before_create", before("INSERT")),
+		callback.Create().After("*").Register("tracing:after_create", after),
+		callback.Query().Before("*").Register("tracing:before_query", before("SELECT")),
+		callback.Query().After("*").Register("tracing:after_query", after),
+		callback.Update().Before("*").Register("tracing:before_update", before("UPDATE")),
+		callback.Update().After("*").Register("tracing:after_update", after),
+		callback.Delete().Before("*").Register("tracing:before_delete", before("DELETE")),
+		callback.Delete().After("*").Register("tracing:after_delete", after),
+		callback.Row().Before("*").Register("tracing:before_row", before("SELECT")),
+		callback.Row().After("*").Register("tracing:after_row", after),
+		callback.Raw().Before("*").Register("tracing:before_raw", before("RAW")),
+		callback.Raw().After("*").Register("tracing:after_raw", after),
+	)
+}
+
+func before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		// The tracer is looked up for every statement, so a provider installed
+		// after the plugin is used too.
+		tracer := otel.Tracer("ayo-baca-buku/gorm")
+		name := operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := tracer.Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,96 @@
+// Package tracing sets up OpenTelemetry tracing: a span per HTTP request,
+// database statement and outbound HTTP call. The trace context of incoming
+// requests is continued and passed on to the services that are called.
+package tracing
+
+import (
+	"ayo-baca-buku/app/config"
+	"ayo-baca-buku/app/util/version"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/contrib/otelfiber/v2"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. With the none exporter no spans are recorded, but the trace
+// context of incoming requests is still passed on. The returned function
+// flushes the remaining spans and must be called before the process exits.
+func Setup(tracing config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if tracing.Exporter != "otlp" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var options []otlptracehttp.Option
+	if tracing.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(tracing.Endpoint))
+	}
+	if tracing.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(tracing, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider for the service, sampling
+// tracing.SampleRatio of the traces that do not come with a sampling decision.
+func NewProvider(tracing config.TracingConfig, options ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(
+		semconv.ServiceName(tracing.ServiceName),
+		semconv.ServiceVersion(version.Get().Version),
+	)
+	options = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracing.SampleRatio))),
+	}, options...)
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// untraced are the paths polled by the orchestrator and Prometheus.
+var untraced = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Middleware starts a span named after the method and route of every
+// request, continuing the trace of the traceparent header, and stores it in
+// ctx.UserContext(). Queries run with DB.WithContext(ctx.UserContext()) are
+// traced as its children. Setup must be called first.
+func Middleware() fiber.Handler {
+	return otelfiber.Middleware(
+		otelfiber.WithNext(func(ctx *fiber.Ctx) bool {
+			return untraced[ctx.Path()]
+		}),
+		otelfiber.WithSpanNameFormatter(func(ctx *fiber.Ctx) string {
+			return ctx.Method() + " " + ctx.Route().Path
+		}),
+	)
+}
+
+// HTTPClient returns a client for calls to other services, such as book
+// metadata providers or webhooks. Every call made with a request context, e.g.
+// http.NewRequestWithContext(ctx.UserContext(), ...), is traced as a child of
+// the span in it and sends its traceparent header.
+func HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+}
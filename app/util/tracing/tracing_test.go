@@ -0,0 +1,184 @@
+package tracing
+
+import (
+	"ayo-baca-buku/app/config"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+type book struct {
+	ID    uint
+	Title string
+}
+
+// newTestApp installs a tracer provider that records every span in the
+// returned exporter and serves GET /books/:id from an in-memory database.
+func newTestApp(t *testing.T) (*fiber.App, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(config.TracingConfig{ServiceName: "test", SampleRatio: 1}, sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&book{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&book{Title: "Laskar Pelangi"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/books/:id", func(ctx *fiber.Ctx) error {
+		var b book
+		if err := db.WithContext(ctx.UserContext()).Where("id = ?", ctx.Params("id")).First(&b).Error; err != nil {
+			return err
+		}
+		return ctx.JSON(b)
+	})
+	app.Get("/healthz", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	})
+	return app, exporter
+}
+
+func spanNamed(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func TestRequestAndQuerySpans(t *testing.T) {
+	app, exporter := newTestApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/books/1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	spans := exporter.GetSpans()
+	request := spanNamed(spans, "GET /books/:id")
+	if request == nil {
+		t.Fatalf("no request span in %d spans", len(spans))
+	}
+	query := spanNamed(spans, "SELECT books")
+	if query == nil {
+		t.Fatalf("no query span in %d spans", len(spans))
+	}
+	if query.Parent.SpanID() != request.SpanContext.SpanID() {
+		t.Errorf("query span parent = %s, want request span %s", query.Parent.SpanID(), request.SpanContext.SpanID())
+	}
+	var sql string
+	for _, attr := range query.Attributes {
+		if attr.Key == "db.query.text" {
+			sql = attr.Value.AsString()
+		}
+	}
+	if sql == "" {
+		t.Error("query span has no SQL")
+	}
+}
+
+func TestRequestContinuesTrace(t *testing.T) {
+	app, exporter := newTestApp(t)
+
+	req := httptest.NewRequest("GET", "/books/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+
+	request := spanNamed(exporter.GetSpans(), "GET /books/:id")
+	if request == nil {
+		t.Fatal("no request span")
+	}
+	if got := request.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the one of traceparent", got)
+	}
+}
+
+func TestHealthChecksAreNotTraced(t *testing.T) {
+	app, exporter := newTestApp(t)
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/healthz", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("got %d spans, want none", len(spans))
+	}
+}
+
+func TestOutboundCallSpans(t *testing.T) {
+	app, exporter := newTestApp(t)
+
+	var traceparent string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer provider.Close()
+
+	client := HTTPClient(5 * time.Second)
+	app.Get("/lookup", func(ctx *fiber.Ctx) error {
+		req, err := http.NewRequestWithContext(ctx.UserContext(), http.MethodGet, provider.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return ctx.SendStatus(fiber.StatusNoContent)
+	})
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/lookup", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	request := spanNamed(spans, "GET /lookup")
+	if request == nil {
+		t.Fatalf("no request span in %d spans", len(spans))
+	}
+	call := spanNamed(spans, "HTTP GET")
+	if call == nil {
+		t.Fatalf("no outbound call span in %d spans", len(spans))
+	}
+	if call.Parent.SpanID() != request.SpanContext.SpanID() {
+		t.Errorf("call span parent = %s, want request span %s", call.Parent.SpanID(), request.SpanContext.SpanID())
+	}
+	want := "00-" + call.SpanContext.TraceID().String() + "-" + call.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("traceparent = %q, want %q", traceparent, want)
+	}
+}
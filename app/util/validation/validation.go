@@ -2,11 +2,32 @@ package validation
 
 import (
 	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/isbn"
+	"math"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 )
 
+// New creates a validator that reports fields by their JSON name, so errors
+// name the fields as clients send them.
+func New() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return validate
+}
+
 func UniqueUsername(db *gorm.DB, id int64) validator.Func {
 	return func(fl validator.FieldLevel) bool {
 		username := fl.Field().String()
@@ -40,3 +61,15 @@ func UniqueEmail(db *gorm.DB, id int64) validator.Func {
 		return count == 0
 	}
 }
+
+// HalfStar accepts ratings from 0.5 to 5 in steps of 0.5.
+func HalfStar(fl validator.FieldLevel) bool {
+	rating := fl.Field().Float()
+	return rating >= 0.5 && rating <= 5 && math.Mod(rating*2, 1) == 0
+}
+
+// ISBN accepts ISBN-10 and ISBN-13 values, with or without hyphens.
+func ISBN(fl validator.FieldLevel) bool {
+	_, ok := isbn.Normalize(fl.Field().String())
+	return ok
+}
@@ -0,0 +1,52 @@
+// Package version holds the build information of the binary, set with
+// -ldflags at build time:
+//
+//	go build -ldflags "-X ayo-baca-buku/app/util/version.Version=v1.2.0 \
+//	  -X ayo-baca-buku/app/util/version.Commit=$(git rev-parse HEAD) \
+//	  -X ayo-baca-buku/app/util/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags -X. Commit falls back to the revision Go records in
+// binaries built from a git checkout.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	Modified  bool   `json:"modified,omitempty"` // Dibangun dari checkout dengan perubahan yang belum di-commit
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"ayo-baca-buku/app/config"
+	"ayo-baca-buku/app/database"
+	"ayo-baca-buku/app/database/seeders"
+	"ayo-baca-buku/app/importer"
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/calibre"
+	"ayo-baca-buku/app/util/logger"
+	"ayo-baca-buku/app/util/storage"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const usage = `Usage:
+  go run ./cmd [serve]                            start the API server
+  go run ./cmd migrate up                         apply pending database migrations
+  go run ./cmd migrate down [n]                   revert the last n migrations (default 1)
+  go run ./cmd migrate status                     list migrations and whether they are applied
+  go run ./cmd seed [-list] [set ...]             load seed data (default set: admin)
+  go run ./cmd user create-admin -username <username> -email <email> [-name <name>] [-password-stdin]
+  go run ./cmd user reset-password -username <username> [-password-stdin]
+  go run ./cmd recompute-progress [-user <username>] [-dry-run]
+  go run ./cmd import-calibre -user <username> [-no-covers] <library folder or metadata.db>`
+
+// environment is what every command shares: the configuration and the
+// logger, database and file storage built from it.
+type environment struct {
+	Config config.AppConfig
+	Logger *zap.Logger
+	DB     *gorm.DB
+	Files  *storage.Local
+}
+
+type command struct {
+	run func(env *environment, args []string) error
+	// migrated commands refuse to run while migrations are pending.
+	migrated bool
+}
+
+var commands = map[string]command{
+	"serve":              {run: runServe, migrated: true},
+	"migrate":            {run: runMigrate},
+	"seed":               {run: runSeed, migrated: true},
+	"user":               {run: runUser, migrated: true},
+	"recompute-progress": {run: runRecomputeProgress, migrated: true},
+	"import-calibre":     {run: runImportCalibre, migrated: true},
+}
+
+// runCommand loads the configuration, connects to the database and runs the
+// named command.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", name, usage)
+	}
+
+	appConfig, err := config.LoadAppConfig(".")
+	if err != nil {
+		return err
+	}
+	zLogger := logger.NewLogger(appConfig.Log)
+	defer zLogger.Sync()
+
+	DB, err := database.NewDatabase(zLogger, appConfig.DB)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := database.Close(DB); err != nil {
+			zLogger.Warn("Failed to close database", zap.Error(err))
+		}
+	}()
+	env := &environment{
+		Config: appConfig,
+		Logger: zLogger,
+		DB:     DB,
+		Files:  storage.NewLocal(appConfig.Storage.Dir, appConfig.Storage.URL),
+	}
+
+	// The schema is changed by "migrate up", never by other commands.
+	if cmd.migrated {
+		if err := database.CheckSchema(DB); err != nil {
+			return err
+		}
+	}
+	return cmd.run(env, args)
+}
+
+// runSeed loads the named seed sets. Sets with the default admin password
+// are refused when APP_ENV is production.
+func runSeed(env *environment, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	list := flags.Bool("list", false, "list the seed sets")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *list {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, set := range seeders.Sets {
+			fmt.Fprintf(w, "%s\t%s\n", set.Name, set.Description)
+		}
+		return w.Flush()
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{seeders.DefaultSet}
+	}
+	if err := seeders.Run(env.DB, names, env.Config.IsProduction()); err != nil {
+		return err
+	}
+	fmt.Printf("seeded %s\n", strings.Join(names, ", "))
+	return nil
+}
+
+// runMigrate applies, reverts or lists the embedded database migrations.
+func runMigrate(env *environment, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	migrator, err := database.NewMigrator(env.DB)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no migrations to revert")
+		}
+		return nil
+	case "status":
+		list, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range list {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+}
+
+// runImportCalibre imports a Calibre library folder, including covers, for a user.
+func runImportCalibre(env *environment, args []string) error {
+	flags := flag.NewFlagSet("import-calibre", flag.ContinueOnError)
+	username := flags.String("user", "", "username that receives the books")
+	noCovers := flags.Bool("no-covers", false, "do not copy cover.jpg files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" || flags.NArg() != 1 {
+		return errors.New(usage)
+	}
+
+	dbPath := flags.Arg(0)
+	if info, err := os.Stat(dbPath); err != nil {
+		return err
+	} else if info.IsDir() {
+		dbPath = filepath.Join(dbPath, "metadata.db")
+	}
+
+	user, err := findUser(env.DB, *username)
+	if err != nil {
+		return err
+	}
+
+	books, err := calibre.Read(dbPath)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", dbPath, err)
+	}
+
+	// Covers live next to metadata.db, in <Author>/<Title (id)>/cover.jpg.
+	var covers calibre.Covers
+	if !*noCovers {
+		covers = calibre.DirCovers(filepath.Dir(dbPath))
+	}
+
+	report := importer.Calibre(env.DB, env.Files, user.ID, books, covers)
+	for _, item := range report.Items {
+		if item.Status == models.ImportItemFailed {
+			fmt.Printf("failed: %s (calibre id %d): %s\n", item.Title, item.Row, item.Reason)
+		}
+	}
+	fmt.Printf("Imported %d Calibre books for %s: %d created, %d updated, %d skipped, %d failed\n",
+		len(books), user.Username, report.Created, report.Updated, report.Skipped, report.Failed)
+	return nil
+}
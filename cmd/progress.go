@@ -0,0 +1,86 @@
+package main
+
+import (
+	"ayo-baca-buku/app/models"
+	"flag"
+	"fmt"
+)
+
+// runRecomputeProgress repairs the current page of user books. Creating a
+// reading activity moves the current page, but editing or deleting one does
+// not, so the stored value can drift from the reading history.
+//
+// A finished book is on its last page. A book being read is on the end page
+// of its latest reading activity; books without activities are left alone.
+func runRecomputeProgress(env *environment, args []string) error {
+	flags := flag.NewFlagSet("recompute-progress", flag.ContinueOnError)
+	username := flags.String("user", "", "only recompute the books of this user")
+	dryRun := flags.Bool("dry-run", false, "print the changes without saving them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := env.DB.Table("user_books AS ub").
+		Select(`ub.id, ub.title, ub.status, ub.total_pages, ub.current_page, latest.end_page AS latest_end_page`).
+		Joins(`LEFT JOIN LATERAL (
+			SELECT ra.end_page FROM reading_activities ra
+			WHERE ra.user_book_id = ub.id AND ra.deleted_at IS NULL
+			ORDER BY ra.reading_date DESC, ra.id DESC LIMIT 1
+		) latest ON true`).
+		Where("ub.deleted_at IS NULL").
+		Order("ub.id")
+	if *username != "" {
+		user, err := findUser(env.DB, *username)
+		if err != nil {
+			return err
+		}
+		query = query.Where("ub.user_id = ?", user.ID)
+	}
+
+	var rows []struct {
+		ID            uint
+		Title         string
+		Status        string
+		TotalPages    int
+		CurrentPage   int
+		LatestEndPage *int
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, row := range rows {
+		page := row.CurrentPage
+		switch {
+		case row.Status == "finished":
+			page = row.TotalPages
+		case row.LatestEndPage != nil:
+			page = *row.LatestEndPage
+		}
+		if page > row.TotalPages {
+			page = row.TotalPages
+		}
+		if page == row.CurrentPage {
+			continue
+		}
+
+		fmt.Printf("%d %s: page %d -> %d\n", row.ID, row.Title, row.CurrentPage, page)
+		changed++
+		if *dryRun {
+			continue
+		}
+		err := env.DB.Model(&models.UserBook{}).Where("id = ?", row.ID).
+			UpdateColumn("current_page", page).Error
+		if err != nil {
+			return fmt.Errorf("update user book %d: %w", row.ID, err)
+		}
+	}
+
+	verb := "updated"
+	if *dryRun {
+		verb = "would update"
+	}
+	fmt.Printf("checked %d user books, %s %d\n", len(rows), verb, changed)
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"ayo-baca-buku/app/config"
+	"ayo-baca-buku/app/importer"
+	"ayo-baca-buku/app/middlewares"
+	"ayo-baca-buku/app/routes"
+	"ayo-baca-buku/app/util/jwt"
+	"ayo-baca-buku/app/util/metrics"
+	"ayo-baca-buku/app/util/tracing"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gofiber/contrib/fiberzap/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/swagger"
+	"go.uber.org/zap"
+)
+
+// runServe starts the API server.
+func runServe(env *environment, args []string) error {
+	if len(args) > 0 {
+		return errors.New(usage)
+	}
+	DB, files, zLogger := env.DB, env.Files, env.Logger
+	tokens, err := jwt.NewManager(env.Config.Auth)
+	if err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(env.Config.Tracing)
+	if err != nil {
+		return err
+	}
+	if err := DB.Use(metrics.GormPlugin{}); err != nil {
+		return err
+	}
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return err
+	}
+
+	importJobs := importer.NewRunner(DB, 2)
+	importJobs.Start()
+
+	app := fiber.New(fiber.Config{
+		ReadTimeout:           env.Config.Server.ReadTimeout,
+		WriteTimeout:          env.Config.Server.WriteTimeout,
+		IdleTimeout:           env.Config.Server.IdleTimeout,
+		BodyLimit:             env.Config.Server.BodyLimit,
+		DisableStartupMessage: true,
+		ErrorHandler:          middlewares.ErrorHandler,
+	})
+	app.Use(metrics.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(middlewares.RequestIDMiddleware())
+	app.Use(fiberzap.New(fiberzap.Config{
+		Logger: zLogger,
+		FieldsFunc: func(ctx *fiber.Ctx) []zap.Field {
+			fields := []zap.Field{zap.String("requestID", middlewares.GetRequestID(ctx))}
+			if user := middlewares.CurrentUser(ctx); user != nil {
+				fields = append(fields, zap.Uint("userID", user.ID))
+			}
+			return fields
+		},
+	}))
+	app.Use(middlewares.RecoverMiddleware())
+	app.Static("/docs", "docs")
+	app.Static(files.BaseURL, files.Dir)
+	app.Get("/docs/*", swagger.New(swagger.Config{
+		URL: "/docs/swagger.json",
+	}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Ayo Baca Buku - API",
+		})
+	})
+
+	app.Get("/scalar", func(c *fiber.Ctx) error {
+		html := fmt.Sprintf(`<!doctype html>
+		<html lang="en">
+			<head>
+				<meta charset="utf-8">
+				<meta name="viewport" content="width=device-width, initial-scale=1">
+				<title>Swagger API Reference - Scalar</title>
+				<link rel="icon" type="image/svg+xml" href="https://docs.scalar.com/favicon.svg">
+				<link rel="icon" type="image/png" href="https://docs.scalar.com/favicon.png">
+			</head>
+			<body>
+				<script id="api-reference" data-url="%s"></script>
+				<script src="https://cdn.jsdelivr.net/npm/@scalar/api-reference"></script>
+			</body>
+		</html>`, "/docs/swagger.json")
+
+		return c.Type("html").Send([]byte(html))
+	})
+
+	routes.SetupHealthRoutes(app, DB, files, env.Config.Mail)
+	app.Get("/metrics", metrics.Handler())
+	routes.SetupAuthRoutes(app, DB, tokens)
+	routes.SetupUserRoutes(app, DB, tokens)
+	routes.SetupUserBookRoutes(app, DB, tokens) // Added UserBook routes
+	routes.SetupEpubRoutes(app, DB, files)
+	routes.SetupBarcodeRoutes(app, DB)
+	routes.SetupReadingActivityRoutes(app, DB) // Added ReadingActivity routes
+	routes.SetupStatisticRoutes(app, DB)
+	routes.SetupStreakRoutes(app, DB)
+	routes.SetupReadingGoalRoutes(app, DB)
+	routes.SetupReadingPlanRoutes(app, DB)
+	// Routes of the authenticated user; the middleware runs once for all of them
+	me := app.Group("/me", middlewares.AuthJWTMiddleware(DB, tokens))
+	routes.SetupCalendarRoutes(app, me, DB)
+	routes.SetupShelfRoutes(app, DB, tokens)
+	routes.SetupTagRoutes(app, DB)
+	routes.SetupReviewRoutes(app, DB, tokens)
+	routes.SetupHighlightRoutes(app, DB)
+	routes.SetupImportRoutes(app, DB, importJobs, files)
+	routes.SetupExportRoutes(me, DB)
+	routes.SetupAdminRoutes(app, DB, tokens)
+
+	ln, err := listen(env.Config.Server)
+	if err != nil {
+		return err
+	}
+
+	// Serve until the server fails or SIGINT/SIGTERM asks it to stop.
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- app.Listener(ln)
+	}()
+	zLogger.Info("Server started", zap.String("address", ln.Addr().String()), zap.Bool("tls", env.Config.Server.TLS()))
+
+	var serveErr error
+	select {
+	case serveErr = <-served:
+		zLogger.Error("Server failed", zap.Error(serveErr))
+	case <-stop.Done():
+		// Restore the default handlers, so a second signal stops the server
+		// right away.
+		cancel()
+		zLogger.Info("Shutting down server", zap.Duration("timeout", env.Config.Server.ShutdownTimeout))
+	}
+
+	// Stop accepting connections and let running requests and imports finish.
+	// The database is closed by runCommand afterwards.
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), env.Config.Server.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		zLogger.Warn("Requests did not finish in time", zap.Error(err))
+	}
+	if err := importJobs.Stop(ctx); err != nil {
+		zLogger.Warn("Import jobs did not finish in time", zap.Error(err))
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		zLogger.Warn("Failed to send the remaining spans", zap.Error(err))
+	}
+	zLogger.Info("Server stopped")
+	return serveErr
+}
+
+// listen opens the Unix socket or TCP address of the server, with TLS when
+// the certificate files are configured.
+func listen(server config.ServerConfig) (net.Listener, error) {
+	var (
+		ln  net.Listener
+		err error
+	)
+	if server.Socket != "" {
+		// A socket file left behind by a crashed server would block the address.
+		if info, statErr := os.Stat(server.Socket); statErr == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(server.Socket)
+		}
+		ln, err = net.Listen("unix", server.Socket)
+	} else {
+		ln, err = net.Listen("tcp", server.Address())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if server.TLS() {
+		cert, err := tls.LoadX509KeyPair(server.TLSCertFile, server.TLSKeyFile)
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		ln = tls.NewListener(ln, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
+	return ln, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"ayo-baca-buku/app/models"
+	"ayo-baca-buku/app/util/jwt"
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// generatedPasswordLength is the length of passwords created by the user
+// commands. They are alphanumeric, like the passwords the API accepts.
+const generatedPasswordLength = 16
+
+// runUser manages accounts without going through the API.
+func runUser(env *environment, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "create-admin":
+		return runCreateAdmin(env.DB, args[1:])
+	case "reset-password":
+		return runResetPassword(env.DB, args[1:])
+	}
+	return fmt.Errorf("unknown user command %q\n%s", args[0], usage)
+}
+
+// runCreateAdmin creates a user with the admin role.
+func runCreateAdmin(DB *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("user create-admin", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the admin")
+	email := flags.String("email", "", "email address of the admin")
+	name := flags.String("name", "", "display name, defaults to the username")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from standard input instead of generating one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = *username
+	}
+
+	password, generated, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	input := struct {
+		Username string `validate:"required,alphanum,max=100"`
+		Email    string `validate:"required,email,max=255"`
+		Name     string `validate:"required,max=255"`
+		Password string `validate:"required,alphanum,min=6"`
+	}{*username, *email, *name, password}
+	if err := validator.New().Struct(&input); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := DB.Model(&models.User{}).Where("username = ? OR email = ?", input.Username, input.Email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("a user with username %q or email %q already exists", input.Username, input.Email)
+	}
+
+	hash, err := jwt.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user := models.User{
+		Name:      input.Name,
+		Username:  input.Username,
+		Email:     input.Email,
+		Password:  hash,
+		Role:      "admin",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := DB.Create(&user).Error; err != nil {
+		return err
+	}
+
+	fmt.Printf("created admin %s (id %d)\n", user.Username, user.ID)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+// runResetPassword sets a new password and signs the user out.
+func runResetPassword(DB *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the account")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from standard input instead of generating one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New(usage)
+	}
+
+	user, err := findUser(DB, *username)
+	if err != nil {
+		return err
+	}
+	password, generated, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	if err := validator.New().Var(password, "alphanum,min=6"); err != nil {
+		return errors.New("password must be at least 6 letters or digits")
+	}
+	hash, err := jwt.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	// Clearing the token ends the current session, see AuthJWTMiddleware.
+	err = DB.Model(user).Updates(map[string]interface{}{"password": hash, "token": ""}).Error
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("password of %s reset\n", user.Username)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+// findUser loads a user by username.
+func findUser(DB *gorm.DB, username string) (*models.User, error) {
+	var user models.User
+	if err := DB.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user %q not found", username)
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// readPassword reads the first line of standard input when fromStdin is set
+// and generates a random password otherwise. Passwords are not accepted as
+// flags, so they do not end up in the shell history.
+func readPassword(fromStdin bool) (password string, generated bool, err error) {
+	if !fromStdin {
+		password, err = generatePassword(generatedPasswordLength)
+		return password, true, err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", false, fmt.Errorf("read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), false, nil
+}
+
+func generatePassword(length int) (string, error) {
+	const alphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	password := make([]byte, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = alphabet[n.Int64()]
+	}
+	return string(password), nil
+}